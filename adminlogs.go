@@ -0,0 +1,325 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/igor-fedko/go_multiply_app/models"
+	"github.com/igor-fedko/go_multiply_app/storage"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxLogTextLength ограничивает длину искомого текста
+const maxLogTextLength = 200
+
+// errAdminOnly возвращается пользователю без роли администратора
+var errAdminOnly = errors.New("Журнал операций доступен только администратору")
+
+// logQuery - параметры поиска в журнале операций: page, size, operation,
+// ip, from, to и q (текст в Input) из строки запроса. Исходные значения
+// from и to сохраняются для формы фильтра.
+type logQuery struct {
+	Page      int
+	Size      int
+	Operation string
+	IP        string
+	From      string
+	To        string
+	Text      string
+
+	from, to time.Time
+}
+
+// logDetail - запись журнала и результат, сохраненный операцией.
+// Result равен nil, если запись не связана с результатом.
+type logDetail struct {
+	Log    models.LogEntry `json:"log"`
+	Result *models.Result  `json:"result"`
+}
+
+// logStatusTitles - названия статусов записей журнала
+var logStatusTitles = map[string]string{
+	models.LogStatusSuccess:         "Успешно",
+	models.LogStatusValidationError: "Ошибка ввода",
+	models.LogStatusStorageError:    "Ошибка хранилища",
+}
+
+// requireAdmin пускает дальше только администратора; reject отвечает
+// остальным пользователям. Должен стоять после проверки входа.
+func requireAdmin(reject func(*gin.Context)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if user, _ := currentUser(c); !user.IsAdmin() {
+			reject(c)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// forbidPage отвечает пользователю без роли администратора на HTML страницах
+func forbidPage(c *gin.Context) {
+	c.String(http.StatusForbidden, printer(c).Translate(errAdminOnly.Error()))
+}
+
+// forbidAPI отвечает пользователю без роли администратора в JSON API
+func forbidAPI(c *gin.Context) {
+	respondAPIError(c, http.StatusForbidden, errCodeForbidden, errAdminOnly.Error(), "")
+}
+
+// parseLogQuery разбирает и проверяет параметры поиска в журнале.
+// Ошибки параметров возвращаются как *queryError.
+func parseLogQuery(c *gin.Context) (logQuery, error) {
+	q := logQuery{
+		Page:      1,
+		Size:      defaultPageSize,
+		Operation: c.Query("operation"),
+		IP:        c.Query("ip"),
+		From:      c.Query("from"),
+		To:        c.Query("to"),
+		Text:      c.Query("q"),
+	}
+
+	if s := c.Query("page"); s != "" {
+		page, err := strconv.Atoi(s)
+		if err != nil || page < 1 {
+			return q, &queryError{Field: "page", Message: "Номер страницы должен быть положительным целым числом"}
+		}
+		if page > maxPage {
+			return q, &queryError{Field: "page", Message: "Номер страницы должен быть не больше " + strconv.Itoa(maxPage)}
+		}
+		q.Page = page
+	}
+
+	if s := c.Query("size"); s != "" {
+		size, err := strconv.Atoi(s)
+		if err != nil || size < 1 || size > maxPageSize {
+			return q, &queryError{Field: "size", Message: "Размер страницы должен быть от 1 до " + strconv.Itoa(maxPageSize)}
+		}
+		q.Size = size
+	}
+
+	if q.Operation != "" && !slices.Contains(operationNames(), q.Operation) {
+		return q, &queryError{Field: "operation", Message: "Неизвестная операция " + q.Operation}
+	}
+
+	if q.IP != "" {
+		addr, err := netip.ParseAddr(q.IP)
+		if err != nil {
+			return q, &queryError{Field: "ip", Message: "Неверный формат IP адреса"}
+		}
+		q.IP = addr.String()
+	}
+
+	if len([]rune(q.Text)) > maxLogTextLength {
+		return q, &queryError{Field: "q", Message: "Искомый текст длиннее " + strconv.Itoa(maxLogTextLength) + " символов"}
+	}
+
+	var err error
+	if q.from, err = parseDateParam(q.From, false); err != nil {
+		return q, &queryError{Field: "from", Message: "Неверный формат начальной даты"}
+	}
+	if q.to, err = parseDateParam(q.To, true); err != nil {
+		return q, &queryError{Field: "to", Message: "Неверный формат конечной даты"}
+	}
+	if !q.from.IsZero() && !q.to.IsZero() && q.from.After(q.to) {
+		return q, &queryError{Field: "from", Message: "Начальная дата позже конечной"}
+	}
+	return q, nil
+}
+
+// storeQuery преобразует параметры в запрос к хранилищу
+func (q logQuery) storeQuery() storage.LogQuery {
+	return storage.LogQuery{
+		Operation: q.Operation,
+		UserIP:    q.IP,
+		From:      q.from,
+		To:        q.to,
+		Text:      q.Text,
+		Offset:    (q.Page - 1) * q.Size,
+		Limit:     q.Size,
+	}
+}
+
+// listLogs возвращает страницу журнала и сведения о пагинации
+func listLogs(q logQuery) ([]models.LogEntry, pagination, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), appConfig.Timeouts.Read.Duration)
+	defer cancel()
+
+	query := q.storeQuery()
+	total, err := store.CountLogs(ctx, query)
+	if err != nil {
+		return nil, pagination{}, err
+	}
+	entries, err := store.ListLogs(ctx, query)
+	if err != nil {
+		return nil, pagination{}, err
+	}
+	return entries, newPagination(q.Page, q.Size, total), nil
+}
+
+// getLogDetail возвращает запись журнала и связанный с ней результат
+func getLogDetail(id primitive.ObjectID) (logDetail, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), appConfig.Timeouts.Read.Duration)
+	defer cancel()
+
+	entry, err := store.GetLog(ctx, id)
+	if err != nil {
+		return logDetail{}, err
+	}
+
+	detail := logDetail{Log: entry}
+	if entry.ResultID.IsZero() {
+		return detail, nil
+	}
+	result, err := store.GetResult(ctx, storage.AllUsers(), entry.ResultID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return detail, nil
+	}
+	if err != nil {
+		return logDetail{}, err
+	}
+	detail.Result = &result
+	return detail, nil
+}
+
+// values возвращает параметры, отличающиеся от значений по умолчанию
+func (q logQuery) values() url.Values {
+	v := url.Values{}
+	if q.Page != 1 {
+		v.Set("page", strconv.Itoa(q.Page))
+	}
+	if q.Size != defaultPageSize {
+		v.Set("size", strconv.Itoa(q.Size))
+	}
+	if q.Operation != "" {
+		v.Set("operation", q.Operation)
+	}
+	if q.IP != "" {
+		v.Set("ip", q.IP)
+	}
+	if q.From != "" {
+		v.Set("from", q.From)
+	}
+	if q.To != "" {
+		v.Set("to", q.To)
+	}
+	if q.Text != "" {
+		v.Set("q", q.Text)
+	}
+	return v
+}
+
+// PageURL возвращает ссылку на страницу журнала page с теми же фильтрами
+func (q logQuery) PageURL(page int) string {
+	q.Page = page
+	if encoded := q.values().Encode(); encoded != "" {
+		return "/admin/logs?" + encoded
+	}
+	return "/admin/logs"
+}
+
+// renderLogs отображает страницу журнала, дополняя данные названиями операций,
+// переводчиком и форматом записи чисел
+func renderLogs(c *gin.Context, status int, data gin.H) {
+	localizePage(c, data)
+	data["OperationNames"] = operationNames()
+	data["OperationTitles"] = operationTitles(printer(c))
+	data["StatusTitles"] = logStatusTitles
+	data["Numbers"] = newNumberView(requestLocale(c))
+	data["PageSizes"] = pageSizes
+	if user, ok := currentUser(c); ok {
+		data["User"] = user
+	}
+	c.HTML(status, "logs.html", data)
+}
+
+// adminLogsHandler показывает страницу журнала операций
+func adminLogsHandler(c *gin.Context) {
+	query, err := parseLogQuery(c)
+	if err != nil {
+		renderLogs(c, http.StatusBadRequest, gin.H{"Error": err.Error(), "Query": query})
+		return
+	}
+
+	entries, page, err := listLogs(query)
+	if err != nil {
+		renderLogs(c, http.StatusInternalServerError, gin.H{
+			"Error": "Ошибка при получении журнала: " + err.Error(),
+			"Query": query,
+		})
+		return
+	}
+
+	renderLogs(c, http.StatusOK, gin.H{"Logs": entries, "Query": query, "Pagination": page})
+}
+
+// adminLogHandler показывает запись журнала и сохраненный операцией результат
+func adminLogHandler(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		renderLogs(c, http.StatusBadRequest, gin.H{"Error": "Некорректный идентификатор записи"})
+		return
+	}
+
+	detail, err := getLogDetail(id)
+	if errors.Is(err, storage.ErrNotFound) {
+		renderLogs(c, http.StatusNotFound, gin.H{"Error": "Запись журнала не найдена"})
+		return
+	}
+	if err != nil {
+		renderLogs(c, http.StatusInternalServerError, gin.H{"Error": "Ошибка при получении записи журнала: " + err.Error()})
+		return
+	}
+
+	renderLogs(c, http.StatusOK, gin.H{"Detail": detail})
+}
+
+// apiListLogsHandler возвращает страницу журнала операций
+func apiListLogsHandler(c *gin.Context) {
+	query, err := parseLogQuery(c)
+	if err != nil {
+		var qErr *queryError
+		errors.As(err, &qErr)
+		respondAPIError(c, http.StatusBadRequest, errCodeInvalidQuery, qErr.Message, qErr.Field)
+		return
+	}
+
+	entries, page, err := listLogs(query)
+	if err != nil {
+		respondAPIError(c, http.StatusInternalServerError, errCodeStorageError,
+			"Ошибка при получении журнала: "+err.Error(), "")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"logs": entries, "pagination": page})
+}
+
+// apiGetLogHandler возвращает запись журнала и сохраненный операцией результат
+func apiGetLogHandler(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		respondAPIError(c, http.StatusBadRequest, errCodeInvalidID, "Некорректный идентификатор", "id")
+		return
+	}
+
+	detail, err := getLogDetail(id)
+	if errors.Is(err, storage.ErrNotFound) {
+		respondAPIError(c, http.StatusNotFound, errCodeNotFound, "Запись журнала не найдена", "")
+		return
+	}
+	if err != nil {
+		respondAPIError(c, http.StatusInternalServerError, errCodeStorageError,
+			"Ошибка при получении записи журнала: "+err.Error(), "")
+		return
+	}
+
+	c.JSON(http.StatusOK, detail)
+}
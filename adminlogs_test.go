@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/igor-fedko/go_multiply_app/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// logsResponse - ответ GET /api/v1/admin/logs
+type logsResponse struct {
+	Logs       []models.LogEntry `json:"logs"`
+	Pagination pagination        `json:"pagination"`
+}
+
+// listAdminLogs запрашивает страницу журнала через API
+func (s *APITestSuite) listAdminLogs(query string) logsResponse {
+	w := s.get("/api/v1/admin/logs?" + query)
+	require.Equal(s.T(), http.StatusOK, w.Code, w.Body.String())
+
+	var response logsResponse
+	require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &response))
+	return response
+}
+
+// TestAdminLogsForbidden тестирует доступ к журналу без роли администратора
+func (s *APITestSuite) TestAdminLogsForbidden() {
+	w := s.get("/api/v1/admin/logs")
+	assert.Equal(s.T(), http.StatusForbidden, w.Code)
+	assert.Contains(s.T(), w.Body.String(), `"code":"forbidden"`)
+
+	assert.Equal(s.T(), http.StatusForbidden, s.get("/admin/logs").Code)
+	assert.NotContains(s.T(), s.get("/").Body.String(), "/admin/logs")
+}
+
+// TestAdminLogsSearch тестирует поиск в журнале операций
+func (s *APITestSuite) TestAdminLogsSearch() {
+	require.NoError(s.T(), s.store.SetUserRole(context.Background(), s.user.Username, models.RoleAdmin))
+
+	require.Equal(s.T(), http.StatusSeeOther, s.postForm("/multiply", url.Values{"number1": {"12"}, "number2": {"5"}}).Code)
+	require.Equal(s.T(), http.StatusSeeOther, s.postForm("/add", url.Values{"number1": {"7"}, "number2": {"1"}}).Code)
+	require.Equal(s.T(), http.StatusCreated, s.postJSON("/api/v1/evaluate", `{"expression": "12 * 2"}`).Code)
+	old := models.LogEntry{Operation: "add", Input: "1 + 1", Result: "2", UserIP: "10.0.0.1", Timestamp: time.Date(2020, 1, 15, 10, 0, 0, 0, time.UTC)}
+	require.NoError(s.T(), s.store.InsertLog(context.Background(), &old))
+	require.Len(s.T(), s.logs(), 4)
+
+	all := s.listAdminLogs("")
+	assert.Equal(s.T(), int64(4), all.Pagination.Total)
+	require.Len(s.T(), all.Logs, 4)
+	assert.Equal(s.T(), "evaluate", all.Logs[0].Operation)
+	assert.Equal(s.T(), old.ID, all.Logs[3].ID)
+
+	tests := map[string]struct {
+		query string
+		total int64
+	}{
+		"operation": {"operation=add", 2},
+		"ip":        {"ip=10.0.0.1", 1},
+		"text":      {"q=12", 2},
+		"from":      {"from=2021-01-01", 3},
+		"range":     {"from=2020-01-15&to=2020-01-15", 1},
+		"combined":  {"operation=multiply&q=12", 1},
+		"none":      {"q=999", 0},
+	}
+	for name, tt := range tests {
+		assert.Equal(s.T(), tt.total, s.listAdminLogs(tt.query).Pagination.Total, name)
+	}
+
+	page := s.listAdminLogs("size=3&page=2")
+	require.Len(s.T(), page.Logs, 1)
+	assert.Equal(s.T(), 2, page.Pagination.Pages)
+
+	for _, query := range []string{"ip=local", "operation=pow", "size=0", "page=9223372036854775807", "from=2021-13-01"} {
+		w := s.get("/api/v1/admin/logs?" + query)
+		assert.Equal(s.T(), http.StatusBadRequest, w.Code, query)
+		assert.Contains(s.T(), w.Body.String(), `"code":"invalid_query"`, query)
+	}
+}
+
+// TestAdminLogDetail тестирует запись журнала со ссылкой на результат
+func (s *APITestSuite) TestAdminLogDetail() {
+	require.NoError(s.T(), s.store.SetUserRole(context.Background(), s.user.Username, models.RoleAdmin))
+	require.Equal(s.T(), http.StatusSeeOther, s.postForm("/multiply", url.Values{"number1": {"12"}, "number2": {"5"}}).Code)
+
+	result := s.findResult("multiply")
+	logs := s.logs()
+	require.Len(s.T(), logs, 1)
+	assert.Equal(s.T(), result.ID, logs[0].ResultID)
+
+	w := s.get("/api/v1/admin/logs/" + logs[0].ID.Hex())
+	require.Equal(s.T(), http.StatusOK, w.Code)
+	var detail logDetail
+	require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &detail))
+	assert.Equal(s.T(), logs[0].ID, detail.Log.ID)
+	require.NotNil(s.T(), detail.Result)
+	assert.Equal(s.T(), float64(60), detail.Result.Result)
+
+	w = s.get("/admin/logs/" + logs[0].ID.Hex())
+	require.Equal(s.T(), http.StatusOK, w.Code)
+	assert.Contains(s.T(), w.Body.String(), result.ID.Hex())
+	assert.Contains(s.T(), w.Body.String(), "12 * 5")
+
+	w = s.get("/admin/logs")
+	require.Equal(s.T(), http.StatusOK, w.Code)
+	assert.Contains(s.T(), w.Body.String(), `href="/admin/logs/`+logs[0].ID.Hex()+`"`)
+	assert.Contains(s.T(), s.get("/").Body.String(), `href="/admin/logs"`)
+
+	assert.Equal(s.T(), http.StatusNotFound, s.get("/api/v1/admin/logs/"+result.ID.Hex()).Code)
+	assert.Equal(s.T(), http.StatusBadRequest, s.get("/api/v1/admin/logs/123").Code)
+	assert.Equal(s.T(), http.StatusNotFound, s.get("/admin/logs/"+result.ID.Hex()).Code)
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/igor-fedko/go_multiply_app/metrics"
+	"github.com/igor-fedko/go_multiply_app/models"
+	"github.com/igor-fedko/go_multiply_app/operations"
+	"github.com/igor-fedko/go_multiply_app/storage"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Стабильные коды ошибок JSON API. Клиенты опираются на них,
+// поэтому существующие значения менять нельзя. Коды ошибок проверки
+// операндов (например, division_by_zero) задаются в пакете operations.
+const (
+	errCodeInvalidJSON      = "invalid_json"
+	errCodeInvalidNumber    = "invalid_number"
+	errCodeMissingOperand   = "missing_operand"
+	errCodeInvalidPrecision = "invalid_precision"
+	errCodeInvalidID        = "invalid_id"
+	errCodeInvalidQuery     = "invalid_query"
+	errCodeNotFound         = "not_found"
+	errCodeStorageError     = "storage_error"
+
+	errCodeUnauthorized       = "unauthorized"
+	errCodeForbidden          = "forbidden"
+	errCodeInvalidCredentials = "invalid_credentials"
+	errCodeUsernameTaken      = "username_taken"
+	errCodeRateLimited        = "rate_limited"
+)
+
+// apiError описывает ошибку, возвращаемую JSON API
+type apiError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Field   string `json:"field,omitempty"`
+}
+
+func (e *apiError) Error() string {
+	return e.Message
+}
+
+// apiErrorResponse - оболочка ответа с ошибкой
+type apiErrorResponse struct {
+	Error apiError `json:"error"`
+}
+
+// apiOperationInfo описывает операцию в ответе GET /api/v1/operations
+type apiOperationInfo struct {
+	Name  string `json:"name"`
+	Arity int    `json:"arity"`
+	Title string `json:"title"`
+}
+
+// registerAPIRoutes регистрирует маршруты JSON API версии v1
+func registerAPIRoutes(router *gin.Engine) {
+	v1 := router.Group("/api/v1")
+	limit := limitRequests(rejectAPI)
+
+	// Регистрация и выпуск токена доступны без токена;
+	// частота таких запросов ограничивается по IP-адресу
+	v1.GET("/operations", limit, apiListOperationsHandler)
+	v1.POST("/users", limit, apiRegisterHandler)
+	v1.POST("/tokens", limit, apiCreateTokenHandler)
+
+	// Частота ограничивается по IP-адресу до проверки токена, чтобы подбор
+	// токенов не обходил лимит и не нагружал хранилище, а после проверки -
+	// по ID пользователя
+	authorized := v1.Group("", limit, requireAPIToken, limit)
+	for _, op := range operations.Default.All() {
+		authorized.POST("/"+op.Name(), apiOperationHandler(op))
+	}
+	authorized.POST("/evaluate", apiEvaluateHandler)
+	authorized.POST("/batch", apiBatchHandler)
+	authorized.POST("/import", apiImportHandler)
+
+	authorized.GET("/results", apiListResultsHandler)
+	authorized.GET("/results/:id", apiGetResultHandler)
+	authorized.GET("/export", apiExportHandler)
+	authorized.DELETE("/tokens/current", apiRevokeTokenHandler)
+
+	admin := authorized.Group("/admin", requireAdmin(forbidAPI))
+	admin.GET("/logs", apiListLogsHandler)
+	admin.GET("/logs/:id", apiGetLogHandler)
+}
+
+// respondAPIError отправляет ошибку в формате JSON API. Сообщение
+// переводится на язык запроса, код ошибки от языка не зависит.
+func respondAPIError(c *gin.Context, status int, code, message, field string) {
+	c.AbortWithStatusJSON(status, apiErrorResponse{
+		Error: apiError{Code: code, Message: printer(c).Translate(message), Field: field},
+	})
+}
+
+// apiListOperationsHandler возвращает список зарегистрированных операций
+func apiListOperationsHandler(c *gin.Context) {
+	ops := operations.Default.All()
+	p := printer(c)
+	infos := make([]apiOperationInfo, 0, len(ops))
+	for _, op := range ops {
+		infos = append(infos, apiOperationInfo{Name: op.Name(), Arity: op.Arity(), Title: p.Translate(op.Labels().Title)})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"operations": infos})
+}
+
+// apiOperationHandler создает обработчик JSON API для операции
+func apiOperationHandler(op operations.Operation) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		started := time.Now()
+		var body map[string]json.RawMessage
+
+		// reject отвечает ошибкой разбора запроса и учитывает ее в метриках и журнале
+		reject := func(status int, code, message, field string) {
+			raw := make([]string, op.Arity())
+			for i := range raw {
+				raw[i] = rawOperandText(body[operandField(i)])
+			}
+			appMetrics.ObserveOperation(op.Name(), metrics.OutcomeInvalidInput)
+			logOperationFailure(c, op.Name(), rawOperandsInput(raw), models.LogStatusValidationError,
+				&apiError{Code: code, Message: message, Field: field}, started)
+			respondAPIError(c, status, code, message, field)
+		}
+
+		if err := json.NewDecoder(c.Request.Body).Decode(&body); err != nil {
+			if errors.Is(err, io.EOF) {
+				reject(http.StatusBadRequest, errCodeInvalidJSON, "Пустое тело запроса", "")
+				return
+			}
+			reject(http.StatusBadRequest, errCodeInvalidJSON, "Некорректный JSON: "+err.Error(), "")
+			return
+		}
+
+		var precisionStr string
+		if raw, ok := body["precision"]; ok {
+			if err := json.Unmarshal(raw, &precisionStr); err != nil {
+				reject(http.StatusBadRequest, errCodeInvalidPrecision, errUnknownPrecision.Error(), "precision")
+				return
+			}
+		}
+		precision, err := parsePrecision(precisionStr)
+		if err != nil {
+			reject(http.StatusBadRequest, errCodeInvalidPrecision, err.Error(), "precision")
+			return
+		}
+
+		operands := make([]float64, op.Arity())
+		var exact []*big.Rat
+		for i := range operands {
+			field := operandField(i)
+			raw, ok := body[field]
+			if !ok || string(raw) == "null" {
+				reject(http.StatusBadRequest, errCodeMissingOperand,
+					"Не указан операнд "+field, field)
+				return
+			}
+
+			value, exactValue, err := parseJSONOperand(raw, precision, i)
+			if numErr, ok := numericError(err); ok {
+				reject(http.StatusUnprocessableEntity, numErr.Code, numErr.Message, field)
+				return
+			}
+			if err != nil {
+				reject(http.StatusBadRequest, errCodeInvalidNumber,
+					invalidOperandMessage(op, i), field)
+				return
+			}
+			operands[i] = value
+			if exactValue != nil {
+				exact = append(exact, exactValue)
+			}
+		}
+
+		result, err := performOperation(c, op, operands, exact)
+		if err != nil {
+			var validationErr *operations.ValidationError
+			if errors.As(err, &validationErr) {
+				var field string
+				if validationErr.Operand >= 0 {
+					field = operandField(validationErr.Operand)
+				}
+				respondAPIError(c, http.StatusUnprocessableEntity, validationErr.Code, validationErr.Message, field)
+				return
+			}
+			respondAPIError(c, http.StatusInternalServerError, errCodeStorageError,
+				"Ошибка при сохранении результата: "+err.Error(), "")
+			return
+		}
+
+		c.JSON(http.StatusCreated, result)
+	}
+}
+
+// errNotJSONNumber возвращается, если операнд в режиме float64 передан не числом
+var errNotJSONNumber = errors.New("операнд должен быть числом JSON")
+
+// parseJSONOperand разбирает операнд с индексом operand из JSON. В точном
+// режиме разбирается исходная запись числа, а не float64; операнд можно
+// передать и строкой, например "0.1". Числа вне диапазона float64
+// (например, 1e400) отклоняются так же, как в форме.
+func parseJSONOperand(raw json.RawMessage, precision string, operand int) (float64, *big.Rat, error) {
+	if precision == models.PrecisionExact {
+		return parseOperand(rawOperandText(raw), precision, operand)
+	}
+	var number json.Number
+	if len(raw) == 0 || raw[0] == '"' || json.Unmarshal(raw, &number) != nil {
+		return 0, nil, errNotJSONNumber
+	}
+	return parseOperand(number.String(), precision, operand)
+}
+
+// rawOperandText возвращает текст операнда из JSON: строку без кавычек
+// или исходную запись числового литерала
+func rawOperandText(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	return string(raw)
+}
+
+// apiListResultsHandler возвращает страницу сохраненных результатов
+// с учетом фильтров и сортировки из строки запроса
+func apiListResultsHandler(c *gin.Context) {
+	query, err := parseHistoryQuery(c)
+	if err != nil {
+		respondHistoryError(c, err)
+		return
+	}
+
+	results, page, err := listHistory(query)
+	if err != nil {
+		respondAPIError(c, http.StatusInternalServerError, errCodeStorageError,
+			"Ошибка при получении результатов: "+err.Error(), "")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"results": results, "pagination": page})
+}
+
+// respondHistoryError отвечает ошибкой разбора параметров истории
+func respondHistoryError(c *gin.Context, err error) {
+	var qErr *queryError
+	switch status := historyErrorStatus(err); {
+	case errors.As(err, &qErr):
+		respondAPIError(c, status, errCodeInvalidQuery, qErr.Message, qErr.Field)
+	case status == http.StatusForbidden:
+		respondAPIError(c, status, errCodeForbidden, err.Error(), "user")
+	default:
+		respondAPIError(c, status, errCodeStorageError, "Ошибка при получении результатов: "+err.Error(), "")
+	}
+}
+
+// apiGetResultHandler возвращает результат по его ObjectID
+func apiGetResultHandler(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		respondAPIError(c, http.StatusBadRequest, errCodeInvalidID, "Некорректный идентификатор", "id")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), appConfig.Timeouts.Read.Duration)
+	defer cancel()
+
+	// Чужой результат неотличим от отсутствующего
+	result, err := store.GetResult(ctx, viewerScope(c), id)
+	if errors.Is(err, storage.ErrNotFound) {
+		respondAPIError(c, http.StatusNotFound, errCodeNotFound, "Результат не найден", "")
+		return
+	}
+	if err != nil {
+		respondAPIError(c, http.StatusInternalServerError, errCodeStorageError,
+			"Ошибка при получении результата: "+err.Error(), "")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,359 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/igor-fedko/go_multiply_app/auth"
+	"github.com/igor-fedko/go_multiply_app/models"
+	"github.com/igor-fedko/go_multiply_app/storage"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// sessionCookie - имя cookie с токеном сессии HTML интерфейса
+const sessionCookie = "session"
+
+// userContextKey - ключ текущего пользователя в gin.Context
+const userContextKey = "user"
+
+// credentials - имя и пароль из формы или JSON
+type credentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	// Name - необязательное название выпускаемого токена API
+	Name string `json:"name"`
+}
+
+// currentUser возвращает пользователя, выполнившего вход
+func currentUser(c *gin.Context) (models.User, bool) {
+	user, ok := c.Get(userContextKey)
+	if !ok {
+		return models.User{}, false
+	}
+	return user.(models.User), true
+}
+
+// currentUserID возвращает ID текущего пользователя или нулевой ID
+func currentUserID(c *gin.Context) primitive.ObjectID {
+	user, _ := currentUser(c)
+	return user.ID
+}
+
+// bearerToken возвращает токен из заголовка Authorization: Bearer
+func bearerToken(c *gin.Context) string {
+	scheme, token, ok := strings.Cut(c.GetHeader("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// lookupToken находит пользователя по токену вида kind.
+// Неизвестный, чужого вида или истекший токен - storage.ErrNotFound.
+func lookupToken(ctx context.Context, raw, kind string) (models.User, error) {
+	if raw == "" {
+		return models.User{}, storage.ErrNotFound
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, appConfig.Timeouts.Read.Duration)
+	defer cancel()
+
+	token, err := store.GetTokenByHash(ctx, auth.HashToken(raw))
+	if err != nil {
+		return models.User{}, err
+	}
+	if token.Kind != kind || token.Expired(time.Now()) {
+		return models.User{}, storage.ErrNotFound
+	}
+	return store.GetUser(ctx, token.UserID)
+}
+
+// requireSession пускает к HTML страницам только после входа;
+// без действующей сессии запрос перенаправляется на страницу входа
+func requireSession(c *gin.Context) {
+	raw, _ := c.Cookie(sessionCookie)
+	user, err := lookupToken(c.Request.Context(), raw, models.TokenSession)
+	if errors.Is(err, storage.ErrNotFound) {
+		c.Redirect(http.StatusSeeOther, "/login")
+		c.Abort()
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "check session", "error", err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	c.Set(userContextKey, user)
+	c.Next()
+}
+
+// requireAPIToken пускает к JSON API только с действующим токеном API
+func requireAPIToken(c *gin.Context) {
+	user, err := lookupToken(c.Request.Context(), bearerToken(c), models.TokenAPI)
+	if errors.Is(err, storage.ErrNotFound) {
+		c.Header("WWW-Authenticate", `Bearer realm="api"`)
+		respondAPIError(c, http.StatusUnauthorized, errCodeUnauthorized, "Требуется токен API в заголовке Authorization: Bearer", "")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "check api token", "error", err)
+		respondAPIError(c, http.StatusInternalServerError, errCodeStorageError, "Ошибка при проверке токена: "+err.Error(), "")
+		return
+	}
+
+	c.Set(userContextKey, user)
+	c.Next()
+}
+
+// registerUser проверяет учетные данные и создает пользователя.
+// Занятое имя - storage.ErrDuplicate.
+func registerUser(ctx context.Context, cred credentials) (models.User, error) {
+	if err := auth.ValidateUsername(cred.Username); err != nil {
+		return models.User{}, err
+	}
+	hash, err := auth.HashPassword(cred.Password)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	user := models.User{
+		Username:     cred.Username,
+		PasswordHash: hash,
+		Role:         models.RoleUser,
+		CreatedAt:    time.Now().UTC(),
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, appConfig.Timeouts.Write.Duration)
+	defer cancel()
+
+	if err := store.CreateUser(ctx, &user); err != nil {
+		return models.User{}, err
+	}
+	return user, nil
+}
+
+// loginUser проверяет имя и пароль. Неизвестное имя и неверный пароль
+// неразличимы для клиента: оба дают auth.ErrWrongPassword, и для неизвестного
+// имени пароль тоже сравнивается с хешем, чтобы не выдать его временем ответа.
+func loginUser(ctx context.Context, cred credentials) (models.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, appConfig.Timeouts.Read.Duration)
+	defer cancel()
+
+	user, err := store.GetUserByUsername(ctx, cred.Username)
+	if errors.Is(err, storage.ErrNotFound) {
+		return models.User{}, auth.RejectPassword(cred.Password)
+	}
+	if err != nil {
+		return models.User{}, err
+	}
+	if err := auth.CheckPassword(user.PasswordHash, cred.Password); err != nil {
+		return models.User{}, err
+	}
+	return user, nil
+}
+
+// issueToken выпускает токен вида kind и возвращает его пользователю;
+// в хранилище сохраняется только хеш. Нулевой ttl - бессрочный токен.
+func issueToken(ctx context.Context, userID primitive.ObjectID, kind, name string, ttl time.Duration) (string, models.Token, error) {
+	raw, hash, err := auth.NewToken()
+	if err != nil {
+		return "", models.Token{}, err
+	}
+
+	token := models.Token{
+		UserID:    userID,
+		Kind:      kind,
+		Name:      name,
+		Hash:      hash,
+		CreatedAt: time.Now().UTC(),
+	}
+	if ttl > 0 {
+		token.ExpiresAt = token.CreatedAt.Add(ttl)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, appConfig.Timeouts.Write.Duration)
+	defer cancel()
+
+	if err := store.CreateToken(ctx, &token); err != nil {
+		return "", models.Token{}, err
+	}
+	return raw, token, nil
+}
+
+// revokeToken удаляет токен
+func revokeToken(ctx context.Context, raw string) error {
+	ctx, cancel := context.WithTimeout(ctx, appConfig.Timeouts.Write.Duration)
+	defer cancel()
+
+	return store.DeleteToken(ctx, auth.HashToken(raw))
+}
+
+// credentialsError возвращает HTTP статус и сообщение для ошибки входа или регистрации
+func credentialsError(err error) (int, string) {
+	switch {
+	case errors.Is(err, auth.ErrInvalidUsername), errors.Is(err, auth.ErrInvalidPassword):
+		return http.StatusBadRequest, err.Error()
+	case errors.Is(err, auth.ErrWrongPassword):
+		return http.StatusUnauthorized, err.Error()
+	case errors.Is(err, storage.ErrDuplicate):
+		return http.StatusConflict, "Имя пользователя уже занято"
+	default:
+		return http.StatusInternalServerError, "Ошибка хранилища: " + err.Error()
+	}
+}
+
+// startSession выпускает токен сессии и устанавливает cookie
+func startSession(c *gin.Context, user models.User) error {
+	ttl := appConfig.Auth.SessionTTL.Duration
+	raw, _, err := issueToken(c.Request.Context(), user.ID, models.TokenSession, "", ttl)
+	if err != nil {
+		return err
+	}
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(sessionCookie, raw, int(ttl.Seconds()), "/", "", appConfig.Auth.CookieSecure, true)
+	return nil
+}
+
+// renderAuth отображает страницу входа или регистрации
+func renderAuth(c *gin.Context, status int, register bool, username, errMessage string) {
+	data := gin.H{
+		"Register": register,
+		"Username": username,
+		"Error":    errMessage,
+	}
+	localizePage(c, data)
+	c.HTML(status, "auth.html", data)
+}
+
+// authFormHandler создает обработчик формы входа (register = false)
+// или регистрации (register = true)
+func authFormHandler(register bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var cred credentials
+		cred.Username = strings.TrimSpace(c.PostForm("username"))
+		cred.Password = c.PostForm("password")
+
+		var user models.User
+		var err error
+		if register {
+			user, err = registerUser(c.Request.Context(), cred)
+		} else {
+			user, err = loginUser(c.Request.Context(), cred)
+		}
+		if err == nil {
+			err = startSession(c, user)
+		}
+		if err != nil {
+			status, message := credentialsError(err)
+			if status == http.StatusInternalServerError {
+				slog.ErrorContext(c.Request.Context(), "authenticate user", "error", err)
+			}
+			renderAuth(c, status, register, cred.Username, message)
+			return
+		}
+
+		slog.InfoContext(c.Request.Context(), "user signed in", "user", user.Username, "registered", register)
+		c.Redirect(http.StatusSeeOther, "/")
+	}
+}
+
+// authPageHandler создает обработчик страницы входа или регистрации
+func authPageHandler(register bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		renderAuth(c, http.StatusOK, register, "", "")
+	}
+}
+
+// logoutHandler завершает сессию и удаляет cookie
+func logoutHandler(c *gin.Context) {
+	if raw, err := c.Cookie(sessionCookie); err == nil {
+		if err := revokeToken(c.Request.Context(), raw); err != nil {
+			slog.ErrorContext(c.Request.Context(), "revoke session", "error", err)
+		}
+	}
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(sessionCookie, "", -1, "/", "", appConfig.Auth.CookieSecure, true)
+	c.Redirect(http.StatusSeeOther, "/login")
+}
+
+// bindCredentials разбирает учетные данные из JSON; при ошибке отвечает сам
+func bindCredentials(c *gin.Context) (credentials, bool) {
+	var cred credentials
+	if err := c.ShouldBindJSON(&cred); err != nil {
+		respondAPIError(c, http.StatusBadRequest, errCodeInvalidJSON, "Некорректный JSON: "+err.Error(), "")
+		return credentials{}, false
+	}
+	return cred, true
+}
+
+// respondCredentialsError отвечает на ошибку входа или регистрации в формате JSON API
+func respondCredentialsError(c *gin.Context, err error) {
+	status, message := credentialsError(err)
+	switch {
+	case errors.Is(err, auth.ErrInvalidUsername):
+		respondAPIError(c, status, errCodeInvalidCredentials, message, "username")
+	case errors.Is(err, auth.ErrInvalidPassword):
+		respondAPIError(c, status, errCodeInvalidCredentials, message, "password")
+	case errors.Is(err, auth.ErrWrongPassword):
+		respondAPIError(c, status, errCodeUnauthorized, message, "")
+	case errors.Is(err, storage.ErrDuplicate):
+		respondAPIError(c, status, errCodeUsernameTaken, message, "username")
+	default:
+		slog.ErrorContext(c.Request.Context(), "authenticate user", "error", err)
+		respondAPIError(c, status, errCodeStorageError, message, "")
+	}
+}
+
+// apiRegisterHandler создает пользователя
+func apiRegisterHandler(c *gin.Context) {
+	cred, ok := bindCredentials(c)
+	if !ok {
+		return
+	}
+
+	user, err := registerUser(c.Request.Context(), cred)
+	if err != nil {
+		respondCredentialsError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"user": user})
+}
+
+// apiCreateTokenHandler выпускает бессрочный токен API по имени и паролю.
+// Токен возвращается только в этом ответе.
+func apiCreateTokenHandler(c *gin.Context) {
+	cred, ok := bindCredentials(c)
+	if !ok {
+		return
+	}
+
+	user, err := loginUser(c.Request.Context(), cred)
+	if err != nil {
+		respondCredentialsError(c, err)
+		return
+	}
+
+	raw, token, err := issueToken(c.Request.Context(), user.ID, models.TokenAPI, cred.Name, 0)
+	if err != nil {
+		respondCredentialsError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"token": raw, "info": token, "user": user})
+}
+
+// apiRevokeTokenHandler отзывает токен, с которым выполнен запрос
+func apiRevokeTokenHandler(c *gin.Context) {
+	if err := revokeToken(c.Request.Context(), bearerToken(c)); err != nil {
+		respondAPIError(c, http.StatusInternalServerError, errCodeStorageError, "Ошибка при отзыве токена: "+err.Error(), "")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
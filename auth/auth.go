@@ -0,0 +1,114 @@
+// Package auth содержит проверку учетных данных пользователей: хеширование
+// паролей (bcrypt), требования к имени и паролю, выпуск токенов доступа.
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Ограничения имени пользователя и пароля. bcrypt учитывает
+// только первые 72 байта пароля, поэтому более длинные пароли отклоняются.
+const (
+	MinUsernameLength = 3
+	MaxUsernameLength = 32
+	MinPasswordLength = 8
+	MaxPasswordLength = 72
+)
+
+// tokenBytes - число случайных байт в токене доступа
+const tokenBytes = 32
+
+// Ошибки проверки учетных данных
+var (
+	ErrInvalidUsername = fmt.Errorf("Имя пользователя должно содержать от %d до %d символов: латинские буквы, цифры, '.', '_' или '-'",
+		MinUsernameLength, MaxUsernameLength)
+	ErrInvalidPassword = fmt.Errorf("Пароль должен содержать от %d до %d байт", MinPasswordLength, MaxPasswordLength)
+	ErrWrongPassword   = errors.New("Неверное имя пользователя или пароль")
+)
+
+// ValidateUsername проверяет имя пользователя
+func ValidateUsername(username string) error {
+	if len(username) < MinUsernameLength || len(username) > MaxUsernameLength {
+		return ErrInvalidUsername
+	}
+	invalid := strings.ContainsFunc(username, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '_' || r == '-')
+	})
+	if invalid {
+		return ErrInvalidUsername
+	}
+	return nil
+}
+
+// ValidatePassword проверяет длину пароля
+func ValidatePassword(password string) error {
+	if len(password) < MinPasswordLength || len(password) > MaxPasswordLength {
+		return ErrInvalidPassword
+	}
+	return nil
+}
+
+// HashPassword возвращает bcrypt-хеш пароля
+func HashPassword(password string) (string, error) {
+	if err := ValidatePassword(password); err != nil {
+		return "", err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// CheckPassword сравнивает пароль с хешем; при несовпадении возвращает ErrWrongPassword
+func CheckPassword(hash, password string) error {
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return ErrWrongPassword
+	}
+	return err
+}
+
+// dummyHash - хеш, с которым сравнивается пароль неизвестного пользователя
+var dummyHash = sync.OnceValue(func() []byte {
+	hash, err := bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+	if err != nil {
+		panic(err)
+	}
+	return hash
+})
+
+// RejectPassword отклоняет вход неизвестного пользователя за то же время,
+// что и проверка настоящего пароля, и всегда возвращает ErrWrongPassword.
+// Так время ответа не выдает, существует ли имя пользователя.
+func RejectPassword(password string) error {
+	_ = bcrypt.CompareHashAndPassword(dummyHash(), []byte(password))
+	return ErrWrongPassword
+}
+
+// NewToken генерирует токен доступа. Токен выдается пользователю,
+// а в хранилище сохраняется только его хеш.
+func NewToken() (token, hash string, err error) {
+	b := make([]byte, tokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, HashToken(token), nil
+}
+
+// HashToken возвращает хеш токена для поиска в хранилище. Токен содержит
+// 256 случайных бит, поэтому достаточно SHA-256 без соли.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestValidateUsername(t *testing.T) {
+	for _, username := range []string{"bob", "alice_01", "j.doe-2", strings.Repeat("a", MaxUsernameLength)} {
+		assert.NoError(t, ValidateUsername(username), username)
+	}
+	for _, username := range []string{"", "ab", "with space", "иван", "a/b", strings.Repeat("a", MaxUsernameLength+1)} {
+		assert.ErrorIs(t, ValidateUsername(username), ErrInvalidUsername, username)
+	}
+}
+
+func TestPassword(t *testing.T) {
+	hash, err := HashPassword("correct horse")
+	require.NoError(t, err)
+	assert.NotEqual(t, "correct horse", hash)
+
+	assert.NoError(t, CheckPassword(hash, "correct horse"))
+	assert.ErrorIs(t, CheckPassword(hash, "wrong horse"), ErrWrongPassword)
+
+	assert.ErrorIs(t, RejectPassword("correct horse"), ErrWrongPassword)
+	cost, err := bcrypt.Cost(dummyHash())
+	require.NoError(t, err)
+	assert.Equal(t, bcrypt.DefaultCost, cost)
+
+	_, err = HashPassword("short")
+	assert.ErrorIs(t, err, ErrInvalidPassword)
+	_, err = HashPassword(strings.Repeat("x", MaxPasswordLength+1))
+	assert.ErrorIs(t, err, ErrInvalidPassword)
+}
+
+func TestNewToken(t *testing.T) {
+	token, hash, err := NewToken()
+	require.NoError(t, err)
+	assert.Len(t, token, 43)
+	assert.Equal(t, HashToken(token), hash)
+
+	other, _, err := NewToken()
+	require.NoError(t, err)
+	assert.NotEqual(t, token, other)
+}
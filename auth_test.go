@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/igor-fedko/go_multiply_app/models"
+	"github.com/igor-fedko/go_multiply_app/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// anonymous выполняет запрос без cookie сессии и токена API
+func (s *APITestSuite) anonymous(req *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	s.app.ServeHTTP(w, req)
+	return w
+}
+
+// sessionCookieFrom возвращает cookie сессии из ответа
+func sessionCookieFrom(w *httptest.ResponseRecorder) *http.Cookie {
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == sessionCookie {
+			return cookie
+		}
+	}
+	return nil
+}
+
+// TestAuthRequired тестирует доступ без входа
+func (s *APITestSuite) TestAuthRequired() {
+	w := s.anonymous(httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(s.T(), http.StatusSeeOther, w.Code)
+	assert.Equal(s.T(), "/login", w.Header().Get("Location"))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/results", nil)
+	w = s.anonymous(req)
+	assert.Equal(s.T(), http.StatusUnauthorized, w.Code)
+	assert.Contains(s.T(), w.Body.String(), `"code":"unauthorized"`)
+
+	// Токен сессии не подходит для API
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/results", nil)
+	req.Header.Set("Authorization", "Bearer "+s.session)
+	assert.Equal(s.T(), http.StatusUnauthorized, s.anonymous(req).Code)
+
+	// Проверки для оркестраторов доступны без входа
+	assert.Equal(s.T(), http.StatusOK, s.anonymous(httptest.NewRequest(http.MethodGet, "/healthz", nil)).Code)
+}
+
+// TestRegisterLoginLogout тестирует регистрацию, вход и выход через HTML формы
+func (s *APITestSuite) TestRegisterLoginLogout() {
+	postForm := func(path string, form url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return s.anonymous(req)
+	}
+
+	w := postForm("/register", url.Values{"username": {"alice"}, "password": {"short"}})
+	assert.Equal(s.T(), http.StatusBadRequest, w.Code)
+	assert.Contains(s.T(), w.Body.String(), "Пароль должен содержать")
+
+	w = postForm("/register", url.Values{"username": {"tester"}, "password": {"long enough"}})
+	assert.Equal(s.T(), http.StatusConflict, w.Code)
+
+	w = postForm("/register", url.Values{"username": {"alice"}, "password": {"long enough"}})
+	require.Equal(s.T(), http.StatusSeeOther, w.Code)
+	cookie := sessionCookieFrom(w)
+	require.NotNil(s.T(), cookie)
+	assert.True(s.T(), cookie.HttpOnly)
+
+	w = postForm("/login", url.Values{"username": {"alice"}, "password": {"wrong password"}})
+	assert.Equal(s.T(), http.StatusUnauthorized, w.Code)
+	assert.Contains(s.T(), w.Body.String(), "Неверное имя пользователя или пароль")
+
+	w = postForm("/login", url.Values{"username": {"alice"}, "password": {"long enough"}})
+	require.Equal(s.T(), http.StatusSeeOther, w.Code)
+	cookie = sessionCookieFrom(w)
+	require.NotNil(s.T(), cookie)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(cookie)
+	w = s.anonymous(req)
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	assert.Contains(s.T(), w.Body.String(), "alice")
+
+	// После выхода сессия недействительна
+	req = httptest.NewRequest(http.MethodPost, "/logout", nil)
+	req.AddCookie(cookie)
+	assert.Equal(s.T(), http.StatusSeeOther, s.anonymous(req).Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(cookie)
+	assert.Equal(s.T(), http.StatusSeeOther, s.anonymous(req).Code)
+}
+
+// TestAPITokens тестирует регистрацию, выпуск и отзыв токена через JSON API
+func (s *APITestSuite) TestAPITokens() {
+	postJSON := func(path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		return s.anonymous(req)
+	}
+
+	w := postJSON("/api/v1/users", `{"username": "a b", "password": "long enough"}`)
+	assert.Equal(s.T(), http.StatusBadRequest, w.Code)
+	assert.Contains(s.T(), w.Body.String(), `"field":"username"`)
+
+	w = postJSON("/api/v1/users", `{"username": "bob", "password": "long enough"}`)
+	require.Equal(s.T(), http.StatusCreated, w.Code)
+	assert.NotContains(s.T(), w.Body.String(), "password")
+
+	w = postJSON("/api/v1/tokens", `{"username": "bob", "password": "wrong password"}`)
+	assert.Equal(s.T(), http.StatusUnauthorized, w.Code)
+
+	w = postJSON("/api/v1/tokens", `{"username": "bob", "password": "long enough", "name": "ci"}`)
+	require.Equal(s.T(), http.StatusCreated, w.Code)
+	var response struct {
+		Token string       `json:"token"`
+		Info  models.Token `json:"info"`
+	}
+	require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &response))
+	require.NotEmpty(s.T(), response.Token)
+	assert.Equal(s.T(), "ci", response.Info.Name)
+
+	// Результат сохраняется с ID пользователя
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/add", strings.NewReader(`{"number1": 1, "number2": 2}`))
+	req.Header.Set("Authorization", "Bearer "+response.Token)
+	w = s.anonymous(req)
+	require.Equal(s.T(), http.StatusCreated, w.Code)
+	var result models.Result
+	require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(s.T(), response.Info.UserID, result.UserID)
+
+	logs := s.logs()
+	require.Len(s.T(), logs, 1)
+	assert.Equal(s.T(), response.Info.UserID, logs[0].UserID)
+
+	// Отозванный токен больше не действует
+	req = httptest.NewRequest(http.MethodDelete, "/api/v1/tokens/current", nil)
+	req.Header.Set("Authorization", "Bearer "+response.Token)
+	assert.Equal(s.T(), http.StatusNoContent, s.anonymous(req).Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/results", nil)
+	req.Header.Set("Authorization", "Bearer "+response.Token)
+	assert.Equal(s.T(), http.StatusUnauthorized, s.anonymous(req).Code)
+}
+
+// TestHistoryIsolation тестирует видимость истории: пользователь видит
+// только свои результаты, администратор - любые
+func (s *APITestSuite) TestHistoryIsolation() {
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	other := models.User{Username: "other", PasswordHash: "-", Role: models.RoleUser}
+	require.NoError(s.T(), s.store.CreateUser(context.Background(), &other))
+	s.seedResults("add", 2, start)
+	s.seedUserResults(other.ID, "add", 3, start)
+
+	var response struct {
+		Results    []models.Result `json:"results"`
+		Pagination pagination      `json:"pagination"`
+	}
+	w := s.get("/api/v1/results")
+	require.Equal(s.T(), http.StatusOK, w.Code)
+	require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(s.T(), int64(2), response.Pagination.Total)
+
+	// Чужой результат недоступен ни списком, ни по идентификатору
+	foreign, err := s.store.ListResults(context.Background(), storage.ResultQuery{Scope: storage.UserScope(other.ID)})
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), http.StatusNotFound, s.get("/api/v1/results/"+foreign[0].ID.Hex()).Code)
+
+	w = s.get("/api/v1/results?user=other")
+	assert.Equal(s.T(), http.StatusForbidden, w.Code)
+	assert.Contains(s.T(), w.Body.String(), `"code":"forbidden"`)
+	assert.Equal(s.T(), http.StatusForbidden, s.get("/?user=*").Code)
+
+	// Администратор видит историю всех пользователей и конкретного пользователя
+	require.NoError(s.T(), s.store.SetUserRole(context.Background(), s.user.Username, models.RoleAdmin))
+
+	w = s.get("/api/v1/results")
+	require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(s.T(), int64(2), response.Pagination.Total)
+
+	w = s.get("/api/v1/results?user=*")
+	require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(s.T(), int64(5), response.Pagination.Total)
+
+	w = s.get("/api/v1/results?user=other")
+	require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(s.T(), int64(3), response.Pagination.Total)
+
+	w = s.get("/api/v1/results?user=nobody")
+	assert.Equal(s.T(), http.StatusBadRequest, w.Code)
+	assert.Contains(s.T(), w.Body.String(), `"field":"user"`)
+
+	assert.Equal(s.T(), http.StatusOK, s.get("/api/v1/results/"+foreign[0].ID.Hex()).Code)
+
+	w = s.get("/?user=*")
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	assert.Contains(s.T(), w.Body.String(), "всего записей: 5")
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/igor-fedko/go_multiply_app/metrics"
+	"github.com/igor-fedko/go_multiply_app/models"
+	"github.com/igor-fedko/go_multiply_app/operations"
+	"github.com/igor-fedko/go_multiply_app/storage"
+)
+
+// maxBatchItems ограничивает число вычислений в одном пакете
+const maxBatchItems = 10000
+
+// maxBatchSize ограничивает размер тела пакетного запроса в байтах
+const maxBatchSize = 10 << 20
+
+// maxNDJSONLine ограничивает длину строки NDJSON
+const maxNDJSONLine = 64 * 1024
+
+// ndjsonContentType - тип содержимого пакета в формате NDJSON
+// (одно вычисление на строку)
+const ndjsonContentType = "application/x-ndjson"
+
+// Коды ошибок пакетных вычислений
+const (
+	errCodeBatchTooLarge     = "batch_too_large"
+	errCodeEmptyBatch        = "empty_batch"
+	errCodeUnknownOperation  = "unknown_operation"
+	errCodeInvalidOperandNum = "invalid_operand_count"
+)
+
+// errBatchTooLarge возвращается, если пакет больше maxBatchItems
+var errBatchTooLarge = fmt.Errorf("Пакет содержит больше %d вычислений", maxBatchItems)
+
+// batchItem - вычисление в пакете
+type batchItem struct {
+	Operation string            `json:"operation"`
+	Operands  []json.RawMessage `json:"operands"`
+	Precision string            `json:"precision,omitempty"`
+}
+
+// batchItemResult - результат вычисления в пакете: сохраненный результат
+// или ошибка. Index - номер вычисления в пакете, начиная с 0.
+type batchItemResult struct {
+	Index  int            `json:"index"`
+	Result *models.Result `json:"result,omitempty"`
+	Error  *apiError      `json:"error,omitempty"`
+}
+
+// batchSummary - итоги пакета
+type batchSummary struct {
+	Total     int `json:"total"`
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+}
+
+// preparedItem - разобранное вычисление пакета. name и input - операция
+// и исходные значения операндов для журнала неудачной попытки.
+type preparedItem struct {
+	op       operations.Operation
+	operands []float64
+	exact    []*big.Rat
+	name     string
+	input    string
+}
+
+// readBatch читает элементы пакета из тела запроса: JSON массив или NDJSON.
+// Ошибка разбора отдельной строки NDJSON не прерывает чтение - строка
+// возвращается как есть и получает ошибку при разборе элемента. Тело
+// длиннее maxBatchSize не дочитывается: возвращается *http.MaxBytesError.
+func readBatch(c *gin.Context) ([]json.RawMessage, error) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBatchSize)
+	mediaType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	if mediaType == ndjsonContentType {
+		return readNDJSON(c.Request.Body)
+	}
+	return readJSONArray(c.Request.Body)
+}
+
+// readNDJSON читает элементы по одному на строку, пропуская пустые строки
+func readNDJSON(r io.Reader) ([]json.RawMessage, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), maxNDJSONLine)
+
+	var items []json.RawMessage
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if len(items) == maxBatchItems {
+			return nil, errBatchTooLarge
+		}
+		items = append(items, bytes.Clone(line))
+	}
+	if err := scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return nil, fmt.Errorf("Строка NDJSON длиннее %d байт", maxNDJSONLine)
+		}
+		return nil, err
+	}
+	return items, nil
+}
+
+// readJSONArray читает элементы JSON массива по одному
+func readJSONArray(r io.Reader) ([]json.RawMessage, error) {
+	dec := json.NewDecoder(r)
+	tok, err := dec.Token()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		return nil, errors.New("ожидается массив вычислений")
+	}
+
+	var items []json.RawMessage
+	for dec.More() {
+		if len(items) == maxBatchItems {
+			return nil, errBatchTooLarge
+		}
+		var item json.RawMessage
+		if err := dec.Decode(&item); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// prepareBatchItem разбирает вычисление пакета. Ошибка возвращается
+// в формате JSON API; если операция известна, она заполнена и при ошибке.
+func prepareBatchItem(raw json.RawMessage) (preparedItem, *apiError) {
+	var item batchItem
+	if err := json.Unmarshal(raw, &item); err != nil {
+		return preparedItem{}, &apiError{Code: errCodeInvalidJSON, Message: "Некорректный JSON: " + err.Error()}
+	}
+
+	rawOperands := make([]string, len(item.Operands))
+	for i, raw := range item.Operands {
+		rawOperands[i] = rawOperandText(raw)
+	}
+	prepared := preparedItem{name: item.Operation, input: rawOperandsInput(rawOperands)}
+
+	op, ok := operations.Default.Get(item.Operation)
+	if !ok {
+		return prepared, &apiError{
+			Code:    errCodeUnknownOperation,
+			Message: fmt.Sprintf("Неизвестная операция %q", item.Operation),
+			Field:   "operation",
+		}
+	}
+	prepared.op = op
+
+	precision, err := parsePrecision(item.Precision)
+	if err != nil {
+		return prepared, &apiError{Code: errCodeInvalidPrecision, Message: err.Error(), Field: "precision"}
+	}
+
+	if len(item.Operands) != op.Arity() {
+		return prepared, &apiError{
+			Code:    errCodeInvalidOperandNum,
+			Message: fmt.Sprintf("Операция %s принимает операндов: %d", op.Name(), op.Arity()),
+			Field:   "operands",
+		}
+	}
+
+	prepared.operands = make([]float64, op.Arity())
+	for i, raw := range item.Operands {
+		field := fmt.Sprintf("operands[%d]", i)
+		if string(raw) == "null" {
+			return prepared, &apiError{Code: errCodeMissingOperand, Message: "Не указан операнд " + field, Field: field}
+		}
+		value, exactValue, err := parseJSONOperand(raw, precision, i)
+		if numErr, ok := numericError(err); ok {
+			return prepared, &apiError{Code: numErr.Code, Message: numErr.Message, Field: field}
+		}
+		if err != nil {
+			return prepared, &apiError{Code: errCodeInvalidNumber, Message: invalidOperandMessage(op, i), Field: field}
+		}
+		prepared.operands[i] = value
+		if exactValue != nil {
+			prepared.exact = append(prepared.exact, exactValue)
+		}
+	}
+	return prepared, nil
+}
+
+// apiBatchHandler вычисляет пакет операций. Каждое вычисление проверяется
+// отдельно: ошибка в одном не мешает остальным. Успешные результаты
+// сохраняются одним обращением к хранилищу.
+func apiBatchHandler(c *gin.Context) {
+	items, err := readBatch(c)
+	if errors.Is(err, errBatchTooLarge) {
+		respondAPIError(c, http.StatusRequestEntityTooLarge, errCodeBatchTooLarge, err.Error(), "")
+		return
+	}
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		respondAPIError(c, http.StatusRequestEntityTooLarge, errCodeBatchTooLarge, fmt.Sprintf("Пакет больше %d МБ", maxBatchSize>>20), "")
+		return
+	}
+	if err != nil {
+		respondAPIError(c, http.StatusBadRequest, errCodeInvalidJSON, "Некорректный пакет: "+err.Error(), "")
+		return
+	}
+	if len(items) == 0 {
+		respondAPIError(c, http.StatusBadRequest, errCodeEmptyBatch, "Пакет не содержит вычислений", "")
+		return
+	}
+
+	responses := make([]batchItemResult, len(items))
+	prepared := make([]preparedItem, len(items))
+	var pending []*models.Result
+	var pendingIndex []int
+	started := time.Now()
+
+	for i, raw := range items {
+		responses[i].Index = i
+
+		item, apiErr := prepareBatchItem(raw)
+		if apiErr != nil {
+			if item.op != nil {
+				appMetrics.ObserveOperation(item.op.Name(), metrics.OutcomeInvalidInput)
+			}
+			logOperationFailure(c, item.name, item.input, models.LogStatusValidationError, apiErr, started)
+			responses[i].Error = apiErr
+			continue
+		}
+		prepared[i] = item
+
+		result, err := computeOperation(c, item.op, item.operands, item.exact)
+		if err != nil {
+			appMetrics.ObserveOperation(item.op.Name(), operationOutcome(err))
+			logOperationFailure(c, item.op.Name(), item.op.Format(formatOperands(item.operands)), logStatus(err), err, started)
+			var validationErr *operations.ValidationError
+			if errors.As(err, &validationErr) {
+				responses[i].Error = &apiError{Code: validationErr.Code, Message: validationErr.Message}
+				if validationErr.Operand >= 0 {
+					responses[i].Error.Field = fmt.Sprintf("operands[%d]", validationErr.Operand)
+				}
+				continue
+			}
+			responses[i].Error = &apiError{Code: errCodeStorageError, Message: err.Error()}
+			continue
+		}
+		pending = append(pending, &result)
+		pendingIndex = append(pendingIndex, i)
+	}
+
+	// Сохраняем результаты в хранилище
+	ctx, cancel := context.WithTimeout(context.Background(), appConfig.Timeouts.Write.Duration)
+	defer cancel()
+
+	// При частичной записи сохраненные результаты остаются успешными
+	storeErr := store.InsertResults(ctx, pending)
+	saved := len(pending)
+	if storeErr != nil {
+		saved = storage.InsertedBefore(storeErr)
+		slog.ErrorContext(c.Request.Context(), "store batch results", "error", storeErr, "count", len(pending), "saved", saved)
+	}
+
+	for j, result := range pending {
+		i := pendingIndex[j]
+		item := prepared[i]
+		if j >= saved {
+			appMetrics.ObserveOperation(item.op.Name(), metrics.OutcomeStorageError)
+			logOperationFailure(c, item.op.Name(), item.op.Format(formatOperands(item.operands)), models.LogStatusStorageError, storeErr, started)
+			responses[i].Error = &apiError{Code: errCodeStorageError, Message: "Ошибка при сохранении результата: " + storeErr.Error()}
+			continue
+		}
+		appMetrics.ObserveOperation(item.op.Name(), metrics.OutcomeSuccess)
+		logOperationResult(c, item.op, item.operands, *result, started)
+		responses[i].Result = result
+	}
+
+	// Ошибки уже записаны в журнал на русском языке; клиенту они
+	// возвращаются на языке запроса
+	p := printer(c)
+	summary := batchSummary{Total: len(items)}
+	for i, r := range responses {
+		if r.Error != nil {
+			localized := *r.Error
+			localized.Message = p.Translate(localized.Message)
+			responses[i].Error = &localized
+			summary.Failed++
+		} else {
+			summary.Succeeded++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"results": responses, "summary": summary})
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/igor-fedko/go_multiply_app/models"
+	"github.com/igor-fedko/go_multiply_app/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// batchResponse - ответ POST /api/v1/batch
+type batchResponse struct {
+	Results []batchItemResult `json:"results"`
+	Summary batchSummary      `json:"summary"`
+}
+
+// postBatch отправляет пакет с типом содержимого contentType
+func (s *APITestSuite) postBatch(contentType, body string) (*httptest.ResponseRecorder, batchResponse) {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/batch", strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	w := s.do(req)
+
+	var resp batchResponse
+	if w.Code == http.StatusOK {
+		require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &resp))
+	}
+	return w, resp
+}
+
+// TestBatch тестирует пакетное вычисление с ошибками в отдельных элементах
+func (s *APITestSuite) TestBatch() {
+	w, resp := s.postBatch("application/json", `[
+		{"operation": "multiply", "operands": [2, 3]},
+		{"operation": "divide", "operands": [1, 0]},
+		{"operation": "modulo", "operands": [1, 2]},
+		{"operation": "square", "operands": [4, 5]},
+		{"operation": "add", "operands": ["x", 1]},
+		{"operation": "add", "operands": ["0.1", "0.2"], "precision": "exact"},
+		42
+	]`)
+	require.Equal(s.T(), http.StatusOK, w.Code)
+
+	assert.Equal(s.T(), batchSummary{Total: 7, Succeeded: 2, Failed: 5}, resp.Summary)
+	require.Len(s.T(), resp.Results, 7)
+
+	require.NotNil(s.T(), resp.Results[0].Result)
+	assert.Equal(s.T(), 6.0, resp.Results[0].Result.Result)
+	assert.False(s.T(), resp.Results[0].Result.ID.IsZero())
+
+	codes := make([]string, len(resp.Results))
+	for i, r := range resp.Results {
+		assert.Equal(s.T(), i, r.Index)
+		if r.Error != nil {
+			codes[i] = r.Error.Code
+		}
+	}
+	assert.Equal(s.T(), []string{"", "division_by_zero", "unknown_operation", "invalid_operand_count", "invalid_number", "", "invalid_json"}, codes)
+	assert.Equal(s.T(), "operands[1]", resp.Results[1].Error.Field)
+	assert.Equal(s.T(), "operands[0]", resp.Results[4].Error.Field)
+	assert.Equal(s.T(), "0.3", resp.Results[5].Result.ResultExact)
+
+	// Сохранены только успешные вычисления, от имени пользователя
+	results, err := s.store.ListResults(context.Background(), storage.ResultQuery{Scope: storage.UserScope(s.user.ID)})
+	require.NoError(s.T(), err)
+	assert.Len(s.T(), results, 2)
+
+	// В журнал записана каждая попытка
+	statuses := map[string]int{}
+	for _, entry := range s.logs() {
+		statuses[entry.Status]++
+	}
+	assert.Equal(s.T(), map[string]int{models.LogStatusSuccess: 2, models.LogStatusValidationError: 5}, statuses)
+}
+
+// TestBatchNDJSON тестирует пакет в формате NDJSON
+func (s *APITestSuite) TestBatchNDJSON() {
+	var body strings.Builder
+	for i := 1; i <= 100; i++ {
+		fmt.Fprintf(&body, `{"operation": "multiply", "operands": [%d, 2]}`+"\n", i)
+	}
+	body.WriteString("\n{not json}\n")
+
+	w, resp := s.postBatch("application/x-ndjson; charset=utf-8", body.String())
+	require.Equal(s.T(), http.StatusOK, w.Code)
+	assert.Equal(s.T(), batchSummary{Total: 101, Succeeded: 100, Failed: 1}, resp.Summary)
+	assert.Equal(s.T(), 200.0, resp.Results[99].Result.Result)
+	assert.Equal(s.T(), "invalid_json", resp.Results[100].Error.Code)
+
+	count, err := s.store.CountResults(context.Background(), storage.ResultQuery{Scope: storage.AllUsers(), Operation: "multiply"})
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), int64(100), count)
+}
+
+// partialStore сохраняет только первые saved результатов среза,
+// как прерванный insertMany в MongoDB
+type partialStore struct {
+	storage.Store
+	saved int
+}
+
+func (s partialStore) InsertResults(ctx context.Context, results []*models.Result) error {
+	if err := s.Store.InsertResults(ctx, results[:s.saved]); err != nil {
+		return err
+	}
+	return &storage.PartialInsertError{Inserted: s.saved, Err: errors.New("connection reset")}
+}
+
+// TestBatchPartialInsert тестирует пакет, сохраненный хранилищем частично:
+// сохраненные результаты остаются успешными
+func (s *APITestSuite) TestBatchPartialInsert() {
+	store = partialStore{Store: store, saved: 2}
+
+	w, resp := s.postBatch("application/json", `[
+		{"operation": "multiply", "operands": [2, 3]},
+		{"operation": "divide", "operands": [1, 0]},
+		{"operation": "add", "operands": [1, 2]},
+		{"operation": "subtract", "operands": [5, 1]}
+	]`)
+	require.Equal(s.T(), http.StatusOK, w.Code)
+	assert.Equal(s.T(), batchSummary{Total: 4, Succeeded: 2, Failed: 2}, resp.Summary)
+	assert.NotNil(s.T(), resp.Results[0].Result)
+	assert.NotNil(s.T(), resp.Results[2].Result)
+	require.NotNil(s.T(), resp.Results[3].Error)
+	assert.Equal(s.T(), "storage_error", resp.Results[3].Error.Code)
+
+	count, err := s.store.CountResults(context.Background(), storage.ResultQuery{Scope: storage.AllUsers()})
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), int64(2), count)
+}
+
+// TestBatchInvalid тестирует ошибки пакета целиком
+func (s *APITestSuite) TestBatchInvalid() {
+	tests := map[string]struct {
+		contentType, body string
+		status            int
+		code              string
+	}{
+		"empty":           {"application/json", `[]`, http.StatusBadRequest, "empty_batch"},
+		"no body":         {"application/json", ``, http.StatusBadRequest, "empty_batch"},
+		"object":          {"application/json", `{"operation": "add"}`, http.StatusBadRequest, "invalid_json"},
+		"truncated":       {"application/json", `[{"operation": "add"`, http.StatusBadRequest, "invalid_json"},
+		"too large":       {"application/x-ndjson", strings.Repeat("{}\n", maxBatchItems+1), http.StatusRequestEntityTooLarge, "batch_too_large"},
+		"too long":        {"application/json", "[" + strings.Repeat(" ", maxBatchSize) + "]", http.StatusRequestEntityTooLarge, "batch_too_large"},
+		"too long ndjson": {"application/x-ndjson", strings.Repeat("\n", maxBatchSize+1), http.StatusRequestEntityTooLarge, "batch_too_large"},
+	}
+
+	for name, tt := range tests {
+		w, _ := s.postBatch(tt.contentType, tt.body)
+		assert.Equal(s.T(), tt.status, w.Code, name)
+		assert.Contains(s.T(), w.Body.String(), `"code":"`+tt.code+`"`, name)
+	}
+}
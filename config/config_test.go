@@ -0,0 +1,179 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/igor-fedko/go_multiply_app/numfmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func envMap(m map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		v, ok := m[key]
+		return v, ok
+	}
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+	cfg, err := load("", envMap(nil))
+	require.NoError(t, err)
+
+	assert.Equal(t, ":8080", cfg.Server.Addr)
+	assert.Equal(t, "multiply_app", cfg.Mongo.Database)
+	assert.Equal(t, 5*time.Second, cfg.Timeouts.Write.Duration)
+	assert.Equal(t, 10*time.Second, cfg.Timeouts.Read.Duration)
+	assert.Equal(t, 7*24*time.Hour, cfg.Auth.SessionTTL.Duration)
+	assert.False(t, cfg.Auth.CookieSecure)
+}
+
+func TestLoadYAMLWithEnvOverride(t *testing.T) {
+	path := writeFile(t, "config.yaml", `
+server:
+  addr: ":9090"
+  gin_mode: release
+mongo:
+  uri: mongodb://localhost:27017
+  database: calc
+timeouts:
+  write: 2s
+`)
+
+	cfg, err := load(path, envMap(map[string]string{
+		"APP_MONGO_DATABASE": "calc_ci",
+		"APP_TIMEOUTS_READ":  "1500ms",
+	}))
+	require.NoError(t, err)
+
+	assert.Equal(t, ":9090", cfg.Server.Addr)
+	assert.Equal(t, "release", cfg.Server.GinMode)
+	assert.Equal(t, "mongodb://localhost:27017", cfg.Mongo.URI)
+	assert.Equal(t, "calc_ci", cfg.Mongo.Database)
+	assert.Equal(t, "results", cfg.Mongo.ResultsCollection)
+	assert.Equal(t, 2*time.Second, cfg.Timeouts.Write.Duration)
+	assert.Equal(t, 1500*time.Millisecond, cfg.Timeouts.Read.Duration)
+}
+
+func TestLoadTOML(t *testing.T) {
+	path := writeFile(t, "config.toml", `
+[server]
+addr = ":7070"
+
+[timeouts]
+connect = "30s"
+`)
+
+	cfg, err := load(path, envMap(nil))
+	require.NoError(t, err)
+
+	assert.Equal(t, ":7070", cfg.Server.Addr)
+	assert.Equal(t, 30*time.Second, cfg.Timeouts.Connect.Duration)
+}
+
+func TestLoadRejectsUnknownFields(t *testing.T) {
+	path := writeFile(t, "config.yaml", "server:\n  port: 8080\n")
+
+	_, err := load(path, envMap(nil))
+	assert.Error(t, err)
+}
+
+func TestLoadValidation(t *testing.T) {
+	tests := map[string]map[string]string{
+		"gin mode":     {"APP_SERVER_GIN_MODE": "verbose"},
+		"mongo uri":    {"APP_MONGO_URI": "localhost:27017"},
+		"empty db":     {"APP_MONGO_DATABASE": ""},
+		"zero timeout": {"APP_TIMEOUTS_WRITE": "0s"},
+		"bad duration": {"APP_TIMEOUTS_READ": "soon"},
+		"bad backend":  {"APP_STORAGE_BACKEND": "redis"},
+		"bad driver":   {"APP_STORAGE_BACKEND": "sql", "APP_SQL_DRIVER": "oracle"},
+		"bad level":    {"APP_LOG_LEVEL": "verbose"},
+		"bad format":   {"APP_LOG_FORMAT": "xml"},
+		"zero session": {"APP_AUTH_SESSION_TTL": "0s"},
+		"rate backend": {"APP_RATE_LIMIT_BACKEND": "redis"},
+		"rate mongo":   {"APP_STORAGE_BACKEND": "memory", "APP_RATE_LIMIT_BACKEND": "mongo"},
+		"rate per":     {"APP_RATE_LIMIT_PER": "0s"},
+	}
+
+	for name, env := range tests {
+		_, err := load("", envMap(env))
+		assert.Error(t, err, name)
+	}
+}
+
+func TestLoadMemoryBackendSkipsMongoValidation(t *testing.T) {
+	cfg, err := load("", envMap(map[string]string{
+		"APP_STORAGE_BACKEND": "memory",
+		"APP_MONGO_URI":       "",
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, BackendMemory, cfg.Storage.Backend)
+}
+
+func TestLoadMigrateOnStart(t *testing.T) {
+	cfg, err := load("", envMap(nil))
+	require.NoError(t, err)
+	assert.True(t, cfg.Storage.MigrateOnStart)
+
+	cfg, err = load("", envMap(map[string]string{"APP_STORAGE_MIGRATE_ON_START": "false"}))
+	require.NoError(t, err)
+	assert.False(t, cfg.Storage.MigrateOnStart)
+}
+
+func TestLoadRateLimitRoutes(t *testing.T) {
+	path := writeFile(t, "config.yaml", `
+rate_limit:
+  requests: 100
+  routes:
+    multiply:
+      requests: 10
+      per: 1s
+    /api/v1/results:
+      requests: 0
+`)
+
+	cfg, err := load(path, envMap(map[string]string{"APP_RATE_LIMIT_PER": "30s"}))
+	require.NoError(t, err)
+
+	assert.True(t, cfg.RateLimit.Enabled)
+	assert.Equal(t, RateLimitMemory, cfg.RateLimit.Backend)
+	assert.Equal(t, 100, cfg.RateLimit.Requests)
+	assert.Equal(t, 30*time.Second, cfg.RateLimit.Per.Duration)
+	assert.Equal(t, RouteLimit{Requests: 10, Per: Duration{time.Second}}, cfg.RateLimit.Routes["multiply"])
+	assert.Equal(t, 0, cfg.RateLimit.Routes["/api/v1/results"].Requests)
+}
+
+func TestLoadRateLimitDisabledSkipsValidation(t *testing.T) {
+	_, err := load("", envMap(map[string]string{
+		"APP_RATE_LIMIT_ENABLED": "false",
+		"APP_RATE_LIMIT_BACKEND": "redis",
+	}))
+	assert.NoError(t, err)
+}
+
+func TestLoadNumberFormat(t *testing.T) {
+	cfg, err := load("", envMap(nil))
+	require.NoError(t, err)
+	assert.Equal(t, numfmt.Default, cfg.NumberFormat.Format())
+
+	path := writeFile(t, "config.toml", `
+[number_format]
+notation = "fixed"
+precision = 2
+`)
+	cfg, err = load(path, envMap(map[string]string{"APP_NUMBER_FORMAT_ROUNDING": "half_up"}))
+	require.NoError(t, err)
+	assert.Equal(t, numfmt.Format{Notation: numfmt.Fixed, Precision: 2, Rounding: numfmt.HalfUp}, cfg.NumberFormat.Format())
+
+	_, err = load("", envMap(map[string]string{"APP_NUMBER_FORMAT_NOTATION": "roman"}))
+	assert.ErrorContains(t, err, "number_format.notation")
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/igor-fedko/go_multiply_app/expr"
+	"github.com/igor-fedko/go_multiply_app/metrics"
+	"github.com/igor-fedko/go_multiply_app/models"
+)
+
+// evaluateRequest - тело запроса POST /api/v1/evaluate
+type evaluateRequest struct {
+	Expression string `json:"expression"`
+}
+
+// performEvaluation разбирает и вычисляет выражение, сохраняет результат и логирует его.
+// Неудачная попытка тоже записывается в журнал.
+// Ошибки разбора и вычисления возвращаются как *expr.Error.
+func performEvaluation(c *gin.Context, src string) (_ models.Result, err error) {
+	started := time.Now()
+	defer func() { appMetrics.ObserveOperation(models.OperationEvaluate, operationOutcome(err)) }()
+	defer func() {
+		if err != nil {
+			logOperationFailure(c, models.OperationEvaluate, src, logStatus(err), err, started)
+		}
+	}()
+
+	result, err := computeEvaluation(c, src)
+	if err != nil {
+		return models.Result{}, err
+	}
+
+	// Сохраняем результат в хранилище
+	ctx, cancel := context.WithTimeout(context.Background(), appConfig.Timeouts.Write.Duration)
+	defer cancel()
+
+	if err = store.InsertResult(ctx, &result); err != nil {
+		slog.ErrorContext(c.Request.Context(), "store result", "error", err, "operation", models.OperationEvaluate)
+		return models.Result{}, err
+	}
+
+	// Логируем операцию
+	logOperation(c, models.LogEntry{
+		Operation: models.OperationEvaluate,
+		Input:     result.Normalized,
+		Result:    numberFormat().Float(result.Result),
+		ResultID:  result.ID,
+		Status:    models.LogStatusSuccess,
+	}, started)
+
+	return result, nil
+}
+
+// computeEvaluation разбирает и вычисляет выражение и возвращает
+// несохраненный результат
+func computeEvaluation(c *gin.Context, src string) (models.Result, error) {
+	node, value, err := expr.Evaluate(src, expr.DefaultLimits)
+	if err != nil {
+		return models.Result{}, err
+	}
+
+	return models.Result{
+		SchemaVersion: models.ResultSchemaVersion,
+		Result:        value,
+		Operation:     models.OperationEvaluate,
+		Kind:          models.KindExpression,
+		Precision:     models.PrecisionFloat,
+		Expression:    src,
+		Normalized:    node.String(),
+		CreatedAt:     time.Now().UTC(),
+		UserID:        currentUserID(c),
+	}, nil
+}
+
+// exprErrorStatus возвращает HTTP статус для ошибки выражения:
+// ошибки записи - 400, ошибки вычисления - 422
+func exprErrorStatus(err *expr.Error) int {
+	switch err.Code {
+	case expr.CodeDivisionByZero, expr.CodeDomain, expr.CodeNonFinite, expr.CodeOverflow, expr.CodeUnderflow:
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+// Обработчик вычисления выражения из HTML-формы
+func evaluateHandler(c *gin.Context) {
+	if _, err := performEvaluation(c, c.PostForm("expression")); err != nil {
+		var exprErr *expr.Error
+		if errors.As(err, &exprErr) {
+			renderIndex(c, exprErrorStatus(exprErr), gin.H{
+				"Error":      "Ошибка в выражении: " + exprErr.Error(),
+				"Expression": c.PostForm("expression"),
+			})
+			return
+		}
+		renderIndex(c, http.StatusInternalServerError, gin.H{
+			"Error": "Ошибка при сохранении результата: " + err.Error(),
+		})
+		return
+	}
+
+	// Перенаправляем на главную страницу
+	c.Redirect(http.StatusSeeOther, "/")
+}
+
+// apiEvaluateHandler вычисляет выражение, переданное в JSON
+func apiEvaluateHandler(c *gin.Context) {
+	started := time.Now()
+	var req evaluateRequest
+	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
+		message := "Некорректный JSON: " + err.Error()
+		appMetrics.ObserveOperation(models.OperationEvaluate, metrics.OutcomeInvalidInput)
+		logOperationFailure(c, models.OperationEvaluate, "", models.LogStatusValidationError,
+			&apiError{Code: errCodeInvalidJSON, Message: message}, started)
+		respondAPIError(c, http.StatusBadRequest, errCodeInvalidJSON, message, "")
+		return
+	}
+
+	result, err := performEvaluation(c, req.Expression)
+	if err != nil {
+		var exprErr *expr.Error
+		if errors.As(err, &exprErr) {
+			respondAPIError(c, exprErrorStatus(exprErr), exprErr.Code, exprErr.Error(), "expression")
+			return
+		}
+		respondAPIError(c, http.StatusInternalServerError, errCodeStorageError,
+			"Ошибка при сохранении результата: "+err.Error(), "")
+		return
+	}
+
+	c.JSON(http.StatusCreated, result)
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/igor-fedko/go_multiply_app/models"
+	"github.com/igor-fedko/go_multiply_app/numfmt"
+	"github.com/igor-fedko/go_multiply_app/operations"
+)
+
+// Форматы выгрузки истории
+const (
+	exportCSV    = "csv"
+	exportJSON   = "json"
+	exportNDJSON = "ndjson"
+)
+
+// exportFlushEvery - через сколько записей выгрузка отправляется клиенту
+const exportFlushEvery = 500
+
+// exportContentTypes - тип содержимого для каждого формата выгрузки
+var exportContentTypes = map[string]string{
+	exportCSV:    "text/csv; charset=utf-8",
+	exportJSON:   "application/json; charset=utf-8",
+	exportNDJSON: ndjsonContentType,
+}
+
+// exportColumns - столбцы выгрузки CSV. Операнды выгружаются в столбцы
+// number1..numberN и number1_exact..numberN_exact, где N - operations.MaxArity;
+// у операций с меньшим числом операндов и выражений лишние столбцы пусты.
+// Столбцы number1_formatted..numberN_formatted и result_formatted повторяют
+// числа по формату number_format для чтения человеком; импорт их не читает.
+var exportColumns = csvColumns(operations.MaxArity)
+
+// csvColumns возвращает столбцы выгрузки CSV для n операндов
+func csvColumns(n int) []string {
+	columns := []string{"id", "operation", "kind", "precision"}
+	for i := 0; i < n; i++ {
+		columns = append(columns, operandField(i))
+	}
+	columns = append(columns, "result")
+	for i := 0; i < n; i++ {
+		columns = append(columns, operandField(i)+"_exact")
+	}
+	columns = append(columns, "result_exact", "expression", "normalized", "created_at", "user_id")
+	for i := 0; i < n; i++ {
+		columns = append(columns, operandField(i)+"_formatted")
+	}
+	return append(columns, "result_formatted")
+}
+
+// exportEncoder записывает результаты в формате выгрузки
+type exportEncoder interface {
+	// Begin записывает начало выгрузки (заголовок CSV, "[" массива JSON)
+	Begin() error
+	// Encode записывает результат
+	Encode(result models.Result) error
+	// Flush передает буферизованные записи в w
+	Flush() error
+	// End записывает конец выгрузки и сбрасывает буферы
+	End() error
+}
+
+// parseExportFormat проверяет параметр format; по умолчанию - CSV
+func parseExportFormat(c *gin.Context) (string, error) {
+	format := c.DefaultQuery("format", exportCSV)
+	if _, ok := exportContentTypes[format]; !ok {
+		return "", &queryError{Field: "format", Message: "Формат выгрузки должен быть csv, json или ndjson"}
+	}
+	return format, nil
+}
+
+// newExportEncoder создает кодировщик формата format
+func newExportEncoder(w io.Writer, format string) exportEncoder {
+	switch format {
+	case exportJSON:
+		return &jsonExporter{w: w}
+	case exportNDJSON:
+		return &ndjsonExporter{enc: json.NewEncoder(w)}
+	default:
+		return &csvExporter{w: csv.NewWriter(w), format: numberFormat()}
+	}
+}
+
+// exportFilename возвращает имя файла выгрузки, например results-20240115-103000.csv
+func exportFilename(format string, now time.Time) string {
+	return "results-" + now.UTC().Format("20060102-150405") + "." + format
+}
+
+// exportResults выгружает историю с фильтрами и сортировкой q целиком,
+// без постраничного разбиения. Результаты читаются из хранилища курсором
+// и сразу отправляются клиенту, поэтому выгрузка не загружает историю
+// в память. Чтение прерывается, если клиент закрыл соединение.
+func exportResults(c *gin.Context, q historyQuery, format string) {
+	query := q.resultQuery()
+	query.Offset, query.Limit = 0, 0
+
+	c.Header("Content-Type", exportContentTypes[format])
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", exportFilename(format, time.Now())))
+	c.Status(http.StatusOK)
+
+	enc := newExportEncoder(c.Writer, format)
+	count := 0
+	err := enc.Begin()
+	if err == nil {
+		err = store.EachResult(c.Request.Context(), query, func(result models.Result) error {
+			if err := enc.Encode(result); err != nil {
+				return err
+			}
+			if count++; count%exportFlushEvery == 0 {
+				if err := enc.Flush(); err != nil {
+					return err
+				}
+				c.Writer.Flush()
+			}
+			return nil
+		})
+	}
+	if err == nil {
+		err = enc.End()
+	}
+
+	// Заголовки уже отправлены, поэтому ошибку можно только записать в журнал:
+	// клиент получит оборванный файл
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "export results", "error", err, "format", format, "exported", count)
+	}
+}
+
+// exportHandler выгружает историю для HTML интерфейса
+func exportHandler(c *gin.Context) {
+	query, err := parseHistoryQuery(c)
+	if err == nil {
+		var format string
+		if format, err = parseExportFormat(c); err == nil {
+			exportResults(c, query, format)
+			return
+		}
+	}
+
+	renderIndex(c, historyErrorStatus(err), gin.H{
+		"Error": err.Error(),
+		"Query": query,
+	})
+}
+
+// apiExportHandler выгружает историю для JSON API
+func apiExportHandler(c *gin.Context) {
+	query, err := parseHistoryQuery(c)
+	if err == nil {
+		var format string
+		if format, err = parseExportFormat(c); err == nil {
+			exportResults(c, query, format)
+			return
+		}
+	}
+	respondHistoryError(c, err)
+}
+
+// ExportURL возвращает ссылку на выгрузку истории в формате format
+// с теми же фильтрами и сортировкой
+func (q historyQuery) ExportURL(format string) string {
+	v := q.values()
+	v.Del("page")
+	v.Del("size")
+	v.Set("format", format)
+	return "/export?" + v.Encode()
+}
+
+// csvExporter записывает результаты в CSV с заголовком exportColumns;
+// format - формат столбцов *_formatted
+type csvExporter struct {
+	w      *csv.Writer
+	format numfmt.Format
+}
+
+func (e *csvExporter) Begin() error {
+	return e.w.Write(exportColumns)
+}
+
+func (e *csvExporter) Encode(r models.Result) error {
+	var userID string
+	if !r.UserID.IsZero() {
+		userID = r.UserID.Hex()
+	}
+	record := make([]string, 0, len(exportColumns))
+	record = append(record, r.ID.Hex(), r.Operation, r.Kind, r.Precision)
+	for i := 0; i < operations.MaxArity; i++ {
+		record = append(record, csvOperand(r, i))
+	}
+	record = append(record, formatCSVFloat(r.Result))
+	for i := 0; i < operations.MaxArity; i++ {
+		record = append(record, csvOperandExact(r, i))
+	}
+	record = append(record,
+		r.ResultExact, r.Expression, r.Normalized, r.CreatedAt.UTC().Format(time.RFC3339Nano), userID,
+	)
+	for i := 0; i < operations.MaxArity; i++ {
+		record = append(record, e.formatOperand(r, i))
+	}
+	return e.w.Write(append(record, formatResult(e.format, r)))
+}
+
+// formatOperand записывает i-й операнд по формату или возвращает
+// пустую строку, если его нет
+func (e *csvExporter) formatOperand(r models.Result, i int) string {
+	if _, ok := r.Operand(i); ok {
+		return e.format.Decimal(r.OperandText(i))
+	}
+	return ""
+}
+
+func (e *csvExporter) Flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func (e *csvExporter) End() error {
+	return e.Flush()
+}
+
+// csvOperand возвращает значение i-го операнда или пустую строку, если его нет
+func csvOperand(r models.Result, i int) string {
+	if o, ok := r.Operand(i); ok {
+		return formatCSVFloat(o.Value)
+	}
+	return ""
+}
+
+// csvOperandExact возвращает точную запись i-го операнда
+func csvOperandExact(r models.Result, i int) string {
+	o, _ := r.Operand(i)
+	return o.Exact
+}
+
+// formatCSVFloat записывает число кратчайшей точной записью: эти столбцы
+// читают программы и импорт, поэтому формат number_format к ним не применяется
+func formatCSVFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// jsonExporter записывает результаты массивом JSON
+type jsonExporter struct {
+	w     io.Writer
+	count int
+}
+
+func (e *jsonExporter) Begin() error {
+	_, err := io.WriteString(e.w, "[")
+	return err
+}
+
+func (e *jsonExporter) Encode(r models.Result) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	if e.count > 0 {
+		if _, err := io.WriteString(e.w, ",\n"); err != nil {
+			return err
+		}
+	}
+	e.count++
+	_, err = e.w.Write(data)
+	return err
+}
+
+func (e *jsonExporter) Flush() error { return nil }
+
+func (e *jsonExporter) End() error {
+	_, err := io.WriteString(e.w, "]\n")
+	return err
+}
+
+// ndjsonExporter записывает результаты по одному объекту JSON на строку
+type ndjsonExporter struct {
+	enc *json.Encoder
+}
+
+func (e *ndjsonExporter) Begin() error { return nil }
+
+func (e *ndjsonExporter) Encode(r models.Result) error { return e.enc.Encode(r) }
+
+func (e *ndjsonExporter) Flush() error { return nil }
+
+func (e *ndjsonExporter) End() error { return nil }
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/igor-fedko/go_multiply_app/models"
+	"github.com/igor-fedko/go_multiply_app/operations"
+	"github.com/igor-fedko/go_multiply_app/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestExportCSV тестирует выгрузку истории в CSV с фильтрами главной страницы
+func (s *APITestSuite) TestExportCSV() {
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	s.seedResults("add", 30, start)
+	s.seedResults("multiply", 5, start)
+
+	w := s.get("/export?operation=add&sort=result&order=asc&page=2&size=10")
+	require.Equal(s.T(), http.StatusOK, w.Code)
+	assert.Equal(s.T(), "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Regexp(s.T(), `^attachment; filename="results-\d{8}-\d{6}\.csv"$`, w.Header().Get("Content-Disposition"))
+
+	// Выгружается вся отфильтрованная история, параметры страницы не учитываются
+	records, err := csv.NewReader(strings.NewReader(w.Body.String())).ReadAll()
+	require.NoError(s.T(), err)
+	require.Len(s.T(), records, 31)
+	assert.Equal(s.T(), exportColumns, records[0])
+	assert.Equal(s.T(), []string{"add", "0", "2024-06-01T12:00:00Z"}, []string{records[1][1], records[1][6], records[1][12]})
+	assert.Equal(s.T(), "29", records[30][6])
+	assert.Equal(s.T(), s.user.ID.Hex(), records[1][13])
+}
+
+// TestCSVColumns тестирует, что столбцы операндов выгрузки CSV следуют
+// за числом операндов, а для двух операндов сохраняют прежний порядок;
+// столбцы по формату number_format добавлены в конец
+func TestCSVColumns(t *testing.T) {
+	assert.Equal(t, []string{
+		"id", "operation", "kind", "precision",
+		"number1", "number2", "result",
+		"number1_exact", "number2_exact", "result_exact",
+		"expression", "normalized", "created_at", "user_id",
+		"number1_formatted", "number2_formatted", "result_formatted",
+	}, csvColumns(2))
+	assert.Equal(t, []string{"number1", "number2", "number3", "result"}, csvColumns(3)[4:8])
+	assert.Equal(t, "number3_exact", csvColumns(3)[10])
+	assert.Equal(t, []string{"number3_formatted", "result_formatted"}, csvColumns(3)[18:])
+	assert.Len(t, exportColumns, 11+3*operations.MaxArity)
+}
+
+// TestExportJSON тестирует выгрузку в JSON и NDJSON через API
+func (s *APITestSuite) TestExportJSON() {
+	s.seedResults("multiply", 3, time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+
+	w := s.get("/api/v1/export?format=json")
+	require.Equal(s.T(), http.StatusOK, w.Code)
+	assert.Contains(s.T(), w.Header().Get("Content-Disposition"), ".json")
+	var results []models.Result
+	require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &results))
+	require.Len(s.T(), results, 3)
+	assert.Equal(s.T(), 2.0, results[0].Result)
+
+	w = s.get("/api/v1/export?format=ndjson&order=asc")
+	require.Equal(s.T(), http.StatusOK, w.Code)
+	assert.Equal(s.T(), ndjsonContentType, w.Header().Get("Content-Type"))
+	var lines int
+	scanner := bufio.NewScanner(strings.NewReader(w.Body.String()))
+	for ; scanner.Scan(); lines++ {
+		var result models.Result
+		require.NoError(s.T(), json.Unmarshal(scanner.Bytes(), &result))
+		assert.Equal(s.T(), float64(lines), result.Result)
+	}
+	assert.Equal(s.T(), 3, lines)
+
+	// Пустая история - пустой массив
+	w = s.get("/api/v1/export?format=json&operation=add")
+	require.Equal(s.T(), http.StatusOK, w.Code)
+	assert.JSONEq(s.T(), `[]`, w.Body.String())
+}
+
+// TestExportScopeAndErrors тестирует область видимости и ошибки параметров выгрузки
+func (s *APITestSuite) TestExportScopeAndErrors() {
+	other := models.User{Username: "other", PasswordHash: "-"}
+	require.NoError(s.T(), s.store.CreateUser(context.Background(), &other))
+	s.seedUserResults(other.ID, "add", 2, time.Now().UTC())
+	s.seedResults("add", 1, time.Now().UTC())
+
+	w := s.get("/api/v1/export?format=ndjson")
+	require.Equal(s.T(), http.StatusOK, w.Code)
+	assert.Equal(s.T(), 1, strings.Count(w.Body.String(), "\n"))
+
+	w = s.get("/api/v1/export?format=xml")
+	assert.Equal(s.T(), http.StatusBadRequest, w.Code)
+	assert.Contains(s.T(), w.Body.String(), `"field":"format"`)
+
+	assert.Equal(s.T(), http.StatusForbidden, s.get("/api/v1/export?user=other").Code)
+	assert.Equal(s.T(), http.StatusBadRequest, s.get("/export?from=yesterday").Code)
+
+	count, err := s.store.CountResults(context.Background(), storage.ResultQuery{Scope: storage.AllUsers()})
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), int64(3), count)
+}
+
+// TestExportLinks тестирует ссылки на выгрузку на главной странице
+func (s *APITestSuite) TestExportLinks() {
+	w := s.get("/?operation=add&page=2")
+	require.Equal(s.T(), http.StatusOK, w.Code)
+	assert.Contains(s.T(), w.Body.String(), `href="/export?format=csv&amp;operation=add"`)
+}
@@ -0,0 +1,186 @@
+package expr
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Приоритеты узлов: чем больше, тем сильнее связывание
+const (
+	precAdditive = iota + 1
+	precMultiplicative
+	precUnary
+	precPower
+	precAtom
+)
+
+// Node - узел синтаксического дерева выражения
+type Node interface {
+	// Eval вычисляет значение узла
+	Eval() (float64, error)
+	// String возвращает нормализованную запись узла с минимально необходимыми скобками
+	String() string
+
+	precedence() int
+}
+
+// Number - числовой литерал
+type Number struct {
+	Value float64
+}
+
+func (n *Number) Eval() (float64, error) { return n.Value, nil }
+func (n *Number) String() string         { return strconv.FormatFloat(n.Value, 'g', -1, 64) }
+func (n *Number) precedence() int        { return precAtom }
+
+// Constant - именованная константа (pi, e)
+type Constant struct {
+	Name  string
+	Value float64
+}
+
+func (n *Constant) Eval() (float64, error) { return n.Value, nil }
+func (n *Constant) String() string         { return n.Name }
+func (n *Constant) precedence() int        { return precAtom }
+
+// Unary - унарный минус
+type Unary struct {
+	Op string
+	X  Node
+}
+
+func (n *Unary) Eval() (float64, error) {
+	x, err := n.X.Eval()
+	if err != nil {
+		return 0, err
+	}
+	return -x, nil
+}
+
+func (n *Unary) String() string {
+	return n.Op + wrap(n.X, n.X.precedence() < precUnary)
+}
+
+func (n *Unary) precedence() int { return precUnary }
+
+// Binary - бинарная операция (+, -, *, /, ^)
+type Binary struct {
+	Op          string
+	Left, Right Node
+}
+
+func (n *Binary) Eval() (float64, error) {
+	left, err := n.Left.Eval()
+	if err != nil {
+		return 0, err
+	}
+	right, err := n.Right.Eval()
+	if err != nil {
+		return 0, err
+	}
+
+	var value float64
+	switch n.Op {
+	case "+":
+		value = left + right
+	case "-":
+		value = left - right
+	case "*":
+		value = left * right
+	case "/":
+		if right == 0 {
+			return 0, &Error{Code: CodeDivisionByZero, Message: "Деление на ноль невозможно", Pos: -1}
+		}
+		value = left / right
+	case "^":
+		value = math.Pow(left, right)
+	}
+	if value == 0 && nonZeroResult(n.Op, left, right) {
+		return 0, &Error{Code: CodeUnderflow, Message: "Результат " + n.String() + " слишком мал по модулю и округляется до нуля", Pos: -1}
+	}
+	return checkFinite(value, n)
+}
+
+// nonZeroResult сообщает, что точный результат операции op над конечными
+// left и right не равен нулю. Сложение и вычитание float64 дают ноль только
+// для равных по модулю слагаемых, поэтому потерю значимости проверяют
+// только умножение, деление и возведение в степень.
+func nonZeroResult(op string, left, right float64) bool {
+	switch op {
+	case "*":
+		return left != 0 && right != 0
+	case "/", "^":
+		return left != 0
+	}
+	return false
+}
+
+func (n *Binary) String() string {
+	prec := n.precedence()
+	// ^ правоассоциативна: скобки нужны слева при равном приоритете,
+	// остальные операции левоассоциативны: скобки нужны справа
+	rightAssoc := n.Op == "^"
+	left := wrap(n.Left, n.Left.precedence() < prec || (rightAssoc && n.Left.precedence() == prec))
+	right := wrap(n.Right, n.Right.precedence() < prec || (!rightAssoc && n.Right.precedence() == prec))
+	return left + " " + n.Op + " " + right
+}
+
+func (n *Binary) precedence() int {
+	switch n.Op {
+	case "+", "-":
+		return precAdditive
+	case "*", "/":
+		return precMultiplicative
+	default:
+		return precPower
+	}
+}
+
+// Call - вызов функции
+type Call struct {
+	Name string
+	Args []Node
+}
+
+func (n *Call) Eval() (float64, error) {
+	args := make([]float64, len(n.Args))
+	for i, arg := range n.Args {
+		value, err := arg.Eval()
+		if err != nil {
+			return 0, err
+		}
+		args[i] = value
+	}
+
+	value, err := functions[n.Name].call(args)
+	if err != nil {
+		return 0, err
+	}
+	return checkFinite(value, n)
+}
+
+func (n *Call) String() string {
+	args := make([]string, len(n.Args))
+	for i, arg := range n.Args {
+		args[i] = arg.String()
+	}
+	return n.Name + "(" + strings.Join(args, ", ") + ")"
+}
+
+func (n *Call) precedence() int { return precAtom }
+
+func wrap(n Node, parens bool) string {
+	if parens {
+		return "(" + n.String() + ")"
+	}
+	return n.String()
+}
+
+// checkFinite отклоняет бесконечные и неопределенные промежуточные результаты
+func checkFinite(value float64, n Node) (float64, error) {
+	if math.IsInf(value, 0) || math.IsNaN(value) {
+		return 0, &Error{Code: CodeNonFinite, Message: "Результат " + n.String() + " не является конечным числом", Pos: -1}
+	}
+	return value, nil
+}
@@ -0,0 +1,37 @@
+package expr
+
+import "fmt"
+
+// Коды ошибок разбора и вычисления выражений
+const (
+	CodeSyntax          = "syntax_error"
+	CodeTooLong         = "expression_too_long"
+	CodeTooDeep         = "expression_too_deep"
+	CodeUnknownFunction = "unknown_function"
+	CodeArgumentCount   = "wrong_argument_count"
+	CodeDivisionByZero  = "division_by_zero"
+	CodeDomain          = "domain_error"
+	CodeNonFinite       = "non_finite_result"
+	// Числа вне диапазона float64 в записи выражения; underflow - также
+	// ненулевой промежуточный результат, округленный до нуля
+	CodeOverflow  = "overflow"
+	CodeUnderflow = "underflow"
+)
+
+// Error - ошибка разбора или вычисления выражения
+type Error struct {
+	Code    string
+	Message string
+	Pos     int // позиция в исходной строке (в байтах) или -1
+}
+
+func (e *Error) Error() string {
+	if e.Pos >= 0 {
+		return fmt.Sprintf("%s (позиция %d)", e.Message, e.Pos+1)
+	}
+	return e.Message
+}
+
+func errorAt(pos int, code, format string, args ...any) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...), Pos: pos}
+}
@@ -0,0 +1,82 @@
+package expr
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		src        string
+		want       float64
+		normalized string
+	}{
+		{"1 + 2 * 3", 7, "1 + 2 * 3"},
+		{"(1 + 2) * 3", 9, "(1 + 2) * 3"},
+		{"((2))", 2, "2"},
+		{"10 - 4 - 3", 3, "10 - 4 - 3"},
+		{"10 - (4 - 3)", 9, "10 - (4 - 3)"},
+		{"2 ^ 3 ^ 2", 512, "2 ^ 3 ^ 2"},
+		{"(2 ^ 3) ^ 2", 64, "(2 ^ 3) ^ 2"},
+		{"-2 ^ 2", -4, "-2 ^ 2"},
+		{"(-2) ^ 2", 4, "(-2) ^ 2"},
+		{"2 ^ -1", 0.5, "2 ^ (-1)"},
+		{"--3", 3, "--3"},
+		{"+5", 5, "5"},
+		{"sqrt(16) + abs(-3)", 7, "sqrt(16) + abs(-3)"},
+		{"MAX(1, 5, 3) - min(4, 2)", 3, "max(1, 5, 3) - min(4, 2)"},
+		{"1.5e3 / 3", 500, "1500 / 3"},
+		{"2*pi", 2 * 3.141592653589793, "2 * pi"},
+	}
+
+	for _, tt := range tests {
+		node, value, err := Evaluate(tt.src, DefaultLimits)
+		require.NoError(t, err, tt.src)
+		assert.InDelta(t, tt.want, value, 1e-12, tt.src)
+		assert.Equal(t, tt.normalized, node.String(), tt.src)
+
+		// Нормализованная запись должна разбираться в то же значение
+		_, again, err := Evaluate(node.String(), DefaultLimits)
+		require.NoError(t, err, node.String())
+		assert.InDelta(t, value, again, 1e-12, node.String())
+	}
+}
+
+func TestEvaluateErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		code string
+	}{
+		{"", CodeSyntax},
+		{"1 +", CodeSyntax},
+		{"(1 + 2", CodeSyntax},
+		{"1 2", CodeSyntax},
+		{"2 $ 3", CodeSyntax},
+		{"foo", CodeSyntax},
+		{"foo(1)", CodeUnknownFunction},
+		{"sqrt(1, 2)", CodeArgumentCount},
+		{"max()", CodeArgumentCount},
+		{"1 / (2 - 2)", CodeDivisionByZero},
+		{"sqrt(-1)", CodeDomain},
+		{"10 ^ 400", CodeNonFinite},
+		{"1e400 + 1", CodeOverflow},
+		{"2 * 1e-400", CodeUnderflow},
+		{"1e-200 * 1e-200", CodeUnderflow},
+		{"1e-300 / 1e300", CodeUnderflow},
+		{"2 ^ -2000", CodeUnderflow},
+		{strings.Repeat("(", 100) + "1" + strings.Repeat(")", 100), CodeTooDeep},
+		{strings.Repeat("-", 100) + "1", CodeTooDeep},
+		{strings.Repeat("1+", 600) + "1", CodeTooLong},
+	}
+
+	for _, tt := range tests {
+		_, _, err := Evaluate(tt.src, DefaultLimits)
+		var exprErr *Error
+		require.True(t, errors.As(err, &exprErr), "%q: %v", tt.src, err)
+		assert.Equal(t, tt.code, exprErr.Code, tt.src)
+	}
+}
@@ -0,0 +1,49 @@
+package expr
+
+import (
+	"math"
+)
+
+// function описывает встроенную функцию; maxArgs < 0 означает произвольное число аргументов
+type function struct {
+	minArgs, maxArgs int
+	call             func(args []float64) (float64, error)
+}
+
+var functions = map[string]function{
+	"sqrt": {1, 1, func(args []float64) (float64, error) {
+		if args[0] < 0 {
+			return 0, &Error{Code: CodeDomain, Message: "Квадратный корень из отрицательного числа", Pos: -1}
+		}
+		return math.Sqrt(args[0]), nil
+	}},
+	"abs":   {1, 1, unary(math.Abs)},
+	"round": {1, 1, unary(math.Round)},
+	"floor": {1, 1, unary(math.Floor)},
+	"ceil":  {1, 1, unary(math.Ceil)},
+	"min": {1, -1, func(args []float64) (float64, error) {
+		result := args[0]
+		for _, v := range args[1:] {
+			result = math.Min(result, v)
+		}
+		return result, nil
+	}},
+	"max": {1, -1, func(args []float64) (float64, error) {
+		result := args[0]
+		for _, v := range args[1:] {
+			result = math.Max(result, v)
+		}
+		return result, nil
+	}},
+}
+
+var constants = map[string]float64{
+	"pi": math.Pi,
+	"e":  math.E,
+}
+
+func unary(fn func(float64) float64) func([]float64) (float64, error) {
+	return func(args []float64) (float64, error) {
+		return fn(args[0]), nil
+	}
+}
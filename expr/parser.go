@@ -0,0 +1,239 @@
+package expr
+
+import "strings"
+
+// Limits ограничивает размер разбираемых выражений
+type Limits struct {
+	MaxLength int // максимальная длина выражения в символах
+	MaxDepth  int // максимальная вложенность скобок, унарных операций и вызовов
+}
+
+// DefaultLimits - ограничения по умолчанию
+var DefaultLimits = Limits{MaxLength: 1000, MaxDepth: 64}
+
+type parser struct {
+	tokens []token
+	pos    int
+	depth  int
+	limits Limits
+}
+
+// Parse разбирает инфиксное выражение в синтаксическое дерево.
+//
+// Грамматика:
+//
+//	expr    = term { ("+" | "-") term }
+//	term    = unary { ("*" | "/") unary }
+//	unary   = ("-" | "+") unary | power
+//	power   = primary [ "^" unary ]
+//	primary = number | constant | ident "(" expr { "," expr } ")" | "(" expr ")"
+func Parse(src string, limits Limits) (Node, error) {
+	if strings.TrimSpace(src) == "" {
+		return nil, &Error{Code: CodeSyntax, Message: "Пустое выражение", Pos: -1}
+	}
+	if length := len([]rune(src)); length > limits.MaxLength {
+		return nil, &Error{Code: CodeTooLong, Message: "Выражение слишком длинное", Pos: -1}
+	}
+
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens, limits: limits}
+	node, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, errorAt(tok.pos, CodeSyntax, "Неожиданный символ %q", tok.text)
+	}
+	return node, nil
+}
+
+// Evaluate разбирает и вычисляет выражение. Возвращает дерево, чтобы
+// вызывающий код мог сохранить нормализованную запись.
+func Evaluate(src string, limits Limits) (Node, float64, error) {
+	node, err := Parse(src, limits)
+	if err != nil {
+		return nil, 0, err
+	}
+	value, err := node.Eval()
+	if err != nil {
+		return nil, 0, err
+	}
+	return node, value, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// enter увеличивает глубину вложенности и проверяет ограничение
+func (p *parser) enter() error {
+	p.depth++
+	if p.depth > p.limits.MaxDepth {
+		return errorAt(p.peek().pos, CodeTooDeep, "Слишком большая вложенность выражения")
+	}
+	return nil
+}
+
+func (p *parser) leave() {
+	p.depth--
+}
+
+func (p *parser) parseExpr() (Node, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok := p.peek()
+		if tok.kind != tokOperator || (tok.text != "+" && tok.text != "-") {
+			return left, nil
+		}
+		p.next()
+		right, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		left = &Binary{Op: tok.text, Left: left, Right: right}
+	}
+}
+
+func (p *parser) parseTerm() (Node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok := p.peek()
+		if tok.kind != tokOperator || (tok.text != "*" && tok.text != "/") {
+			return left, nil
+		}
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &Binary{Op: tok.text, Left: left, Right: right}
+	}
+}
+
+func (p *parser) parseUnary() (Node, error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+
+	tok := p.peek()
+	if tok.kind == tokOperator && (tok.text == "-" || tok.text == "+") {
+		p.next()
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		// Унарный плюс не меняет значение и не попадает в дерево
+		if tok.text == "+" {
+			return x, nil
+		}
+		return &Unary{Op: "-", X: x}, nil
+	}
+	return p.parsePower()
+}
+
+func (p *parser) parsePower() (Node, error) {
+	base, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind == tokOperator && tok.text == "^" {
+		p.next()
+		// Показатель разбирается как unary: так 2^-1 и 2^3^2 = 2^(3^2) работают естественно
+		exponent, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &Binary{Op: "^", Left: base, Right: exponent}, nil
+	}
+	return base, nil
+}
+
+func (p *parser) parsePrimary() (Node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokNumber:
+		return &Number{Value: tok.value}, nil
+	case tokIdent:
+		name := strings.ToLower(tok.text)
+		if p.peek().kind == tokLParen {
+			return p.parseCall(name, tok)
+		}
+		if value, ok := constants[name]; ok {
+			return &Constant{Name: name, Value: value}, nil
+		}
+		return nil, errorAt(tok.pos, CodeSyntax, "Неизвестный идентификатор %q", tok.text)
+	case tokLParen:
+		if err := p.enter(); err != nil {
+			return nil, err
+		}
+		defer p.leave()
+
+		node, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokRParen {
+			return nil, errorAt(closing.pos, CodeSyntax, "Ожидалась закрывающая скобка")
+		}
+		return node, nil
+	case tokEOF:
+		return nil, errorAt(tok.pos, CodeSyntax, "Неожиданный конец выражения")
+	default:
+		return nil, errorAt(tok.pos, CodeSyntax, "Неожиданный символ %q", tok.text)
+	}
+}
+
+func (p *parser) parseCall(name string, nameTok token) (Node, error) {
+	fn, ok := functions[name]
+	if !ok {
+		return nil, errorAt(nameTok.pos, CodeUnknownFunction, "Неизвестная функция %q", nameTok.text)
+	}
+
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+
+	p.next() // "("
+	var args []Node
+	if p.peek().kind != tokRParen {
+		for {
+			arg, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if p.peek().kind != tokComma {
+				break
+			}
+			p.next()
+		}
+	}
+	if closing := p.next(); closing.kind != tokRParen {
+		return nil, errorAt(closing.pos, CodeSyntax, "Ожидалась закрывающая скобка")
+	}
+
+	if len(args) < fn.minArgs || (fn.maxArgs >= 0 && len(args) > fn.maxArgs) {
+		return nil, errorAt(nameTok.pos, CodeArgumentCount, "Неверное число аргументов функции %s: %d", name, len(args))
+	}
+	return &Call{Name: name, Args: args}, nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/igor-fedko/go_multiply_app/storage"
+)
+
+// Состояния проверок в ответах /healthz и /readyz
+const (
+	healthOK          = "ok"
+	healthUnavailable = "unavailable"
+)
+
+// healthzHandler сообщает, что процесс жив и обрабатывает запросы
+func healthzHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": healthOK})
+}
+
+// readyzHandler сообщает, готово ли приложение принимать запросы:
+// хранилище отвечает и все миграции схемы применены
+func readyzHandler(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), appConfig.Timeouts.Read.Duration)
+	defer cancel()
+
+	checks := gin.H{}
+	status, code := healthOK, http.StatusOK
+
+	if err := store.Ping(ctx); err != nil {
+		checks["storage"] = err.Error()
+		status, code = healthUnavailable, http.StatusServiceUnavailable
+	} else {
+		checks["storage"] = healthOK
+	}
+
+	if err := checkMigrations(ctx); err != nil {
+		checks["migrations"] = err.Error()
+		status, code = healthUnavailable, http.StatusServiceUnavailable
+	} else {
+		checks["migrations"] = healthOK
+	}
+
+	c.JSON(code, gin.H{"status": status, "checks": checks})
+}
+
+// checkMigrations возвращает ошибку, если есть непримененные миграции
+func checkMigrations(ctx context.Context) error {
+	migrator, ok := store.(storage.Migrator)
+	if !ok {
+		return nil
+	}
+
+	statuses, err := migrator.MigrationStatus(ctx)
+	if err != nil {
+		return err
+	}
+	for _, st := range statuses {
+		if !st.Applied {
+			return fmt.Errorf("миграция %04d_%s не применена", st.Version, st.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,354 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/igor-fedko/go_multiply_app/models"
+	"github.com/igor-fedko/go_multiply_app/operations"
+	"github.com/igor-fedko/go_multiply_app/storage"
+)
+
+// Размеры страницы истории результатов
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// maxPage ограничивает номер страницы истории и журнала, чтобы смещение
+// (page-1)*size не переполняло int
+const maxPage = 100000
+
+// Направления сортировки в строке запроса
+const (
+	orderAsc  = "asc"
+	orderDesc = "desc"
+)
+
+// dateLayout - формат даты в полях from и to (как у <input type="date">)
+const dateLayout = "2006-01-02"
+
+// pageSizes - варианты размера страницы, предлагаемые в форме истории
+var pageSizes = []int{10, 20, 50, 100}
+
+// allUsers - значение параметра user для просмотра истории всех пользователей
+const allUsers = "*"
+
+// errHistoryForbidden возвращается, если пользователь без роли администратора
+// запрашивает чужую историю
+var errHistoryForbidden = errors.New("Просмотр истории других пользователей доступен только администратору")
+
+// queryError - ошибка в параметре строки запроса
+type queryError struct {
+	Field   string
+	Message string
+}
+
+func (e *queryError) Error() string {
+	return e.Message
+}
+
+// historyQuery - параметры просмотра истории результатов:
+// page, size, sort, order, operation, from, to и user из строки запроса.
+// Исходные значения from и to сохраняются для формы фильтра.
+// User - имя пользователя, чья история показывается администратору,
+// или "*" - история всех пользователей; пустое значение - своя история.
+type historyQuery struct {
+	Page      int
+	Size      int
+	Sort      string
+	Order     string
+	Operation string
+	From      string
+	To        string
+	User      string
+
+	from, to time.Time
+	scope    storage.Scope
+}
+
+// defaultHistoryQuery возвращает параметры первой страницы истории:
+// новые записи первыми, без фильтров
+func defaultHistoryQuery() historyQuery {
+	return historyQuery{
+		Page:  1,
+		Size:  defaultPageSize,
+		Sort:  storage.SortCreatedAt,
+		Order: orderDesc,
+	}
+}
+
+// parseHistoryQuery разбирает и проверяет параметры истории и определяет,
+// чьи результаты доступны текущему пользователю. Ошибки параметров
+// возвращаются как *queryError, запрос чужой истории без роли
+// администратора - errHistoryForbidden.
+func parseHistoryQuery(c *gin.Context) (historyQuery, error) {
+	q := defaultHistoryQuery()
+	q.Operation = c.Query("operation")
+	q.From = c.Query("from")
+	q.To = c.Query("to")
+	q.User = c.Query("user")
+
+	if s := c.Query("page"); s != "" {
+		page, err := strconv.Atoi(s)
+		if err != nil || page < 1 {
+			return q, &queryError{Field: "page", Message: "Номер страницы должен быть положительным целым числом"}
+		}
+		if page > maxPage {
+			return q, &queryError{Field: "page", Message: "Номер страницы должен быть не больше " + strconv.Itoa(maxPage)}
+		}
+		q.Page = page
+	}
+
+	if s := c.Query("size"); s != "" {
+		size, err := strconv.Atoi(s)
+		if err != nil || size < 1 || size > maxPageSize {
+			return q, &queryError{Field: "size", Message: "Размер страницы должен быть от 1 до " + strconv.Itoa(maxPageSize)}
+		}
+		q.Size = size
+	}
+
+	if s := c.Query("sort"); s != "" {
+		if !storage.ValidSortField(s) {
+			return q, &queryError{Field: "sort", Message: "Сортировка по полю " + s + " не поддерживается"}
+		}
+		q.Sort = s
+	}
+
+	switch order := c.Query("order"); order {
+	case "":
+	case orderAsc, orderDesc:
+		q.Order = order
+	default:
+		return q, &queryError{Field: "order", Message: "Направление сортировки должно быть asc или desc"}
+	}
+
+	if q.Operation != "" && q.Operation != models.OperationEvaluate {
+		if _, ok := operations.Default.Get(q.Operation); !ok {
+			return q, &queryError{Field: "operation", Message: "Неизвестная операция " + q.Operation}
+		}
+	}
+
+	var err error
+	if q.from, err = parseDateParam(q.From, false); err != nil {
+		return q, &queryError{Field: "from", Message: "Неверный формат начальной даты"}
+	}
+	if q.to, err = parseDateParam(q.To, true); err != nil {
+		return q, &queryError{Field: "to", Message: "Неверный формат конечной даты"}
+	}
+	if !q.from.IsZero() && !q.to.IsZero() && q.from.After(q.to) {
+		return q, &queryError{Field: "from", Message: "Начальная дата позже конечной"}
+	}
+
+	q.scope, err = historyScope(c, q.User)
+	return q, err
+}
+
+// historyScope возвращает область истории для параметра user: своя история,
+// история пользователя username или всех пользователей ("*").
+// Чужая история доступна только администратору.
+func historyScope(c *gin.Context, username string) (storage.Scope, error) {
+	user, _ := currentUser(c)
+	if username == "" || username == user.Username {
+		return storage.UserScope(user.ID), nil
+	}
+	if !user.IsAdmin() {
+		return storage.Scope{}, errHistoryForbidden
+	}
+	if username == allUsers {
+		return storage.AllUsers(), nil
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), appConfig.Timeouts.Read.Duration)
+	defer cancel()
+
+	owner, err := store.GetUserByUsername(ctx, username)
+	if errors.Is(err, storage.ErrNotFound) {
+		return storage.Scope{}, &queryError{Field: "user", Message: "Пользователь " + username + " не найден"}
+	}
+	if err != nil {
+		return storage.Scope{}, err
+	}
+	return storage.UserScope(owner.ID), nil
+}
+
+// viewerScope возвращает область результатов, доступных текущему
+// пользователю: администратору - все, остальным - только свои
+func viewerScope(c *gin.Context) storage.Scope {
+	user, _ := currentUser(c)
+	if user.IsAdmin() {
+		return storage.AllUsers()
+	}
+	return storage.UserScope(user.ID)
+}
+
+// historyErrorStatus возвращает HTTP статус ошибки parseHistoryQuery
+func historyErrorStatus(err error) int {
+	var qErr *queryError
+	switch {
+	case errors.As(err, &qErr):
+		return http.StatusBadRequest
+	case errors.Is(err, errHistoryForbidden):
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// parseDateParam разбирает дату в формате ГГГГ-ММ-ДД (UTC) или RFC 3339.
+// Для конца диапазона дата без времени означает конец дня.
+func parseDateParam(s string, endOfDay bool) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.UTC(), nil
+	}
+
+	t, err := time.Parse(dateLayout, s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+	return t, nil
+}
+
+// resultQuery преобразует параметры в запрос к хранилищу.
+// Фильтр по операции с сортировкой по дате обслуживается индексом
+// (operation, created_at, _id) коллекции результатов.
+func (q historyQuery) resultQuery() storage.ResultQuery {
+	return storage.ResultQuery{
+		Scope:     q.scope,
+		Operation: q.Operation,
+		From:      q.from,
+		To:        q.to,
+		SortBy:    q.Sort,
+		SortAsc:   q.Order == orderAsc,
+		Offset:    (q.Page - 1) * q.Size,
+		Limit:     q.Size,
+	}
+}
+
+// listHistory возвращает страницу результатов и сведения о пагинации
+func listHistory(q historyQuery) ([]models.Result, pagination, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), appConfig.Timeouts.Read.Duration)
+	defer cancel()
+
+	query := q.resultQuery()
+	total, err := store.CountResults(ctx, query)
+	if err != nil {
+		return nil, pagination{}, err
+	}
+	results, err := store.ListResults(ctx, query)
+	if err != nil {
+		return nil, pagination{}, err
+	}
+	return results, newPagination(q.Page, q.Size, total), nil
+}
+
+// values возвращает параметры, отличающиеся от значений по умолчанию
+func (q historyQuery) values() url.Values {
+	v := url.Values{}
+	if q.Page != 1 {
+		v.Set("page", strconv.Itoa(q.Page))
+	}
+	if q.Size != defaultPageSize {
+		v.Set("size", strconv.Itoa(q.Size))
+	}
+	if q.Sort != storage.SortCreatedAt {
+		v.Set("sort", q.Sort)
+	}
+	if q.Order != orderDesc {
+		v.Set("order", q.Order)
+	}
+	if q.Operation != "" {
+		v.Set("operation", q.Operation)
+	}
+	if q.From != "" {
+		v.Set("from", q.From)
+	}
+	if q.To != "" {
+		v.Set("to", q.To)
+	}
+	if q.User != "" {
+		v.Set("user", q.User)
+	}
+	return v
+}
+
+// url возвращает адрес главной страницы с параметрами q
+func (q historyQuery) url() string {
+	if encoded := q.values().Encode(); encoded != "" {
+		return "/?" + encoded
+	}
+	return "/"
+}
+
+// PageURL возвращает ссылку на страницу page с теми же фильтрами и сортировкой
+func (q historyQuery) PageURL(page int) string {
+	q.Page = page
+	return q.url()
+}
+
+// SortURL возвращает ссылку для сортировки по полю field: повторный выбор
+// поля меняет направление, новое поле сортируется по убыванию.
+// Сортировка всегда начинается с первой страницы.
+func (q historyQuery) SortURL(field string) string {
+	if q.Sort == field && q.Order == orderDesc {
+		q.Order = orderAsc
+	} else {
+		q.Order = orderDesc
+	}
+	q.Sort = field
+	q.Page = 1
+	return q.url()
+}
+
+// SortClass возвращает CSS-класс заголовка столбца field: asc, desc или пустую строку
+func (q historyQuery) SortClass(field string) string {
+	if q.Sort != field {
+		return ""
+	}
+	return q.Order
+}
+
+// pagination описывает положение текущей страницы истории
+type pagination struct {
+	Page  int   `json:"page"`
+	Size  int   `json:"size"`
+	Total int64 `json:"total"`
+	Pages int   `json:"pages"`
+}
+
+// newPagination вычисляет число страниц по size записей для total записей
+func newPagination(page, size int, total int64) pagination {
+	pages := int((total + int64(size) - 1) / int64(size))
+	return pagination{Page: page, Size: size, Total: total, Pages: pages}
+}
+
+// HasPrev сообщает, есть ли предыдущая страница
+func (p pagination) HasPrev() bool {
+	return p.Page > 1
+}
+
+// HasNext сообщает, есть ли следующая страница
+func (p pagination) HasNext() bool {
+	return p.Page < p.Pages
+}
+
+// PrevPage возвращает номер предыдущей страницы
+func (p pagination) PrevPage() int {
+	return p.Page - 1
+}
+
+// NextPage возвращает номер следующей страницы
+func (p pagination) NextPage() int {
+	return p.Page + 1
+}
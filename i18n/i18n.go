@@ -0,0 +1,243 @@
+// Package i18n переводит сообщения интерфейса и ошибок. Сообщения
+// формируются на русском языке - на нем же записывается журнал операций -
+// и переводятся при выводе пользователю по каталогу сообщений.
+package i18n
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Поддерживаемые языки
+const (
+	Russian = "ru"
+	English = "en"
+)
+
+// Default - язык, если ни запрос, ни браузер не указали поддерживаемый
+const Default = Russian
+
+// Language - язык интерфейса
+type Language struct {
+	// Tag - код языка, например "ru"
+	Tag string
+	// Name - название языка на нем самом
+	Name string
+}
+
+// languages - поддерживаемые языки в порядке показа в переключателе
+var languages = []Language{
+	{Tag: Russian, Name: "Русский"},
+	{Tag: English, Name: "English"},
+}
+
+// Languages возвращает поддерживаемые языки
+func Languages() []Language {
+	return append([]Language(nil), languages...)
+}
+
+// Lookup возвращает поддерживаемый язык по коду. Учитывается только
+// основной код: "en-US" и "en_GB" означают English.
+func Lookup(tag string) (string, bool) {
+	primary, _, _ := strings.Cut(strings.TrimSpace(tag), "-")
+	primary, _, _ = strings.Cut(primary, "_")
+	for _, l := range languages {
+		if strings.EqualFold(primary, l.Tag) {
+			return l.Tag, true
+		}
+	}
+	return "", false
+}
+
+// ParseAcceptLanguage возвращает коды языков из заголовка Accept-Language
+// по убыванию веса q. Языки с q=0 и записи с неверным весом пропускаются,
+// при равных весах сохраняется порядок из заголовка.
+func ParseAcceptLanguage(header string) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+	var tags []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		tag = strings.TrimSpace(tag)
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if tag != "" && q > 0 {
+			tags = append(tags, weighted{tag: tag, q: q})
+		}
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+
+	result := make([]string, len(tags))
+	for i, t := range tags {
+		result[i] = t.tag
+	}
+	return result
+}
+
+// Negotiate выбирает язык по заголовку Accept-Language.
+// Если подходящего языка нет, возвращается Default.
+func Negotiate(acceptLanguage string) string {
+	for _, tag := range ParseAcceptLanguage(acceptLanguage) {
+		if lang, ok := Lookup(tag); ok {
+			return lang
+		}
+	}
+	return Default
+}
+
+// verbPattern находит глаголы формата fmt, например %s, %d, %q и %%
+var verbPattern = regexp.MustCompile(`%[-+# 0]*[0-9]*(?:\.[0-9]+)?[a-zA-Z%]`)
+
+// catalog - переводы сообщений на один язык
+type catalog struct {
+	// messages - перевод по сообщению или формату на русском языке
+	messages map[string]string
+	// patterns - форматы с параметрами для перевода готовых сообщений,
+	// более длинные первыми
+	patterns []pattern
+}
+
+// pattern - формат сообщения с параметрами и его перевод
+type pattern struct {
+	re          *regexp.Regexp
+	translation string
+	literal     int
+}
+
+// catalogs - каталоги сообщений по языкам; для русского языка перевод не нужен
+var catalogs = map[string]*catalog{
+	English: newCatalog(english),
+}
+
+// newCatalog строит каталог и шаблоны для форматов с параметрами.
+// Перевод формата содержит те же глаголы в том же порядке.
+func newCatalog(messages map[string]string) *catalog {
+	c := &catalog{messages: messages}
+	for format, translation := range messages {
+		verbs := verbPattern.FindAllString(format, -1)
+		if len(verbs) == 0 {
+			continue
+		}
+
+		var expr strings.Builder
+		expr.WriteString(`(?s)^`)
+		literal := 0
+		for i, part := range verbPattern.Split(format, -1) {
+			expr.WriteString(regexp.QuoteMeta(part))
+			literal += len(part)
+			if i >= len(verbs) {
+				break
+			}
+			switch verbs[i][len(verbs[i])-1] {
+			case '%':
+				expr.WriteString("%")
+			case 'd':
+				expr.WriteString(`(-?[0-9]+)`)
+			default:
+				expr.WriteString(`(.+?)`)
+			}
+		}
+		expr.WriteString(`$`)
+
+		c.patterns = append(c.patterns, pattern{
+			re:          regexp.MustCompile(expr.String()),
+			translation: translation,
+			literal:     literal,
+		})
+	}
+	sort.Slice(c.patterns, func(i, j int) bool {
+		if c.patterns[i].literal != c.patterns[j].literal {
+			return c.patterns[i].literal > c.patterns[j].literal
+		}
+		return c.patterns[i].re.String() < c.patterns[j].re.String()
+	})
+	return c
+}
+
+// Printer переводит сообщения на выбранный язык
+type Printer struct {
+	lang    string
+	catalog *catalog
+}
+
+// NewPrinter возвращает Printer для языка lang; неизвестный язык означает Default
+func NewPrinter(lang string) Printer {
+	if _, ok := Lookup(lang); !ok {
+		lang = Default
+	}
+	return Printer{lang: lang, catalog: catalogs[lang]}
+}
+
+// Lang возвращает код языка
+func (p Printer) Lang() string {
+	return p.lang
+}
+
+// Sprintf переводит формат на русском языке и подставляет аргументы.
+// Сообщения аргументов-ошибок тоже переводятся.
+func (p Printer) Sprintf(format string, args ...any) string {
+	translated := format
+	if p.catalog != nil {
+		if t, ok := p.catalog.messages[format]; ok {
+			translated = t
+		}
+	}
+	if len(args) == 0 {
+		return translated
+	}
+
+	localized := make([]any, len(args))
+	for i, arg := range args {
+		if err, ok := arg.(error); ok {
+			arg = p.Translate(err.Error())
+		}
+		localized[i] = arg
+	}
+	return fmt.Sprintf(translated, localized...)
+}
+
+// Translate переводит готовое сообщение на русском языке, например
+// текст ошибки. Сообщение ищется в каталоге целиком, затем по форматам
+// с параметрами: "Неизвестная операция pow" переводится по формату
+// "Неизвестная операция %s", подставленные значения переводятся так же.
+// Сообщение без перевода возвращается без изменений.
+func (p Printer) Translate(msg string) string {
+	if p.catalog == nil || msg == "" {
+		return msg
+	}
+	if t, ok := p.catalog.messages[msg]; ok {
+		return t
+	}
+
+	for _, pt := range p.catalog.patterns {
+		match := pt.re.FindStringSubmatch(msg)
+		if match == nil {
+			continue
+		}
+		values := match[1:]
+		next := 0
+		return verbPattern.ReplaceAllStringFunc(pt.translation, func(verb string) string {
+			if verb == "%%" || next >= len(values) {
+				return "%"
+			}
+			value := values[next]
+			next++
+			if strings.HasSuffix(verb, "d") {
+				return value
+			}
+			return p.Translate(value)
+		})
+	}
+	return msg
+}
@@ -0,0 +1,77 @@
+package i18n
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", Russian},
+		{"en-US,en;q=0.9", English},
+		{"de-DE, en;q=0.8, ru;q=0.9", Russian},
+		{"en;q=0, ru;q=0.5", Russian},
+		{"fr, de", Default},
+		{"EN_gb", English},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, Negotiate(tt.header), tt.header)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	lang, ok := Lookup("en-US")
+	assert.True(t, ok)
+	assert.Equal(t, English, lang)
+
+	_, ok = Lookup("de")
+	assert.False(t, ok)
+	assert.Equal(t, Default, NewPrinter("de").Lang())
+}
+
+func TestSprintf(t *testing.T) {
+	en := NewPrinter(English)
+	assert.Equal(t, "Page 2 of 5 · 93 records total", en.Sprintf("Страница %d из %d · всего записей: %d", 2, 5, 93))
+	assert.Equal(t, "Failed to save the result: record not found",
+		en.Sprintf("Ошибка при сохранении результата: %s", errors.New("запись не найдена")))
+
+	ru := NewPrinter(Russian)
+	assert.Equal(t, "Страница 2 из 5 · всего записей: 93", ru.Sprintf("Страница %d из %d · всего записей: %d", 2, 5, 93))
+}
+
+func TestTranslate(t *testing.T) {
+	en := NewPrinter(English)
+	tests := []struct {
+		msg  string
+		want string
+	}{
+		{"Деление на ноль невозможно", "Division by zero is not possible"},
+		{"Неизвестная операция pow", "Unknown operation pow"},
+		{"Слишком много запросов, повторите через 30 с", "Too many requests, retry in 30 s"},
+		{`Ошибка в выражении: Неожиданный символ "x" (позиция 3)`, `Error in expression: Unexpected character "x" (position 3)`},
+		{"Ошибка хранилища: connection refused", "Storage error: connection refused"},
+		{"Сообщение без перевода", "Сообщение без перевода"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, en.Translate(tt.msg), tt.msg)
+	}
+
+	assert.Equal(t, "Деление на ноль невозможно", NewPrinter(Russian).Translate("Деление на ноль невозможно"))
+}
+
+// TestCatalogVerbs проверяет, что перевод формата содержит те же глаголы
+// в том же порядке, что и исходный формат
+func TestCatalogVerbs(t *testing.T) {
+	for lang, c := range catalogs {
+		for format, translation := range c.messages {
+			assert.Equal(t, verbPattern.FindAllString(format, -1), verbPattern.FindAllString(translation, -1),
+				"%s: %s", lang, format)
+		}
+	}
+}
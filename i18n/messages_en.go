@@ -0,0 +1,224 @@
+package i18n
+
+// english - английский каталог сообщений. Ключ - сообщение или формат fmt
+// на русском языке, как он записан в коде; перевод формата содержит
+// те же глаголы в том же порядке.
+var english = map[string]string{
+	// Общие страницы и навигация
+	"Математические операции": "Math operations",
+	"Журнал операций":         "Operation log",
+	"(администратор)":         "(administrator)",
+	"Выйти":                   "Log out",
+	"Язык:":                   "Language:",
+	"Формат чисел:":           "Number format:",
+	"По языку интерфейса":     "Same as interface language",
+	"Сохранить":               "Save",
+	"← Назад":                 "← Back",
+	"Вперед →":                "Next →",
+	"Страница %d из %d · всего записей: %d": "Page %d of %d · %d records total",
+	"С даты:":      "From:",
+	"По дату:":     "To:",
+	"На странице:": "Per page:",
+	"Операция:":    "Operation:",
+	"Все операции": "All operations",
+
+	// Вход и регистрация
+	"Вход":                     "Log in",
+	"Регистрация":              "Sign up",
+	"Имя пользователя:":        "Username:",
+	"Пароль:":                  "Password:",
+	"Войти":                    "Log in",
+	"Зарегистрироваться":       "Sign up",
+	"Уже есть учетная запись?": "Already have an account?",
+	"Нет учетной записи?":      "Don't have an account?",
+
+	// Форма операций и выражений
+	"Первое число:":        "First number:",
+	"Второе число:":        "Second number:",
+	"Точность вычислений:": "Precision:",
+	"Обычная (float64)":    "Standard (float64)",
+	"Точная (десятичная, без потери точности)": "Exact (decimal, no loss of precision)",
+	"Выражение:": "Expression:",
+	"Вычислить":  "Evaluate",
+	"Импорт вычислений из файла CSV или JSON:": "Import calculations from a CSV or JSON file:",
+	"Загрузить":                 "Upload",
+	"Импортировано записей: %d": "Records imported: %d",
+	", с ошибками: %d":          ", with errors: %d",
+	"Строка %d":                 "Row %d",
+	"и еще ошибок: %d":          "and %d more errors",
+
+	// Операции
+	"Умножение":                   "Multiplication",
+	"Умножить":                    "Multiply",
+	"Только умножение":            "Multiplication only",
+	"Деление":                     "Division",
+	"Разделить":                   "Divide",
+	"Только деление":              "Division only",
+	"Сложение":                    "Addition",
+	"Сложить":                     "Add",
+	"Только сложение":             "Addition only",
+	"Вычитание":                   "Subtraction",
+	"Вычесть":                     "Subtract",
+	"Только вычитание":            "Subtraction only",
+	"Возведение в квадрат":        "Square",
+	"Квадрат":                     "Square",
+	"Только возведение в квадрат": "Squares only",
+	"Выражение":                   "Expression",
+	"Только выражения":            "Expressions only",
+
+	// История результатов
+	"История результатов": "Result history",
+	"Пользователь:":       "User:",
+	"свои; * - все":       "own; * - everyone",
+	"Показать":            "Show",
+	"Первое число":        "First number",
+	"Второе число":        "Second number",
+	"Результат":           "Result",
+	"Операция":            "Operation",
+	"Дата":                "Date",
+	"Точное значение: %s": "Exact value: %s",
+	"Результатов нет":     "No results",
+	"Скачать историю с текущими фильтрами:": "Download history with current filters:",
+
+	// Журнал операций
+	"← К калькулятору":      "← Back to calculator",
+	"Время":                 "Time",
+	"Ввод":                  "Input",
+	"Статус":                "Status",
+	"Ошибка":                "Error",
+	"Класс чисел":           "Number class",
+	"Длительность":          "Duration",
+	"IP адрес":              "IP address",
+	"IP адрес:":             "IP address:",
+	"ID запроса":            "Request ID",
+	"Пользователь":          "User",
+	"Сохраненный результат": "Stored result",
+	"Операнды":              "Operands",
+	"Запись не связана с сохраненным результатом": "The record is not linked to a stored result",
+	"← Весь журнал":    "← Whole log",
+	"Текст во вводе:":  "Text in input:",
+	"Найти":            "Search",
+	"Подробнее":        "Details",
+	"Записей нет":      "No records",
+	"Успешно":          "Success",
+	"Ошибка ввода":     "Invalid input",
+	"Ошибка хранилища": "Storage error",
+
+	// Ошибки ввода операндов
+	"Неверный формат числа":             "Invalid number format",
+	"Неверный формат первого числа":     "Invalid format of the first number",
+	"Неверный формат второго числа":     "Invalid format of the second number",
+	"Неизвестный режим точности":        "Unknown precision mode",
+	"Неизвестный формат чисел":          "Unknown number format",
+	"Не указан операнд %s":              "Operand %s is missing",
+	"операнд должен быть числом JSON":   "the operand must be a JSON number",
+	"неверный формат десятичного числа": "invalid decimal number format",
+
+	// Ошибки вычислений
+	"Деление на ноль невозможно":                                     "Division by zero is not possible",
+	"Операция не поддерживает точный режим вычислений":               "The operation does not support exact precision",
+	"Число должно быть конечным: NaN и бесконечность не допускаются": "The number must be finite: NaN and infinity are not allowed",
+	"Число слишком велико: допустимы значения до ±1.8e308":           "The number is too large: values up to ±1.8e308 are allowed",
+	"Число слишком мало по модулю и округляется до нуля":             "The number is too small in magnitude and rounds to zero",
+	"Число слишком велико: допустимы значения до ±1.8e308: %s":       "The number is too large: values up to ±1.8e308 are allowed: %s",
+	"Число слишком мало по модулю и округляется до нуля: %s":         "The number is too small in magnitude and rounds to zero: %s",
+	"Результат слишком велик: выходит за пределы ±1.8e308":           "The result is too large: it exceeds ±1.8e308",
+	"Результат слишком мал по модулю и округляется до нуля":          "The result is too small in magnitude and rounds to zero",
+	"Результат не является конечным числом":                          "The result is not a finite number",
+	"Результат %s слишком мал по модулю и округляется до нуля":       "The result of %s is too small in magnitude and rounds to zero",
+	"операция %q ожидает %d операнд(а), получено %d":                 "operation %q expects %d operand(s), got %d",
+
+	// Ошибки выражений
+	"Ошибка в выражении: %s":                    "Error in expression: %s",
+	"%s (позиция %d)":                           "%s (position %d)",
+	"Пустое выражение":                          "Empty expression",
+	"Выражение слишком длинное":                 "The expression is too long",
+	"Слишком большая вложенность выражения":     "The expression is nested too deeply",
+	"Неверная запись числа %q":                  "Invalid number %q",
+	"Недопустимый символ %q":                    "Invalid character %q",
+	"Неожиданный символ %q":                     "Unexpected character %q",
+	"Неизвестный идентификатор %q":              "Unknown identifier %q",
+	"Ожидалась закрывающая скобка":              "A closing parenthesis was expected",
+	"Неожиданный конец выражения":               "Unexpected end of expression",
+	"Неизвестная функция %q":                    "Unknown function %q",
+	"Неверное число аргументов функции %s: %d":  "Wrong number of arguments for function %s: %d",
+	"Квадратный корень из отрицательного числа": "Square root of a negative number",
+	"Результат %s не является конечным числом":  "The result of %s is not a finite number",
+
+	// Ошибки хранилища
+	"Ошибка при сохранении результата: %s":     "Failed to save the result: %s",
+	"Ошибка при сохранении результатов: %s":    "Failed to save the results: %s",
+	"Ошибка при получении результатов: %s":     "Failed to load the results: %s",
+	"Ошибка при получении результата: %s":      "Failed to load the result: %s",
+	"Ошибка при сохранении настроек: %s":       "Failed to save the settings: %s",
+	"Ошибка хранилища: %s":                     "Storage error: %s",
+	"запись не найдена":                        "record not found",
+	"запись уже существует":                    "record already exists",
+	"не указана область видимости результатов": "result scope is not specified",
+
+	// Запросы JSON API
+	"Пустое тело запроса":        "Empty request body",
+	"Некорректный JSON: %s":      "Invalid JSON: %s",
+	"Некорректный идентификатор": "Invalid identifier",
+	"Результат не найден":        "Result not found",
+
+	// Пользователи и доступ
+	"Требуется токен API в заголовке Authorization: Bearer": "An API token is required in the Authorization: Bearer header",
+	"Ошибка при проверке токена: %s":                        "Failed to check the token: %s",
+	"Ошибка при отзыве токена: %s":                          "Failed to revoke the token: %s",
+	"Имя пользователя уже занято":                           "The username is already taken",
+	"Имя пользователя должно содержать от %d до %d символов: латинские буквы, цифры, '.', '_' или '-'": "The username must contain %d to %d characters: Latin letters, digits, '.', '_' or '-'",
+	"Пароль должен содержать от %d до %d байт":                                                         "The password must contain %d to %d bytes",
+	"Неверное имя пользователя или пароль":                                                             "Invalid username or password",
+	"Просмотр истории других пользователей доступен только администратору":                             "Only an administrator can view other users' history",
+	"Журнал операций доступен только администратору":                                                   "The operation log is available only to an administrator",
+	"Слишком много запросов, повторите через %d с":                                                     "Too many requests, retry in %d s",
+
+	// Параметры истории, журнала и выгрузки
+	"Номер страницы должен быть положительным целым числом": "The page number must be a positive integer",
+	"Номер страницы должен быть не больше %d":               "The page number must not exceed %d",
+	"Размер страницы должен быть от 1 до %d":                "The page size must be between 1 and %d",
+	"Сортировка по полю %s не поддерживается":               "Sorting by field %s is not supported",
+	"Направление сортировки должно быть asc или desc":       "The sort order must be asc or desc",
+	"Неизвестная операция %s":                               "Unknown operation %s",
+	"Неизвестная операция %q":                               "Unknown operation %q",
+	"Неверный формат начальной даты":                        "Invalid start date format",
+	"Неверный формат конечной даты":                         "Invalid end date format",
+	"Начальная дата позже конечной":                         "The start date is after the end date",
+	"Пользователь %s не найден":                             "User %s not found",
+	"Неверный формат IP адреса":                             "Invalid IP address format",
+	"Искомый текст длиннее %d символов":                     "The search text is longer than %d characters",
+	"Формат выгрузки должен быть csv, json или ndjson":      "The export format must be csv, json or ndjson",
+	"Ошибка при получении журнала: %s":                      "Failed to load the log: %s",
+	"Некорректный идентификатор записи":                     "Invalid record identifier",
+	"Запись журнала не найдена":                             "Log record not found",
+	"Ошибка при получении записи журнала: %s":               "Failed to load the log record: %s",
+
+	// Пакетные вычисления
+	"Пакет содержит больше %d вычислений": "The batch contains more than %d calculations",
+	"Строка NDJSON длиннее %d байт":       "An NDJSON line is longer than %d bytes",
+	"ожидается массив вычислений":         "an array of calculations is expected",
+	"Операция %s принимает операндов: %d": "Operation %s takes %d operands",
+	"Некорректный пакет: %s":              "Invalid batch: %s",
+	"Пакет не содержит вычислений":        "The batch contains no calculations",
+	"Пакет больше %d МБ":                  "The batch is larger than %d MB",
+
+	// Импорт
+	"Ошибка импорта: %s":                                 "Import error: %s",
+	"Поддерживаются файлы CSV и JSON":                    "Only CSV and JSON files are supported",
+	"Файл больше %d МБ":                                  "The file is larger than %d MB",
+	"Не выбран файл":                                     "No file selected",
+	"Ошибка чтения файла: %s":                            "Failed to read the file: %s",
+	"Файл не содержит вычислений":                        "The file contains no calculations",
+	"Файл содержит больше %d строк":                      "The file contains more than %d rows",
+	"В заголовке CSV нет столбца operation":              "The CSV header has no operation column",
+	"Элемент не является объектом":                       "The item is not an object",
+	"Поле operands должно быть массивом операндов":       "The operands field must be an array of operands",
+	"Некорректный файл: %s":                              "Invalid file: %s",
+	"Не указана операция":                                "The operation is missing",
+	"Не указан результат":                                "The result is missing",
+	"Неверный формат даты %s":                            "Invalid date format %s",
+	"Не указано выражение":                               "The expression is missing",
+	"Неверный формат результата":                         "Invalid result format",
+	"Результат в файле %s не совпадает с вычисленным %s": "The result in the file %s does not match the computed %s",
+}
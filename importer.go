@@ -0,0 +1,623 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"math/big"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/igor-fedko/go_multiply_app/expr"
+	"github.com/igor-fedko/go_multiply_app/locale"
+	"github.com/igor-fedko/go_multiply_app/models"
+	"github.com/igor-fedko/go_multiply_app/operations"
+	"github.com/igor-fedko/go_multiply_app/storage"
+)
+
+// Ограничения загружаемого файла
+const (
+	maxImportSize = 10 << 20
+	maxImportRows = 10000
+)
+
+// importFileField - поле формы с загружаемым файлом
+const importFileField = "file"
+
+// importErrorsShown - сколько ошибок строк показывается на странице
+const importErrorsShown = 50
+
+// Форматы загружаемых файлов
+const (
+	importCSV  = "csv"
+	importJSON = "json"
+)
+
+// Коды ошибок импорта
+const (
+	errCodeInvalidFile       = "invalid_file"
+	errCodeUnsupportedFormat = "unsupported_format"
+	errCodeImportTooLarge    = "import_too_large"
+	errCodeEmptyImport       = "empty_import"
+	errCodeMissingField      = "missing_field"
+	errCodeInvalidDate       = "invalid_date"
+	errCodeResultMismatch    = "result_mismatch"
+)
+
+// resultTolerance - допустимое относительное расхождение результата
+// в файле с пересчитанным значением float64
+const resultTolerance = 1e-9
+
+// importDateLayouts - форматы даты в столбце created_at: RFC 3339 (выгрузка),
+// формат таблицы истории и распространенные форматы электронных таблиц
+var importDateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	"02.01.2006 15:04:05",
+	"02.01.2006",
+}
+
+// importFailure - ошибка загрузки файла целиком
+type importFailure struct {
+	Status  int
+	Code    string
+	Message string
+}
+
+func (e *importFailure) Error() string {
+	return e.Message
+}
+
+// importRecord - строка файла: значения столбцов по именам.
+// Row - номер строки CSV или номер элемента JSON массива, начиная с 1.
+// Locale - локаль записи чисел в CSV; nil - числа JSON, которые, как
+// и в JSON API, записываются с десятичной точкой.
+type importRecord struct {
+	Row    int
+	Fields map[string]string
+	Locale *locale.Locale
+}
+
+// number возвращает запись числа из поля field в формате strconv.ParseFloat
+func (rec importRecord) number(field string) string {
+	if rec.Locale == nil {
+		return rec.Fields[field]
+	}
+	return rec.Locale.Normalize(rec.Fields[field])
+}
+
+// importRowError - ошибка в строке файла
+type importRowError struct {
+	Row     int    `json:"row"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Field   string `json:"field,omitempty"`
+}
+
+// importReport - итоги импорта
+type importReport struct {
+	Imported int              `json:"imported"`
+	Failed   int              `json:"failed"`
+	Errors   []importRowError `json:"errors"`
+}
+
+// ShownErrors возвращает ошибки, показываемые на странице
+func (r importReport) ShownErrors() []importRowError {
+	if len(r.Errors) > importErrorsShown {
+		return r.Errors[:importErrorsShown]
+	}
+	return r.Errors
+}
+
+// HiddenErrors возвращает число ошибок, не показанных на странице
+func (r importReport) HiddenErrors() int {
+	return max(0, len(r.Errors)-importErrorsShown)
+}
+
+// openImport возвращает содержимое загруженного файла и его формат.
+// Файл передается полем file формы multipart/form-data или телом запроса
+// с типом text/csv или application/json. Формат определяется
+// по расширению имени файла, иначе по типу содержимого.
+func openImport(c *gin.Context) ([]byte, string, error) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+
+	mediaType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	var (
+		data []byte
+		err  error
+	)
+	if mediaType == "multipart/form-data" {
+		file, header, ferr := c.Request.FormFile(importFileField)
+		if ferr != nil {
+			return nil, "", importReadError(ferr)
+		}
+		defer file.Close()
+
+		if data, err = io.ReadAll(file); err != nil {
+			return nil, "", importReadError(err)
+		}
+		mediaType = header.Header.Get("Content-Type")
+		switch strings.ToLower(filepath.Ext(header.Filename)) {
+		case ".csv":
+			return data, importCSV, nil
+		case ".json":
+			return data, importJSON, nil
+		}
+		mediaType, _, _ = mime.ParseMediaType(mediaType)
+	} else if data, err = io.ReadAll(c.Request.Body); err != nil {
+		return nil, "", importReadError(err)
+	}
+
+	switch mediaType {
+	case "text/csv":
+		return data, importCSV, nil
+	case "application/json":
+		return data, importJSON, nil
+	}
+	return nil, "", &importFailure{
+		Status:  http.StatusUnsupportedMediaType,
+		Code:    errCodeUnsupportedFormat,
+		Message: "Поддерживаются файлы CSV и JSON",
+	}
+}
+
+// importReadError описывает ошибку чтения загрузки
+func importReadError(err error) error {
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		return &importFailure{
+			Status:  http.StatusRequestEntityTooLarge,
+			Code:    errCodeImportTooLarge,
+			Message: fmt.Sprintf("Файл больше %d МБ", maxImportSize>>20),
+		}
+	}
+	if errors.Is(err, http.ErrMissingFile) {
+		return &importFailure{Status: http.StatusBadRequest, Code: errCodeInvalidFile, Message: "Не выбран файл"}
+	}
+	return &importFailure{Status: http.StatusBadRequest, Code: errCodeInvalidFile, Message: "Ошибка чтения файла: " + err.Error()}
+}
+
+// parseImport разбирает файл в строки. Строки, которые нельзя разобрать
+// (например, элемент JSON массива не является объектом), возвращаются
+// как ошибки строк; ошибка формата файла целиком - как *importFailure.
+func parseImport(data []byte, format string, loc locale.Locale) ([]importRecord, []importRowError, error) {
+	var (
+		records []importRecord
+		rowErrs []importRowError
+		err     error
+	)
+	if format == importCSV {
+		records, err = parseImportCSV(data, loc)
+	} else {
+		records, rowErrs, err = parseImportJSON(data)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch total := len(records) + len(rowErrs); {
+	case total == 0:
+		return nil, nil, &importFailure{Status: http.StatusBadRequest, Code: errCodeEmptyImport, Message: "Файл не содержит вычислений"}
+	case total > maxImportRows:
+		return nil, nil, &importFailure{
+			Status:  http.StatusRequestEntityTooLarge,
+			Code:    errCodeImportTooLarge,
+			Message: fmt.Sprintf("Файл содержит больше %d строк", maxImportRows),
+		}
+	}
+	return records, rowErrs, nil
+}
+
+// parseImportCSV разбирает CSV с заголовком. Имена столбцов совпадают
+// с полями выгрузки (operation, number1, number2, result, ...), лишние
+// столбцы игнорируются. Разделитель - запятая или точка с запятой
+// (так сохраняет CSV Excel в русской локали), BOM в начале файла пропускается.
+// Числа записываются в локали loc, а в файле с точкой с запятой - в русской
+// локали, как их сохраняет Excel: "1,5".
+func parseImportCSV(data []byte, loc locale.Locale) ([]importRecord, error) {
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+
+	firstLine, _, _ := bufio.NewReader(bytes.NewReader(data)).ReadLine()
+	r := csv.NewReader(bytes.NewReader(data))
+	if bytes.Count(firstLine, []byte(";")) > bytes.Count(firstLine, []byte(",")) {
+		r.Comma = ';'
+		loc = locale.Russian
+	}
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	header, err := r.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, invalidFile(err)
+	}
+	for i := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(header[i]))
+	}
+	if !slices.Contains(header, "operation") {
+		return nil, &importFailure{Status: http.StatusBadRequest, Code: errCodeInvalidFile, Message: "В заголовке CSV нет столбца operation"}
+	}
+
+	var records []importRecord
+	for {
+		row, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+		if err != nil {
+			return nil, invalidFile(err)
+		}
+		if len(records) == maxImportRows {
+			// Одна лишняя строка - сигнал превышения для parseImport
+			return append(records, importRecord{}), nil
+		}
+
+		line, _ := r.FieldPos(0)
+		fields := make(map[string]string, len(header))
+		empty := true
+		for i, value := range row {
+			if i < len(header) && header[i] != "" {
+				fields[header[i]] = strings.TrimSpace(value)
+				empty = empty && fields[header[i]] == ""
+			}
+		}
+		if !empty {
+			records = append(records, importRecord{Row: line, Fields: fields, Locale: &loc})
+		}
+	}
+}
+
+// parseImportJSON разбирает JSON массив объектов с теми же полями, что и CSV,
+// или с массивом operands, как в выгрузке JSON. Числа можно передавать
+// числами или строками.
+func parseImportJSON(data []byte) ([]importRecord, []importRowError, error) {
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, nil, invalidFile(err)
+	}
+	if len(items) > maxImportRows {
+		items = items[:maxImportRows+1]
+	}
+
+	var records []importRecord
+	var rowErrs []importRowError
+	for i, raw := range items {
+		var obj map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &obj); err != nil || obj == nil {
+			rowErrs = append(rowErrs, importRowError{Row: i + 1, Code: errCodeInvalidJSON, Message: "Элемент не является объектом"})
+			continue
+		}
+		fields := make(map[string]string, len(obj))
+		for key, value := range obj {
+			if string(value) != "null" {
+				fields[strings.ToLower(key)] = strings.TrimSpace(rawOperandText(value))
+			}
+		}
+		if raw, ok := obj["operands"]; ok {
+			delete(fields, "operands")
+			if err := addOperandFields(fields, raw); err != nil {
+				rowErrs = append(rowErrs, importRowError{Row: i + 1, Code: errCodeInvalidJSON, Message: "Поле operands должно быть массивом операндов", Field: "operands"})
+				continue
+			}
+		}
+		records = append(records, importRecord{Row: i + 1, Fields: fields})
+	}
+	return records, rowErrs, nil
+}
+
+// addOperandFields переносит массив operands из выгрузки JSON в поля
+// number1, number2, ... и number1_exact, ...; операнд задается объектом
+// {"value": ..., "exact": ...} или числом
+func addOperandFields(fields map[string]string, raw json.RawMessage) error {
+	var operands []json.RawMessage
+	if err := json.Unmarshal(raw, &operands); err != nil {
+		return err
+	}
+	for i, item := range operands {
+		field := operandField(i)
+		var operand struct {
+			Value json.RawMessage `json:"value"`
+			Exact string          `json:"exact"`
+		}
+		if json.Unmarshal(item, &operand) != nil {
+			fields[field] = strings.TrimSpace(rawOperandText(item))
+			continue
+		}
+		fields[field] = strings.TrimSpace(rawOperandText(operand.Value))
+		if operand.Exact != "" {
+			fields[field+"_exact"] = operand.Exact
+		}
+	}
+	return nil
+}
+
+// invalidFile описывает ошибку формата файла
+func invalidFile(err error) error {
+	return &importFailure{Status: http.StatusBadRequest, Code: errCodeInvalidFile, Message: "Некорректный файл: " + err.Error()}
+}
+
+// verifyRecord пересчитывает строку файла и сверяет результат с указанным
+// в файле. Возвращает результат для сохранения с датой из файла
+// (или текущей, если дата не указана).
+func verifyRecord(c *gin.Context, rec importRecord) (models.Result, *importRowError) {
+	rowErr := func(code, message, field string) *importRowError {
+		return &importRowError{Row: rec.Row, Code: code, Message: message, Field: field}
+	}
+
+	name := rec.Fields["operation"]
+	if name == "" {
+		return models.Result{}, rowErr(errCodeMissingField, "Не указана операция", "operation")
+	}
+	expected := rec.number("result")
+	if rec.Fields["precision"] == models.PrecisionExact && rec.Fields["result_exact"] != "" {
+		expected = rec.number("result_exact")
+	}
+	if expected == "" {
+		return models.Result{}, rowErr(errCodeMissingField, "Не указан результат", "result")
+	}
+
+	var createdAt time.Time
+	if s := rec.Fields["created_at"]; s != "" {
+		t, err := parseImportDate(s)
+		if err != nil {
+			return models.Result{}, rowErr(errCodeInvalidDate, "Неверный формат даты "+s, "created_at")
+		}
+		createdAt = t
+	}
+
+	var (
+		result models.Result
+		err    error
+	)
+	if name == models.OperationEvaluate {
+		if rec.Fields["expression"] == "" {
+			return models.Result{}, rowErr(errCodeMissingField, "Не указано выражение", "expression")
+		}
+		result, err = computeEvaluation(c, rec.Fields["expression"])
+	} else {
+		result, err = computeImportedOperation(c, rec, name)
+	}
+	if err != nil {
+		failure := importComputeError(err)
+		failure.Row = rec.Row
+		return models.Result{}, failure
+	}
+
+	if ok, err := resultMatches(result, expected); err != nil {
+		return models.Result{}, rowErr(errCodeInvalidNumber, "Неверный формат результата", "result")
+	} else if !ok {
+		computed := formatCSVFloat(result.Result)
+		if result.ResultExact != "" {
+			computed = result.ResultExact
+		}
+		return models.Result{}, rowErr(errCodeResultMismatch,
+			fmt.Sprintf("Результат в файле %s не совпадает с вычисленным %s", expected, computed), "result")
+	}
+
+	if !createdAt.IsZero() {
+		result.CreatedAt = createdAt
+	}
+	return result, nil
+}
+
+func (e *importRowError) Error() string {
+	return e.Message
+}
+
+// importComputeError переводит ошибку вычисления строки в ошибку строки
+func importComputeError(err error) *importRowError {
+	var (
+		rowErr        *importRowError
+		validationErr *operations.ValidationError
+		exprErr       *expr.Error
+	)
+	switch {
+	case errors.As(err, &rowErr):
+		return rowErr
+	case errors.As(err, &validationErr):
+		var field string
+		if validationErr.Operand >= 0 {
+			field = operandField(validationErr.Operand)
+		}
+		return &importRowError{Code: validationErr.Code, Message: validationErr.Message, Field: field}
+	case errors.As(err, &exprErr):
+		return &importRowError{Code: exprErr.Code, Message: "Ошибка в выражении: " + exprErr.Error(), Field: "expression"}
+	default:
+		return &importRowError{Code: errCodeInvalidNumber, Message: err.Error()}
+	}
+}
+
+// computeImportedOperation разбирает операнды строки и вычисляет операцию.
+// Ошибки разбора возвращаются как *importRowError.
+func computeImportedOperation(c *gin.Context, rec importRecord, name string) (models.Result, error) {
+	op, ok := operations.Default.Get(name)
+	if !ok {
+		return models.Result{}, &importRowError{Code: errCodeUnknownOperation, Message: "Неизвестная операция " + name, Field: "operation"}
+	}
+
+	precision, err := parsePrecision(rec.Fields["precision"])
+	if err != nil {
+		return models.Result{}, &importRowError{Code: errCodeInvalidPrecision, Message: err.Error(), Field: "precision"}
+	}
+
+	operands := make([]float64, op.Arity())
+	var exact []*big.Rat
+	for i := range operands {
+		field := operandField(i)
+		text := rec.number(field)
+		if precision == models.PrecisionExact && rec.Fields[field+"_exact"] != "" {
+			text = rec.number(field + "_exact")
+		}
+		if text == "" {
+			return models.Result{}, &importRowError{Code: errCodeMissingOperand, Message: "Не указан операнд " + field, Field: field}
+		}
+		value, exactValue, err := parseOperand(text, precision, i)
+		if numErr, ok := numericError(err); ok {
+			return models.Result{}, &importRowError{Code: numErr.Code, Message: numErr.Message, Field: field}
+		}
+		if err != nil {
+			return models.Result{}, &importRowError{Code: errCodeInvalidNumber, Message: invalidOperandMessage(op, i), Field: field}
+		}
+		operands[i] = value
+		if exactValue != nil {
+			exact = append(exact, exactValue)
+		}
+	}
+
+	return computeOperation(c, op, operands, exact)
+}
+
+// parseImportDate разбирает дату в одном из форматов importDateLayouts (UTC)
+func parseImportDate(s string) (time.Time, error) {
+	var err error
+	for _, layout := range importDateLayouts {
+		var t time.Time
+		if t, err = time.Parse(layout, s); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, err
+}
+
+// resultMatches сравнивает вычисленный результат с записью в файле:
+// в точном режиме - точно, иначе с относительной погрешностью resultTolerance
+func resultMatches(result models.Result, expected string) (bool, error) {
+	if result.ResultExact != "" {
+		want, err := operations.ParseExact(expected)
+		if err != nil {
+			return false, err
+		}
+		got, err := operations.ParseExact(result.ResultExact)
+		if err != nil {
+			return false, err
+		}
+		return want.Cmp(got) == 0, nil
+	}
+
+	want, _, err := parseOperand(expected, models.PrecisionFloat, operations.NoOperand)
+	if err != nil {
+		return false, err
+	}
+	got := result.Result
+	if want == got {
+		return true, nil
+	}
+	return math.Abs(want-got) <= resultTolerance*math.Max(math.Abs(want), math.Abs(got)), nil
+}
+
+// importResults проверяет строки файла и сохраняет прошедшие проверку
+// одним обращением к хранилищу
+func importResults(c *gin.Context, data []byte, format string) (importReport, error) {
+	records, rowErrs, err := parseImport(data, format, requestLocale(c))
+	if err != nil {
+		return importReport{}, err
+	}
+
+	report := importReport{Errors: rowErrs}
+	var valid []*models.Result
+	var validRows []int
+	for _, rec := range records {
+		result, rowErr := verifyRecord(c, rec)
+		if rowErr != nil {
+			report.Errors = append(report.Errors, *rowErr)
+			continue
+		}
+		valid = append(valid, &result)
+		validRows = append(validRows, rec.Row)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), appConfig.Timeouts.Write.Duration)
+	defer cancel()
+
+	// Если сохранено только начало файла, об остальных строках сообщается
+	// как об ошибках сохранения, чтобы загрузить повторно только их
+	saved := len(valid)
+	if err := store.InsertResults(ctx, valid); err != nil {
+		slog.ErrorContext(c.Request.Context(), "store imported results", "error", err, "count", len(valid))
+		saved = storage.InsertedBefore(err)
+		if saved == 0 {
+			return importReport{}, err
+		}
+		for _, row := range validRows[saved:] {
+			report.Errors = append(report.Errors, importRowError{
+				Row:     row,
+				Code:    errCodeStorageError,
+				Message: "Ошибка при сохранении результата: " + err.Error(),
+			})
+		}
+	}
+
+	report.Imported = saved
+	report.Failed = len(report.Errors)
+	slog.InfoContext(c.Request.Context(), "results imported",
+		"format", format, "imported", report.Imported, "failed", report.Failed)
+	return report, nil
+}
+
+// importHandler загружает файл из формы главной страницы
+func importHandler(c *gin.Context) {
+	data, format, err := openImport(c)
+	var report importReport
+	if err == nil {
+		report, err = importResults(c, data, format)
+	}
+
+	var failure *importFailure
+	switch {
+	case errors.As(err, &failure):
+		renderIndex(c, failure.Status, gin.H{"Error": "Ошибка импорта: " + failure.Message})
+	case err != nil:
+		renderIndex(c, http.StatusInternalServerError, gin.H{"Error": "Ошибка при сохранении результатов: " + err.Error()})
+	default:
+		query := defaultHistoryQuery()
+		query.scope = storage.UserScope(currentUserID(c))
+		results, page, err := listHistory(query)
+		if err != nil {
+			renderIndex(c, http.StatusInternalServerError, gin.H{"Error": "Ошибка при получении результатов: " + err.Error(), "Import": report})
+			return
+		}
+		renderIndex(c, http.StatusOK, gin.H{"Import": report, "Results": results, "Pagination": page})
+	}
+}
+
+// apiImportHandler загружает файл через JSON API
+func apiImportHandler(c *gin.Context) {
+	data, format, err := openImport(c)
+	var report importReport
+	if err == nil {
+		report, err = importResults(c, data, format)
+	}
+
+	var failure *importFailure
+	switch {
+	case errors.As(err, &failure):
+		respondAPIError(c, failure.Status, failure.Code, failure.Message, "")
+	case err != nil:
+		respondAPIError(c, http.StatusInternalServerError, errCodeStorageError,
+			"Ошибка при сохранении результатов: "+err.Error(), "")
+	default:
+		p := printer(c)
+		localized := make([]importRowError, len(report.Errors))
+		for i, rowErr := range report.Errors {
+			rowErr.Message = p.Translate(rowErr.Message)
+			localized[i] = rowErr
+		}
+		report.Errors = localized
+		c.JSON(http.StatusOK, report)
+	}
+}
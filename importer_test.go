@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/igor-fedko/go_multiply_app/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// upload отправляет файл name на адрес path формой multipart/form-data
+func (s *APITestSuite) upload(path, name, content string) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, err := mw.CreateFormFile(importFileField, name)
+	require.NoError(s.T(), err)
+	_, err = part.Write([]byte(content))
+	require.NoError(s.T(), err)
+	require.NoError(s.T(), mw.Close())
+
+	req := httptest.NewRequest(http.MethodPost, path, &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return s.do(req)
+}
+
+// apiImport загружает файл через API и возвращает итоги импорта
+func (s *APITestSuite) apiImport(name, content string) importReport {
+	w := s.upload("/api/v1/import", name, content)
+	require.Equal(s.T(), http.StatusOK, w.Code, w.Body.String())
+
+	var report importReport
+	require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &report))
+	return report
+}
+
+// TestImportCSV тестирует импорт CSV с проверкой результатов
+func (s *APITestSuite) TestImportCSV() {
+	report := s.apiImport("legacy.csv", "\ufeffoperation;number1;number2;result;created_at\n"+
+		"multiply;2;3;6;2020-01-15 10:00:00\n"+
+		"divide;1;3;0.333333333333;\n"+
+		"divide;1;0;0;\n"+
+		"add;2;2;5;\n"+
+		"add;x;2;4;\n"+
+		"\n"+
+		"square;4;;16;15.01.2020\n"+
+		"evaluate;;;14;\n")
+
+	// Округленный результат деления принимается с допуском
+	assert.Equal(s.T(), 3, report.Imported)
+	assert.Equal(s.T(), 4, report.Failed)
+	codes := map[int]string{}
+	for _, e := range report.Errors {
+		codes[e.Row] = e.Code
+	}
+	assert.Equal(s.T(), map[int]string{
+		4: "division_by_zero", 5: "result_mismatch", 6: "invalid_number", 9: "missing_field",
+	}, codes)
+
+	results, err := s.store.ListResults(context.Background(), storage.ResultQuery{
+		Scope: storage.UserScope(s.user.ID), SortBy: storage.SortCreatedAt, SortAsc: true,
+	})
+	require.NoError(s.T(), err)
+	require.Len(s.T(), results, 3)
+	assert.Equal(s.T(), time.Date(2020, 1, 15, 0, 0, 0, 0, time.UTC), results[0].CreatedAt)
+	assert.Equal(s.T(), "square", results[0].Operation)
+	assert.Equal(s.T(), time.Date(2020, 1, 15, 10, 0, 0, 0, time.UTC), results[1].CreatedAt)
+	assert.Equal(s.T(), 6.0, results[1].Result)
+}
+
+// TestImportLocaleNumbers тестирует разбор чисел CSV в локали: файл
+// с точкой с запятой - в русской локали, как его сохраняет Excel,
+// остальные - в локали пользователя
+func (s *APITestSuite) TestImportLocaleNumbers() {
+	require.Equal(s.T(), http.StatusSeeOther, s.postForm("/settings/locale", url.Values{"locale": {"en"}}).Code)
+
+	report := s.apiImport("excel.csv", "operation;number1;number2;result\n"+
+		"add;1,5;2,25;3,75\n"+
+		"divide;1;3;0,333333333333\n")
+	assert.Equal(s.T(), 2, report.Imported)
+	assert.Empty(s.T(), report.Errors)
+
+	// В английской локали запятая разделяет разряды
+	report = s.apiImport("en.csv", "operation,number1,number2,result\n"+
+		"multiply,\"1,000\",2,\"2,000\"\n"+
+		"add,\"1,5\",1,2.5\n")
+	assert.Equal(s.T(), 1, report.Imported)
+	require.Len(s.T(), report.Errors, 1)
+	assert.Equal(s.T(), importRowError{Row: 3, Code: "invalid_number", Message: "Неверный формат первого числа", Field: "number1"}, report.Errors[0])
+
+	count, err := s.store.CountResults(context.Background(), storage.ResultQuery{Scope: storage.UserScope(s.user.ID)})
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), int64(3), count)
+}
+
+// TestImportExportRoundTrip тестирует импорт файлов выгрузки
+func (s *APITestSuite) TestImportExportRoundTrip() {
+	require.Equal(s.T(), http.StatusCreated, s.postJSON("/api/v1/add", `{"number1": "0.1", "number2": "0.2", "precision": "exact"}`).Code)
+	require.Equal(s.T(), http.StatusCreated, s.postJSON("/api/v1/evaluate", `{"expression": "2 + 3 * 4"}`).Code)
+
+	exports := map[string]string{}
+	for _, format := range []string{"csv", "json"} {
+		w := s.get("/api/v1/export?format=" + format)
+		require.Equal(s.T(), http.StatusOK, w.Code)
+		exports[format] = w.Body.String()
+	}
+
+	for format, data := range exports {
+		report := s.apiImport("export."+format, data)
+		assert.Equal(s.T(), 2, report.Imported, format)
+		assert.Empty(s.T(), report.Errors, format)
+	}
+
+	count, err := s.store.CountResults(context.Background(), storage.ResultQuery{Scope: storage.AllUsers(), Operation: "add"})
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), int64(3), count)
+}
+
+// TestImportJSONBody тестирует импорт JSON, переданного телом запроса
+func (s *APITestSuite) TestImportJSONBody() {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/import", strings.NewReader(
+		`[{"operation": "multiply", "number1": 4, "number2": "2.5", "result": 10}, 7, {"operation": "pow", "result": 1}]`))
+	req.Header.Set("Content-Type", "application/json")
+	w := s.do(req)
+	require.Equal(s.T(), http.StatusOK, w.Code)
+
+	var report importReport
+	require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &report))
+	assert.Equal(s.T(), 1, report.Imported)
+	require.Len(s.T(), report.Errors, 2)
+	assert.Equal(s.T(), importRowError{Row: 2, Code: "invalid_json", Message: "Элемент не является объектом"}, report.Errors[0])
+	assert.Equal(s.T(), "unknown_operation", report.Errors[1].Code)
+}
+
+// TestImportPartialInsert тестирует импорт, сохраненный хранилищем
+// частично: несохраненные строки попадают в отчет как ошибки
+func (s *APITestSuite) TestImportPartialInsert() {
+	store = partialStore{Store: store, saved: 1}
+
+	report := s.apiImport("data.csv", "operation,number1,number2,result\n"+
+		"multiply,2,3,6\n"+
+		"add,1,1,3\n"+
+		"add,1,1,2\n")
+	assert.Equal(s.T(), 1, report.Imported)
+	assert.Equal(s.T(), 2, report.Failed)
+	require.Len(s.T(), report.Errors, 2)
+	assert.Equal(s.T(), "result_mismatch", report.Errors[0].Code)
+	assert.Equal(s.T(), importRowError{Row: 4, Code: "storage_error", Message: "Ошибка при сохранении результата: connection reset"}, report.Errors[1])
+
+	count, err := s.store.CountResults(context.Background(), storage.ResultQuery{Scope: storage.AllUsers()})
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), int64(1), count)
+
+	// Ошибка без сохраненных результатов прерывает импорт
+	store = partialStore{Store: s.store, saved: 0}
+	w := s.upload("/api/v1/import", "data.csv", "operation,number1,number2,result\nmultiply,2,3,6\n")
+	assert.Equal(s.T(), http.StatusInternalServerError, w.Code)
+	assert.Contains(s.T(), w.Body.String(), `"code":"storage_error"`)
+}
+
+// TestImportInvalidFile тестирует ошибки файла целиком
+func (s *APITestSuite) TestImportInvalidFile() {
+	tests := map[string]struct {
+		name, content string
+		status        int
+		code          string
+	}{
+		"format":    {"data.xlsx", "...", http.StatusUnsupportedMediaType, "unsupported_format"},
+		"no column": {"data.csv", "a,b\n1,2\n", http.StatusBadRequest, "invalid_file"},
+		"empty":     {"data.csv", "operation,result\n", http.StatusBadRequest, "empty_import"},
+		"json":      {"data.json", `{"operation": "add"}`, http.StatusBadRequest, "invalid_file"},
+		"rows":      {"data.csv", "operation\n" + strings.Repeat("add\n", maxImportRows+1), http.StatusRequestEntityTooLarge, "import_too_large"},
+	}
+
+	for name, tt := range tests {
+		w := s.upload("/api/v1/import", tt.name, tt.content)
+		assert.Equal(s.T(), tt.status, w.Code, name)
+		assert.Contains(s.T(), w.Body.String(), `"code":"`+tt.code+`"`, name)
+	}
+}
+
+// TestImportForm тестирует импорт через форму главной страницы
+func (s *APITestSuite) TestImportForm() {
+	w := s.upload("/import", "legacy.csv", "operation,number1,number2,result\nmultiply,2,3,6\nadd,1,1,3\n")
+	require.Equal(s.T(), http.StatusOK, w.Code)
+	body := w.Body.String()
+	assert.Contains(s.T(), body, "Импортировано записей: 1, с ошибками: 1")
+	assert.Contains(s.T(), body, "Строка 3 (result)")
+	assert.Contains(s.T(), body, `<td>6</td>`)
+
+	w = s.upload("/import", "legacy.txt", "operation")
+	assert.Equal(s.T(), http.StatusUnsupportedMediaType, w.Code)
+	assert.Contains(s.T(), w.Body.String(), "Поддерживаются файлы CSV и JSON")
+}
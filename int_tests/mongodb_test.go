@@ -2,8 +2,13 @@ package int_tests
 
 import (
 	"context"
+	"github.com/igor-fedko/go_multiply_app/models"
+	"github.com/igor-fedko/go_multiply_app/storage"
+	"github.com/igor-fedko/go_multiply_app/storage/mongostore"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"testing"
 	"time"
 )
@@ -12,11 +17,11 @@ func TestMongoDB_InsertValidResult(t *testing.T) {
 	env.BeforeEach()
 
 	doc := bson.M{
-		"number1":    10.5,
-		"number2":    5.0,
-		"result":     15.5,
-		"operation":  "add",
-		"created_at": time.Now(),
+		"schema_version": 2,
+		"operands":       bson.A{bson.M{"value": 10.5}, bson.M{"value": 5.0}},
+		"result":         15.5,
+		"operation":      "add",
+		"created_at":     time.Now(),
 	}
 
 	_, err := env.Client.Database("testdb").Collection("results").InsertOne(context.Background(), doc)
@@ -27,8 +32,8 @@ func TestMongoDB_InsertInvalidResult_MissingField(t *testing.T) {
 	env.BeforeEach()
 
 	doc := bson.M{
-		"number1": 10.5,
-		"number2": 5.0,
+		"schema_version": 2,
+		"operands":       bson.A{bson.M{"value": 10.5}, bson.M{"value": 5.0}},
 		// result пропущен
 		"operation":  "add",
 		"created_at": time.Now(),
@@ -42,11 +47,11 @@ func TestMongoDB_InsertInvalidResult_WrongType(t *testing.T) {
 	env.BeforeEach()
 
 	doc := bson.M{
-		"number1":    "wrong", // должно быть float64
-		"number2":    5.0,
-		"result":     15.5,
-		"operation":  "add",
-		"created_at": time.Now(),
+		"schema_version": 2,
+		"operands":       bson.A{bson.M{"value": "wrong"}, bson.M{"value": 5.0}}, // должно быть float64
+		"result":         15.5,
+		"operation":      "add",
+		"created_at":     time.Now(),
 	}
 
 	_, err := env.Client.Database("testdb").Collection("results").InsertOne(context.Background(), doc)
@@ -89,3 +94,115 @@ func TestMongoDB_LogInsert_ValidationFails(t *testing.T) {
 	_, err := env.Client.Database("testdb").Collection("logs").InsertOne(context.Background(), doc)
 	assert.Error(t, err)
 }
+
+func TestMongoDB_MigrationsKeepData(t *testing.T) {
+	ctx := context.Background()
+	db := env.Client.Database("testdb")
+	store := mongostore.New(env.Client, "testdb", "results", "logs")
+
+	_, err := db.Collection("results").InsertOne(ctx, bson.M{
+		"schema_version": 2, "operands": bson.A{bson.M{"value": 1.0}, bson.M{"value": 2.0}},
+		"result": 3.0, "operation": "add", "created_at": time.Now(),
+	})
+	require.NoError(t, err)
+	before, err := db.Collection("results").CountDocuments(ctx, bson.M{})
+	require.NoError(t, err)
+
+	// Миграции уже применены в TestMain: повторный запуск ничего не меняет
+	require.NoError(t, store.MigrateUp(ctx))
+
+	// Откат снимает валидацию, но не удаляет документы
+	require.NoError(t, store.MigrateDown(ctx))
+	statuses, err := store.MigrationStatus(ctx)
+	require.NoError(t, err)
+	assert.False(t, statuses[len(statuses)-1].Applied)
+
+	after, err := db.Collection("results").CountDocuments(ctx, bson.M{})
+	require.NoError(t, err)
+	assert.Equal(t, before, after)
+
+	require.NoError(t, store.MigrateUp(ctx))
+	statuses, err = store.MigrationStatus(ctx)
+	require.NoError(t, err)
+	assert.True(t, statuses[len(statuses)-1].Applied)
+
+	// Страницы истории сортируются по индексам с _id
+	cursor, err := db.Collection("results").Indexes().List(ctx)
+	require.NoError(t, err)
+	var indexes []bson.M
+	require.NoError(t, cursor.All(ctx, &indexes))
+	var names []string
+	for _, index := range indexes {
+		names = append(names, index["name"].(string))
+	}
+	assert.Contains(t, names, "operation_1_created_at_-1__id_-1")
+	assert.Contains(t, names, "user_id_1_created_at_-1__id_-1")
+}
+
+func TestMongoDB_ResultOperandsMigration(t *testing.T) {
+	env.BeforeEach()
+	ctx := context.Background()
+	coll := env.Client.Database("testdb").Collection("results")
+	store := mongostore.New(env.Client, "testdb", "results", "logs")
+
+	// Документы первой версии схемы: операнды в number1/number2.
+	// Откатываются индексы страниц и миграция операндов.
+	require.NoError(t, store.MigrateDown(ctx))
+	require.NoError(t, store.MigrateDown(ctx))
+	binary, err := coll.InsertOne(ctx, bson.M{
+		"number1": 0.1, "number2": 0.2, "number1_exact": "0.1", "number2_exact": "0.2",
+		"result": 0.3, "operation": "add", "created_at": time.Now(),
+	})
+	require.NoError(t, err)
+	unary, err := coll.InsertOne(ctx, bson.M{
+		"number1": -3.0, "number2": 0.0, "result": 9.0, "operation": "square", "created_at": time.Now(),
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, store.MigrateUp(ctx))
+
+	got, err := store.GetResult(ctx, storage.AllUsers(), binary.InsertedID.(primitive.ObjectID))
+	require.NoError(t, err)
+	assert.Equal(t, models.ResultSchemaOperands, got.SchemaVersion)
+	assert.Equal(t, []models.Operand{{Value: 0.1, Exact: "0.1"}, {Value: 0.2, Exact: "0.2"}}, got.Operands)
+
+	got, err = store.GetResult(ctx, storage.AllUsers(), unary.InsertedID.(primitive.ObjectID))
+	require.NoError(t, err)
+	assert.Equal(t, []models.Operand{{Value: -3}}, got.Operands)
+
+	// Документы переписаны на месте: старых полей не осталось
+	legacy, err := coll.CountDocuments(ctx, bson.M{"number1": bson.M{"$exists": true}})
+	require.NoError(t, err)
+	assert.Zero(t, legacy)
+}
+
+func TestMongoDB_InsertResultsPartial(t *testing.T) {
+	env.BeforeEach()
+	ctx := context.Background()
+	store := mongostore.New(env.Client, "testdb", "results", "logs")
+
+	// Второй результат повторяет ID первого: insertMany сохраняет
+	// только первый и останавливается на ошибке
+	id := primitive.NewObjectID()
+	results := make([]*models.Result, 3)
+	for i := range results {
+		results[i] = &models.Result{
+			SchemaVersion: models.ResultSchemaOperands,
+			Operands:      []models.Operand{{Value: float64(i)}, {Value: 1}},
+			Result:        float64(i + 1),
+			Operation:     "add",
+			CreatedAt:     time.Now(),
+		}
+	}
+	results[0].ID = id
+	results[1].ID = id
+
+	err := store.InsertResults(ctx, results)
+	var partial *storage.PartialInsertError
+	require.ErrorAs(t, err, &partial)
+	assert.Equal(t, 1, partial.Inserted)
+
+	count, err := env.Client.Database("testdb").Collection("results").CountDocuments(ctx, bson.M{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/igor-fedko/go_multiply_app/i18n"
+)
+
+// langParam - параметр строки запроса и cookie с выбранным языком интерфейса
+const langParam = "lang"
+
+// langCookieMaxAge - срок хранения выбранного языка в cookie, в секундах
+const langCookieMaxAge = 365 * 24 * 60 * 60
+
+// langContextKey - ключ языка запроса в gin.Context
+const langContextKey = "lang"
+
+// languageMiddleware выбирает язык ответа: параметр lang строки запроса,
+// cookie lang или заголовок Accept-Language. Язык из параметра
+// запоминается в cookie, поэтому переключатель языка достаточно нажать один раз.
+func languageMiddleware(c *gin.Context) {
+	lang := negotiateLanguage(c)
+	c.Set(langContextKey, lang)
+	c.Header("Content-Language", lang)
+	c.Next()
+}
+
+// negotiateLanguage возвращает язык запроса по параметру, cookie и заголовку
+func negotiateLanguage(c *gin.Context) string {
+	if lang, ok := i18n.Lookup(c.Query(langParam)); ok {
+		c.SetSameSite(http.SameSiteLaxMode)
+		c.SetCookie(langParam, lang, langCookieMaxAge, "/", "", appConfig.Auth.CookieSecure, true)
+		return lang
+	}
+	if tag, err := c.Cookie(langParam); err == nil {
+		if lang, ok := i18n.Lookup(tag); ok {
+			return lang
+		}
+	}
+	return i18n.Negotiate(c.GetHeader("Accept-Language"))
+}
+
+// printer возвращает переводчик сообщений на язык запроса
+func printer(c *gin.Context) i18n.Printer {
+	return i18n.NewPrinter(c.GetString(langContextKey))
+}
+
+// localizePage дополняет данные HTML страницы переводчиком и списком
+// языков и переводит сообщение об ошибке в "Error"
+func localizePage(c *gin.Context, data gin.H) {
+	p := printer(c)
+	if message, ok := data["Error"].(string); ok {
+		data["Error"] = p.Translate(message)
+	}
+	data["T"] = p
+	data["Languages"] = i18n.Languages()
+}
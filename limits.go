@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/igor-fedko/go_multiply_app/config"
+	"github.com/igor-fedko/go_multiply_app/ratelimit"
+	"github.com/igor-fedko/go_multiply_app/storage"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// limiter ограничивает частоту запросов; nil - ограничение выключено
+var limiter *ratelimit.Limiter
+
+// rateLimitsCollection - хранилище, предоставляющее коллекцию для общего
+// состояния ограничения частоты (mongostore.Store)
+type rateLimitsCollection interface {
+	RateLimits() *mongo.Collection
+}
+
+// newLimiter создает ограничитель частоты запросов по конфигурации.
+// Состояние mongo хранится в коллекции хранилища s, поэтому s должно быть
+// хранилищем MongoDB.
+func newLimiter(cfg config.RateLimitConfig, s storage.Store) (*ratelimit.Limiter, error) {
+	routes, err := routeLimits(cfg.Routes)
+	if err != nil {
+		return nil, err
+	}
+
+	var limitStore ratelimit.Store = ratelimit.NewMemoryStore()
+	if cfg.Backend == config.RateLimitMongo {
+		mongoStore, ok := s.(rateLimitsCollection)
+		if !ok {
+			return nil, fmt.Errorf("rate_limit.backend: mongo требует хранилища MongoDB")
+		}
+		limitStore = ratelimit.NewMongoStore(mongoStore.RateLimits())
+	}
+
+	def := ratelimit.Limit{Requests: cfg.Requests, Per: cfg.Per.Duration}
+	return ratelimit.New(limitStore, def, routes), nil
+}
+
+// routeLimits переводит лимиты из конфигурации в лимиты маршрутов Gin.
+// Ключ, начинающийся с "/", - шаблон маршрута; иначе это имя операции,
+// и лимит применяется к ее HTML форме и эндпоинту JSON API.
+func routeLimits(routes map[string]config.RouteLimit) (map[string]ratelimit.Limit, error) {
+	limits := make(map[string]ratelimit.Limit, len(routes))
+	for key, route := range routes {
+		limit := ratelimit.Limit{Requests: route.Requests, Per: route.Per.Duration}
+		if strings.HasPrefix(key, "/") {
+			limits[key] = limit
+			continue
+		}
+		if !slices.Contains(operationNames(), key) {
+			return nil, fmt.Errorf("rate_limit.routes: неизвестная операция %q", key)
+		}
+		limits["/"+key] = limit
+		limits["/api/v1/"+key] = limit
+	}
+	return limits, nil
+}
+
+// rateLimitClient определяет клиента для ограничения частоты: вошедший
+// пользователь ограничивается по ID, анонимный клиент - по IP-адресу
+func rateLimitClient(c *gin.Context) string {
+	if user, ok := currentUser(c); ok {
+		return "user:" + user.ID.Hex()
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// rateLimitedMessage возвращает сообщение об отклоненном запросе
+func rateLimitedMessage(retryAfter time.Duration) string {
+	return fmt.Sprintf("Слишком много запросов, повторите через %d с", ratelimit.RetryAfterSeconds(retryAfter))
+}
+
+// limitRequests создает обработчик, ограничивающий частоту запросов
+// к маршруту; reject отвечает на отклоненный запрос. После проверки входа
+// вошедшие пользователи ограничиваются по ID, до нее - по IP-адресу.
+func limitRequests(reject func(*gin.Context, time.Duration)) gin.HandlerFunc {
+	if limiter == nil {
+		return func(c *gin.Context) { c.Next() }
+	}
+	return limiter.Middleware(rateLimitClient, reject)
+}
+
+// rejectPage отвечает на отклоненный запрос к HTML страницам
+func rejectPage(c *gin.Context, retryAfter time.Duration) {
+	c.String(http.StatusTooManyRequests, printer(c).Translate(rateLimitedMessage(retryAfter)))
+	c.Abort()
+}
+
+// rejectAPI отвечает на отклоненный запрос к JSON API
+func rejectAPI(c *gin.Context, retryAfter time.Duration) {
+	respondAPIError(c, http.StatusTooManyRequests, errCodeRateLimited, rateLimitedMessage(retryAfter), "")
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/igor-fedko/go_multiply_app/config"
+	"github.com/igor-fedko/go_multiply_app/ratelimit"
+	"github.com/igor-fedko/go_multiply_app/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// withLimiter пересоздает роутер с ограничителем частоты запросов
+func (s *APITestSuite) withLimiter(def ratelimit.Limit, routes map[string]config.RouteLimit) {
+	limits, err := routeLimits(routes)
+	require.NoError(s.T(), err)
+
+	limiter = ratelimit.New(ratelimit.NewMemoryStore(), def, limits)
+	s.T().Cleanup(func() { limiter = nil })
+	s.app = setupRouter(appConfig)
+}
+
+// TestRateLimit тестирует ограничение частоты запросов к API и HTML страницам
+func (s *APITestSuite) TestRateLimit() {
+	s.withLimiter(ratelimit.Limit{Requests: 100, Per: time.Minute}, map[string]config.RouteLimit{
+		"multiply": {Requests: 1, Per: config.Duration{Duration: time.Minute}},
+	})
+
+	w := s.postJSON("/api/v1/multiply", `{"number1": 2, "number2": 3}`)
+	assert.Equal(s.T(), http.StatusCreated, w.Code)
+	assert.Equal(s.T(), "1", w.Header().Get("X-RateLimit-Limit"))
+
+	w = s.postJSON("/api/v1/multiply", `{"number1": 2, "number2": 3}`)
+	assert.Equal(s.T(), http.StatusTooManyRequests, w.Code)
+	assert.Equal(s.T(), "60", w.Header().Get("Retry-After"))
+	assert.Contains(s.T(), w.Body.String(), `"code":"rate_limited"`)
+
+	// Лимит операции действует и на HTML форму, остальные маршруты не затронуты
+	assert.Equal(s.T(), http.StatusSeeOther, s.postForm("/multiply", url.Values{"number1": {"2"}, "number2": {"3"}}).Code)
+	w = s.postForm("/multiply", url.Values{"number1": {"2"}, "number2": {"3"}})
+	assert.Equal(s.T(), http.StatusTooManyRequests, w.Code)
+	assert.Contains(s.T(), w.Body.String(), "Слишком много запросов")
+	assert.Equal(s.T(), http.StatusCreated, s.postJSON("/api/v1/add", `{"number1": 2, "number2": 3}`).Code)
+
+	// Отклоненные запросы не вычисляются
+	count, err := s.store.CountResults(context.Background(), storage.ResultQuery{Scope: storage.AllUsers(), Operation: "multiply"})
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), int64(2), count)
+}
+
+// TestRateLimitByIP тестирует ограничение анонимных запросов по IP-адресу
+func (s *APITestSuite) TestRateLimitByIP() {
+	s.withLimiter(ratelimit.Limit{Requests: 1, Per: time.Minute}, nil)
+
+	login := func(ip string) int {
+		req := httptest.NewRequest(http.MethodGet, "/login", nil)
+		req.RemoteAddr = ip + ":1234"
+		return s.anonymous(req).Code
+	}
+	assert.Equal(s.T(), http.StatusOK, login("192.0.2.1"))
+	assert.Equal(s.T(), http.StatusTooManyRequests, login("192.0.2.1"))
+	assert.Equal(s.T(), http.StatusOK, login("192.0.2.2"))
+
+	// Вошедший пользователь ограничивается по ID, а не по IP
+	assert.Equal(s.T(), http.StatusOK, s.get("/").Code)
+	assert.Equal(s.T(), http.StatusTooManyRequests, s.get("/").Code)
+
+	// Запросы API с неверным токеном ограничиваются по IP до проверки токена
+	badToken := func() int {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/results", nil)
+		req.RemoteAddr = "192.0.2.3:1234"
+		req.Header.Set("Authorization", "Bearer guess")
+		return s.anonymous(req).Code
+	}
+	assert.Equal(s.T(), http.StatusUnauthorized, badToken())
+	assert.Equal(s.T(), http.StatusTooManyRequests, badToken())
+}
+
+func TestRouteLimits(t *testing.T) {
+	limits, err := routeLimits(map[string]config.RouteLimit{
+		"evaluate":        {Requests: 5, Per: config.Duration{Duration: time.Second}},
+		"/api/v1/results": {},
+	})
+	require.NoError(t, err)
+
+	want := ratelimit.Limit{Requests: 5, Per: time.Second}
+	assert.Equal(t, want, limits["/evaluate"])
+	assert.Equal(t, want, limits["/api/v1/evaluate"])
+	assert.True(t, limits["/api/v1/results"].Unlimited())
+
+	_, err = routeLimits(map[string]config.RouteLimit{"modulo": {Requests: 1}})
+	assert.Error(t, err)
+}
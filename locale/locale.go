@@ -0,0 +1,197 @@
+// Package locale описывает правила записи чисел для языков интерфейса:
+// разбор чисел с десятичной запятой и разделителями разрядов
+// и вывод результатов в принятой для языка записи.
+package locale
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Locale - правила записи чисел для языка
+type Locale struct {
+	// Tag - код языка, например "ru"
+	Tag string
+	// Name - название языка для выбора в настройках
+	Name string
+	// Decimal - десятичный разделитель
+	Decimal string
+	// Group - разделитель разрядов при выводе
+	Group string
+}
+
+// Поддерживаемые локали
+var (
+	// Russian - десятичная запятая, разряды разделяются неразрывным пробелом
+	Russian = Locale{Tag: "ru", Name: "Русский", Decimal: ",", Group: "\u00a0"}
+	// English - десятичная точка, разряды разделяются запятой
+	English = Locale{Tag: "en", Name: "English", Decimal: ".", Group: ","}
+)
+
+// Default - локаль, если ни пользователь, ни язык интерфейса не указали поддерживаемую
+var Default = Russian
+
+// supported - поддерживаемые локали в порядке показа в настройках
+var supported = []Locale{Russian, English}
+
+// groupSeparators - разделители разрядов, допустимые при вводе в любой локали:
+// пробел, неразрывные пробелы и апострофы
+const groupSeparators = " \u00a0\u202f'\u2019"
+
+// Supported возвращает поддерживаемые локали
+func Supported() []Locale {
+	return append([]Locale(nil), supported...)
+}
+
+// Lookup находит локаль по коду языка. Учитывается только основной код:
+// "en-US" и "en_GB" означают English.
+func Lookup(tag string) (Locale, bool) {
+	primary, _, _ := strings.Cut(strings.TrimSpace(tag), "-")
+	primary, _, _ = strings.Cut(primary, "_")
+	for _, l := range supported {
+		if strings.EqualFold(primary, l.Tag) {
+			return l, true
+		}
+	}
+	return Locale{}, false
+}
+
+// Normalize переводит запись числа в локали в формат strconv.ParseFloat:
+// "1 000 000,5" -> "1000000.5". Кроме десятичного разделителя локали
+// всегда принимается точка, разряды целой части разделяются пробелами,
+// неразрывными пробелами, апострофами или разделителем локали по три цифры.
+// Запись, которую не удалось разобрать, например "NaN" или "1,5" в локали
+// English, возвращается без изменений - ее отклонит разбор числа.
+func (l Locale) Normalize(s string) string {
+	s = strings.TrimSpace(s)
+	mantissa, exponent := s, ""
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		mantissa, exponent = s[:i], s[i:]
+		if !validExponent(exponent) {
+			return s
+		}
+	}
+	sign := ""
+	if strings.HasPrefix(mantissa, "+") || strings.HasPrefix(mantissa, "-") {
+		sign, mantissa = mantissa[:1], mantissa[1:]
+	}
+
+	var intPart, fracPart strings.Builder
+	groups := []int{0}
+	hasPoint := false
+	for _, r := range mantissa {
+		switch {
+		case r >= '0' && r <= '9' && hasPoint:
+			fracPart.WriteRune(r)
+		case r >= '0' && r <= '9':
+			intPart.WriteRune(r)
+			groups[len(groups)-1]++
+		case l.isDecimal(r) && !hasPoint:
+			hasPoint = true
+		case l.isGroup(r) && !hasPoint:
+			groups = append(groups, 0)
+		default:
+			return s
+		}
+	}
+	if intPart.Len()+fracPart.Len() == 0 || !validGroups(groups) {
+		return s
+	}
+
+	normalized := sign + intPart.String()
+	if hasPoint {
+		normalized += "." + fracPart.String()
+	}
+	return normalized + exponent
+}
+
+// isDecimal сообщает, что r - десятичный разделитель при вводе
+func (l Locale) isDecimal(r rune) bool {
+	return r == '.' || string(r) == l.Decimal
+}
+
+// isGroup сообщает, что r - разделитель разрядов при вводе
+func (l Locale) isGroup(r rune) bool {
+	return strings.ContainsRune(groupSeparators, r) || string(r) == l.Group
+}
+
+// validGroups проверяет длины групп разрядов целой части: первая группа
+// из 1-3 цифр, остальные ровно по 3. Одна группа - число без разделителей.
+func validGroups(groups []int) bool {
+	if len(groups) == 1 {
+		return true
+	}
+	if groups[0] < 1 || groups[0] > 3 {
+		return false
+	}
+	for _, n := range groups[1:] {
+		if n != 3 {
+			return false
+		}
+	}
+	return true
+}
+
+// validExponent проверяет экспоненту вида e10, E-5, e+3
+func validExponent(s string) bool {
+	digits := s[1:]
+	if strings.HasPrefix(digits, "+") || strings.HasPrefix(digits, "-") {
+		digits = digits[1:]
+	}
+	return digits != "" && strings.Trim(digits, "0123456789") == ""
+}
+
+// Границы обычной записи числа: за их пределами число выводится
+// в экспоненциальной записи, как в fmt %v
+const (
+	plainMin = 1e-4
+	plainMax = 1e21
+)
+
+// FormatFloat записывает число в локали: 1234.5 -> "1 234,5" для Russian.
+// Очень большие и очень малые числа выводятся в экспоненциальной записи.
+func (l Locale) FormatFloat(v float64) string {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	format := byte('f')
+	if abs := math.Abs(v); abs != 0 && (abs < plainMin || abs >= plainMax) {
+		format = 'e'
+	}
+	return l.Localize(strconv.FormatFloat(v, format, -1, 64))
+}
+
+// Localize записывает в локали число, уже записанное с десятичной точкой,
+// например пакетом numfmt: точка заменяется разделителем локали, разряды
+// целой части разделяются, экспонента сохраняется ("1.50e+03" -> "1,50e+03").
+// Строка, которая не начинается с цифры, например "NaN", не меняется.
+func (l Locale) Localize(s string) string {
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	if s == "" || s[0] < '0' || s[0] > '9' {
+		return sign + s
+	}
+	mantissa, exponent := s, ""
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		mantissa, exponent = s[:i], s[i:]
+	}
+	intPart, fracPart, hasPoint := strings.Cut(mantissa, ".")
+
+	var b strings.Builder
+	b.WriteString(sign)
+	for i, r := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			b.WriteString(l.Group)
+		}
+		b.WriteRune(r)
+	}
+	if hasPoint {
+		b.WriteString(l.Decimal)
+		b.WriteString(fracPart)
+	}
+	b.WriteString(exponent)
+	return b.String()
+}
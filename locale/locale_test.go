@@ -0,0 +1,57 @@
+package locale
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		locale Locale
+		in     string
+		want   string
+	}{
+		{Russian, "1,5", "1.5"},
+		{Russian, "1.5", "1.5"},
+		{Russian, "-0,25", "-0.25"},
+		{Russian, "1 000 000", "1000000"},
+		{Russian, "1\u00a0000\u00a0000,5", "1000000.5"},
+		{Russian, "1\u202f000", "1000"},
+		{Russian, "1'000'000", "1000000"},
+		{Russian, " 2,5e3 ", "2.5e3"},
+		{English, "1,000,000.5", "1000000.5"},
+		{English, "1 000", "1000"},
+		{English, "1,5e-3", "1,5e-3"},
+		{English, "1,5", "1,5"},
+		{Russian, "1 00", "1 00"},
+		{Russian, "1234 567", "1234 567"},
+		{Russian, "1,5,5", "1,5,5"},
+		{Russian, "0,000 1", "0,000 1"},
+		{Russian, "NaN", "NaN"},
+		{Russian, "-Inf", "-Inf"},
+		{Russian, "0x1p-2", "0x1p-2"},
+		{Russian, "1e", "1e"},
+		{Russian, ",", ","},
+	}
+	for _, tt := range tests {
+		t.Run(tt.locale.Tag+" "+tt.in, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.locale.Normalize(tt.in))
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	assert.Equal(t, "1\u00a0234\u00a0567,25", Russian.FormatFloat(1234567.25))
+	assert.Equal(t, "1,234,567.25", English.FormatFloat(1234567.25))
+	assert.Equal(t, "-123", Russian.FormatFloat(-123))
+	assert.Equal(t, "0", Russian.FormatFloat(0))
+	assert.Equal(t, "1e-09", English.FormatFloat(1e-9))
+	assert.Equal(t, "1,5e+30", Russian.FormatFloat(1.5e30))
+
+	assert.Equal(t, "-1\u00a0000,125", Russian.Localize("-1000.125"))
+	assert.Equal(t, "12\u00a0345,00", Russian.Localize("12345.00"))
+	assert.Equal(t, "1,50e+03", Russian.Localize("1.50e+03"))
+	assert.Equal(t, "0.3", English.Localize("0.3"))
+	assert.Equal(t, "NaN", English.Localize("NaN"))
+}
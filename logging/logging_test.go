@@ -0,0 +1,75 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRejectsUnknownSettings(t *testing.T) {
+	_, err := New(&bytes.Buffer{}, "verbose", FormatJSON)
+	assert.Error(t, err)
+
+	_, err = New(&bytes.Buffer{}, "info", "xml")
+	assert.Error(t, err)
+}
+
+func TestMiddlewareRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var out bytes.Buffer
+	logger, err := New(&out, "info", FormatJSON)
+	require.NoError(t, err)
+
+	var seen string
+	router := gin.New()
+	router.Use(Middleware(logger))
+	router.GET("/", func(c *gin.Context) {
+		seen = RequestID(c.Request.Context())
+		logger.InfoContext(c.Request.Context(), "handled")
+	})
+
+	tests := map[string]struct {
+		header   string
+		expected string
+	}{
+		"propagated": {header: "abc-123", expected: "abc-123"},
+		"generated":  {header: "", expected: ""},
+		"invalid":    {header: "bad id\n", expected: ""},
+		"too long":   {header: strings.Repeat("x", maxRequestIDLength+1), expected: ""},
+	}
+
+	for name, tt := range tests {
+		out.Reset()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tt.header != "" {
+			req.Header.Set(RequestIDHeader, tt.header)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		id := w.Header().Get(RequestIDHeader)
+		if tt.expected != "" {
+			assert.Equal(t, tt.expected, id, name)
+		} else {
+			assert.Len(t, id, 32, name)
+		}
+		assert.Equal(t, id, seen, name)
+
+		// Обе строки журнала - запись обработчика и строка о запросе - содержат request_id
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		require.Len(t, lines, 2, name)
+		for _, line := range lines {
+			var record map[string]any
+			require.NoError(t, json.Unmarshal([]byte(line), &record), name)
+			assert.Equal(t, id, record["request_id"], name)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/igor-fedko/go_multiply_app/models"
+	"github.com/igor-fedko/go_multiply_app/storage"
+)
+
+// logQueueSize - число записей журнала, ожидающих сохранения
+const logQueueSize = 256
+
+// logWriter сохраняет записи журнала операций в фоне, чтобы запись журнала
+// не задерживала ответ. Записи не теряются: при переполненной очереди
+// запись сохраняется синхронно, а Close дожидается сохранения всей очереди.
+// Записи, пришедшие после Close от еще не завершенных обработчиков,
+// тоже сохраняются синхронно.
+type logWriter struct {
+	store   storage.LogStore
+	timeout time.Duration
+	entries chan models.LogEntry
+	pending sync.WaitGroup
+	done    chan struct{}
+
+	// mu защищает closed и отправку в entries от закрытия канала в Close
+	mu     sync.RWMutex
+	closed bool
+}
+
+// newLogWriter создает журнал и запускает фоновую запись
+func newLogWriter(store storage.LogStore, timeout time.Duration) *logWriter {
+	w := &logWriter{
+		store:   store,
+		timeout: timeout,
+		entries: make(chan models.LogEntry, logQueueSize),
+		done:    make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// Write ставит запись в очередь на сохранение. При переполненной очереди
+// и после Close запись сохраняется синхронно.
+func (w *logWriter) Write(entry models.LogEntry) {
+	w.pending.Add(1)
+
+	w.mu.RLock()
+	queued := false
+	if !w.closed {
+		select {
+		case w.entries <- entry:
+			queued = true
+		default:
+		}
+	}
+	w.mu.RUnlock()
+
+	if !queued {
+		w.insert(entry)
+	}
+}
+
+// Flush ждет сохранения всех записей, поставленных в очередь
+func (w *logWriter) Flush(ctx context.Context) error {
+	return waitContext(ctx, w.pending.Wait)
+}
+
+// Close сохраняет оставшиеся записи и останавливает фоновую запись.
+// Если ctx истекает раньше, оставшиеся записи сохраняются в фоне.
+func (w *logWriter) Close(ctx context.Context) error {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.entries)
+	}
+	w.mu.Unlock()
+
+	return waitContext(ctx, func() { <-w.done })
+}
+
+func (w *logWriter) run() {
+	defer close(w.done)
+	for entry := range w.entries {
+		w.insert(entry)
+	}
+}
+
+// insert сохраняет запись в хранилище
+func (w *logWriter) insert(entry models.LogEntry) {
+	defer w.pending.Done()
+
+	ctx, cancel := context.WithTimeout(context.Background(), w.timeout)
+	defer cancel()
+
+	if err := w.store.InsertLog(ctx, &entry); err != nil {
+		slog.Error("write operation log entry",
+			"error", err,
+			"operation", entry.Operation,
+			"request_id", entry.RequestID,
+		)
+	}
+}
+
+// waitContext ждет завершения wait или отмены ctx
+func waitContext(ctx context.Context, wait func()) error {
+	finished := make(chan struct{})
+	go func() {
+		wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/igor-fedko/go_multiply_app/models"
+	"github.com/igor-fedko/go_multiply_app/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestLogWriterCloseFlushes тестирует, что Close сохраняет все записи,
+// включая не поместившиеся в очередь
+func TestLogWriterCloseFlushes(t *testing.T) {
+	s := memory.New()
+	w := newLogWriter(s, time.Second)
+
+	total := logQueueSize * 2
+	for i := 0; i < total; i++ {
+		w.Write(models.LogEntry{Operation: "add", Timestamp: time.Now().UTC()})
+	}
+
+	require.NoError(t, w.Close(context.Background()))
+	assert.Len(t, s.Logs(), total)
+}
+
+// TestLogWriterWriteAfterClose тестирует, что записи обработчиков,
+// не завершившихся до Close, сохраняются синхронно, а не вызывают панику
+func TestLogWriterWriteAfterClose(t *testing.T) {
+	s := memory.New()
+	w := newLogWriter(s, time.Second)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				w.Write(models.LogEntry{Operation: "add", Timestamp: time.Now().UTC()})
+			}
+		}()
+	}
+
+	require.NoError(t, w.Close(context.Background()))
+	wg.Wait()
+	w.Write(models.LogEntry{Operation: "add", Timestamp: time.Now().UTC()})
+
+	require.NoError(t, w.Flush(context.Background()))
+	assert.Len(t, s.Logs(), 801)
+	require.NoError(t, w.Close(context.Background()))
+}
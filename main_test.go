@@ -2,30 +2,38 @@ package main
 
 import (
 	"context"
+	"encoding/csv"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
-	"strconv"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/igor-fedko/go_multiply_app/config"
+	"github.com/igor-fedko/go_multiply_app/metrics"
+	"github.com/igor-fedko/go_multiply_app/models"
+	"github.com/igor-fedko/go_multiply_app/storage"
+	"github.com/igor-fedko/go_multiply_app/storage/memory"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
-	"github.com/testcontainers/testcontainers-go/modules/mongodb"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // APITestSuite представляет собой набор тестов для API
 type APITestSuite struct {
 	suite.Suite
-	mongoContainer *mongodb.MongoDBContainer
-	mongoClient    *mongo.Client
-	app            *gin.Engine
+	store *memory.Store
+	app   *gin.Engine
+
+	// user - пользователь, от имени которого выполняются запросы;
+	// session и apiToken - его токены для HTML страниц и JSON API
+	user     models.User
+	session  string
+	apiToken string
 }
 
 // SetupSuite запускается перед выполнением всех тестов
@@ -33,509 +41,590 @@ func (s *APITestSuite) SetupSuite() {
 	// Отключаем вывод Gin в консоль для тестов
 	gin.SetMode(gin.TestMode)
 
-	// Запускаем MongoDB контейнер
-	ctx := context.Background()
-	mongodbContainer, err := mongodb.Run(ctx, "mongo:latest")
-	if err != nil {
-		s.T().Fatalf("Не удалось запустить контейнер MongoDB: %s", err)
-	}
-	s.mongoContainer = mongodbContainer
+	appConfig = config.Default()
+	appConfig.Server.GinMode = gin.TestMode
+	appConfig.Storage.Backend = config.BackendMemory
+}
 
-	// Получаем строку подключения к MongoDB
-	connectionString, err := mongodbContainer.ConnectionString(ctx)
-	if err != nil {
-		s.T().Fatalf("Не удалось получить строку подключения: %s", err)
-	}
+// SetupTest создает чистое хранилище в памяти и роутер перед каждым тестом
+func (s *APITestSuite) SetupTest() {
+	s.store = memory.New()
+	appMetrics = metrics.New(s.store, operationNames, appConfig.Timeouts.Read.Duration)
+	store = appMetrics.InstrumentStore(s.store)
+	opLog = newLogWriter(s.store, appConfig.Timeouts.Write.Duration)
+	s.app = setupRouter(appConfig)
+
+	s.user = models.User{Username: "tester", PasswordHash: "-", CreatedAt: time.Now().UTC()}
+	require.NoError(s.T(), s.store.CreateUser(context.Background(), &s.user))
+
+	var err error
+	s.session, _, err = issueToken(context.Background(), s.user.ID, models.TokenSession, "", time.Hour)
+	require.NoError(s.T(), err)
+	s.apiToken, _, err = issueToken(context.Background(), s.user.ID, models.TokenAPI, "tests", 0)
+	require.NoError(s.T(), err)
+}
 
-	// Подключаемся к MongoDB
-	mongoClient, err := mongo.Connect(ctx, options.Client().ApplyURI(connectionString))
-	if err != nil {
-		s.T().Fatalf("Не удалось подключиться к MongoDB: %s", err)
-	}
-	s.mongoClient = mongoClient
+// do выполняет запрос от имени пользователя: с cookie сессии
+// и токеном API в заголовке Authorization
+func (s *APITestSuite) do(req *http.Request) *httptest.ResponseRecorder {
+	req.AddCookie(&http.Cookie{Name: sessionCookie, Value: s.session})
+	req.Header.Set("Authorization", "Bearer "+s.apiToken)
 
-	// Создаем экземпляр приложения
-	s.app = setupTestRouter(mongoClient)
+	w := httptest.NewRecorder()
+	s.app.ServeHTTP(w, req)
+	return w
 }
 
-// TearDownSuite запускается после выполнения всех тестов
-func (s *APITestSuite) TearDownSuite() {
-	ctx := context.Background()
+// TearDownTest сохраняет оставшиеся записи журнала
+func (s *APITestSuite) TearDownTest() {
+	require.NoError(s.T(), opLog.Close(context.Background()))
+}
 
-	// Отключаемся от MongoDB
-	if s.mongoClient != nil {
-		s.mongoClient.Disconnect(ctx)
-	}
+// logs возвращает журнал операций после сохранения всех записей из очереди
+func (s *APITestSuite) logs() []models.LogEntry {
+	require.NoError(s.T(), opLog.Flush(context.Background()))
+	return s.store.Logs()
+}
 
-	// Останавливаем контейнер
-	if s.mongoContainer != nil {
-		if err := s.mongoContainer.Terminate(ctx); err != nil {
-			s.T().Fatalf("Не удалось остановить контейнер: %s", err)
-		}
-	}
+// postForm отправляет форму на указанный адрес
+func (s *APITestSuite) postForm(path string, form url.Values) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return s.do(req)
 }
 
-// setupTestRouter создает тестовый экземпляр приложения
-func setupTestRouter(client *mongo.Client) *gin.Engine {
-	router := gin.Default()
+// postJSON отправляет JSON на указанный адрес
+func (s *APITestSuite) postJSON(path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	return s.do(req)
+}
 
-	// Настраиваем коллекцию для тестов
-	collection := client.Database("multiply_app").Collection("results")
+// findResult возвращает единственный сохраненный результат операции
+func (s *APITestSuite) findResult(operation string) models.Result {
+	results, err := s.store.ListResults(context.Background(), storage.ResultQuery{Scope: storage.AllUsers(), Operation: operation})
+	require.NoError(s.T(), err)
+	require.Len(s.T(), results, 1)
+	return results[0]
+}
 
-	// Загружаем HTML шаблоны
-	router.LoadHTMLGlob("templates/*")
+// TestIndexPage тестирует главную страницу
+func (s *APITestSuite) TestIndexPage() {
+	w := s.get("/")
 
-	// Определяем маршруты
-	router.GET("/", func(c *gin.Context) {
-		// Получаем все результаты из базы данных
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cancel()
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	assert.Contains(s.T(), w.Body.String(), "Математические операции")
+	assert.Contains(s.T(), w.Body.String(), "tester")
+}
 
-		cursor, err := collection.Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"created_at": -1}))
-		if err != nil {
-			c.HTML(http.StatusInternalServerError, "index.html", gin.H{
-				"Error": "Ошибка при получении результатов: " + err.Error(),
-			})
-			return
-		}
-		defer cursor.Close(ctx)
-
-		var results []map[string]interface{}
-		if err = cursor.All(ctx, &results); err != nil {
-			c.HTML(http.StatusInternalServerError, "index.html", gin.H{
-				"Error": "Ошибка при обработке результатов: " + err.Error(),
-			})
-			return
-		}
+// TestMultiply тестирует эндпоинт умножения
+func (s *APITestSuite) TestMultiply() {
+	w := s.postForm("/multiply", url.Values{"number1": {"10"}, "number2": {"5"}})
 
-		c.HTML(http.StatusOK, "index.html", gin.H{
-			"Results": results,
-		})
-	})
-
-	router.POST("/multiply", func(c *gin.Context) {
-		// Получаем данные из формы
-		number1Str := c.PostForm("number1")
-		number2Str := c.PostForm("number2")
-
-		// Преобразуем строки в числа
-		number1, err := strconv.ParseFloat(number1Str, 64)
-		if err != nil {
-			// Для тестов возвращаем JSON вместо HTML
-			if gin.Mode() == gin.TestMode {
-				c.JSON(http.StatusBadRequest, gin.H{
-					"error": "Неверный формат первого числа",
-				})
-				return
-			}
-			c.HTML(http.StatusBadRequest, "index.html", gin.H{
-				"Error": "Неверный формат первого числа",
-			})
-			return
-		}
+	// Проверяем статус ответа (должен быть редирект)
+	assert.Equal(s.T(), http.StatusSeeOther, w.Code)
 
-		number2, err := strconv.ParseFloat(number2Str, 64)
-		if err != nil {
-			// Для тестов возвращаем JSON вместо HTML
-			if gin.Mode() == gin.TestMode {
-				c.JSON(http.StatusBadRequest, gin.H{
-					"error": "Неверный формат второго числа",
-				})
-				return
-			}
-			c.HTML(http.StatusBadRequest, "index.html", gin.H{
-				"Error": "Неверный формат второго числа",
-			})
-			return
-		}
+	// Проверяем, что запись добавлена в хранилище
+	result := s.findResult("multiply")
+	assert.Equal(s.T(), models.ResultSchemaVersion, result.SchemaVersion)
+	assert.Equal(s.T(), []models.Operand{{Value: 10}, {Value: 5}}, result.Operands)
+	assert.Equal(s.T(), float64(50), result.Result)
 
-		// Вычисляем произведение
-		product := number1 * number2
+	// Проверяем, что операция записана в журнал
+	logs := s.logs()
+	require.Len(s.T(), logs, 1)
+	assert.Equal(s.T(), "multiply", logs[0].Operation)
+}
 
-		// Создаем новый результат
-		result := bson.M{
-			"number1":    number1,
-			"number2":    number2,
-			"result":     product,
-			"operation":  "multiply",
-			"created_at": time.Now(),
-		}
+// TestDivide тестирует эндпоинт деления
+func (s *APITestSuite) TestDivide() {
+	w := s.postForm("/divide", url.Values{"number1": {"10"}, "number2": {"2"}})
 
-		// Сохраняем результат в MongoDB
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-
-		_, err = collection.InsertOne(ctx, result)
-		if err != nil {
-			// Для тестов возвращаем JSON вместо HTML
-			if gin.Mode() == gin.TestMode {
-				c.JSON(http.StatusInternalServerError, gin.H{
-					"error": "Ошибка при сохранении результата: " + err.Error(),
-				})
-				return
-			}
-			c.HTML(http.StatusInternalServerError, "index.html", gin.H{
-				"Error": "Ошибка при сохранении результата: " + err.Error(),
-			})
-			return
-		}
+	assert.Equal(s.T(), http.StatusSeeOther, w.Code)
+	assert.Equal(s.T(), float64(5), s.findResult("divide").Result)
+}
 
-		// Перенаправляем на главную страницу
-		c.Redirect(http.StatusSeeOther, "/")
-	})
-
-	router.POST("/subtract", func(c *gin.Context) {
-		// Получаем данные из формы
-		number1Str := c.PostForm("number1")
-		number2Str := c.PostForm("number2")
-
-		// Преобразуем строки в числа
-		number1, err := strconv.ParseFloat(number1Str, 64)
-		if err != nil {
-			// Для тестов возвращаем JSON вместо HTML
-			if gin.Mode() == gin.TestMode {
-				c.JSON(http.StatusBadRequest, gin.H{
-					"error": "Неверный формат первого числа",
-				})
-				return
-			}
-			c.HTML(http.StatusBadRequest, "index.html", gin.H{
-				"Error": "Неверный формат первого числа",
-			})
-			return
-		}
+// TestDivideByZero тестирует обработку ошибки при делении на ноль
+func (s *APITestSuite) TestDivideByZero() {
+	w := s.postForm("/divide", url.Values{"number1": {"10"}, "number2": {"0"}})
 
-		number2, err := strconv.ParseFloat(number2Str, 64)
-		if err != nil {
-			// Для тестов возвращаем JSON вместо HTML
-			if gin.Mode() == gin.TestMode {
-				c.JSON(http.StatusBadRequest, gin.H{
-					"error": "Неверный формат второго числа",
-				})
-				return
-			}
-			c.HTML(http.StatusBadRequest, "index.html", gin.H{
-				"Error": "Неверный формат второго числа",
-			})
-			return
-		}
+	assert.Equal(s.T(), http.StatusBadRequest, w.Code)
+	assert.Contains(s.T(), w.Body.String(), "Деление на ноль невозможно")
+}
 
-		// Вычисляем разность
-		difference := number1 - number2
+// TestInvalidInput1 тестирует обработку неверного формата ввода первого числа
+func (s *APITestSuite) TestInvalidInput1() {
+	w := s.postForm("/multiply", url.Values{"number1": {"abc"}, "number2": {"5"}})
 
-		// Создаем новый результат
-		result := bson.M{
-			"number1":    number1,
-			"number2":    number2,
-			"result":     difference,
-			"operation":  "subtract",
-			"created_at": time.Now(),
-		}
+	assert.Equal(s.T(), http.StatusBadRequest, w.Code)
+	assert.Contains(s.T(), w.Body.String(), "Неверный формат первого числа")
+}
 
-		// Сохраняем результат в MongoDB
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-
-		_, err = collection.InsertOne(ctx, result)
-		if err != nil {
-			// Для тестов возвращаем JSON вместо HTML
-			if gin.Mode() == gin.TestMode {
-				c.JSON(http.StatusInternalServerError, gin.H{
-					"error": "Ошибка при сохранении результата: " + err.Error(),
-				})
-				return
-			}
-			c.HTML(http.StatusInternalServerError, "index.html", gin.H{
-				"Error": "Ошибка при сохранении результата: " + err.Error(),
-			})
-			return
-		}
+// TestInvalidInput2 тестирует обработку неверного формата ввода второго числа
+func (s *APITestSuite) TestInvalidInput2() {
+	w := s.postForm("/multiply", url.Values{"number1": {"12"}, "number2": {"выа"}})
 
-		// Перенаправляем на главную страницу
-		c.Redirect(http.StatusSeeOther, "/")
-	})
-
-	router.POST("/divide", func(c *gin.Context) {
-		// Получаем данные из формы
-		number1Str := c.PostForm("number1")
-		number2Str := c.PostForm("number2")
-
-		// Преобразуем строки в числа
-		number1, err := strconv.ParseFloat(number1Str, 64)
-		if err != nil {
-			// Для тестов возвращаем JSON вместо HTML
-			if gin.Mode() == gin.TestMode {
-				c.JSON(http.StatusBadRequest, gin.H{
-					"error": "Неверный формат первого числа",
-				})
-				return
-			}
-			c.HTML(http.StatusBadRequest, "index.html", gin.H{
-				"Error": "Неверный формат первого числа",
-			})
-			return
-		}
+	assert.Equal(s.T(), http.StatusBadRequest, w.Code)
+	assert.Contains(s.T(), w.Body.String(), "Неверный формат второго числа")
+}
 
-		number2, err := strconv.ParseFloat(number2Str, 64)
-		if err != nil {
-			// Для тестов возвращаем JSON вместо HTML
-			if gin.Mode() == gin.TestMode {
-				c.JSON(http.StatusBadRequest, gin.H{
-					"error": "Неверный формат второго числа",
-				})
-				return
-			}
-			c.HTML(http.StatusBadRequest, "index.html", gin.H{
-				"Error": "Неверный формат второго числа",
-			})
-			return
-		}
+// TestDiff - тестирует эндпоинт вычитания
+func (s *APITestSuite) TestDiff() {
+	w := s.postForm("/subtract", url.Values{"number1": {"12"}, "number2": {"4"}})
 
-		// Проверяем деление на ноль
-		if number2 == 0 {
-			// Для тестов возвращаем JSON вместо HTML
-			if gin.Mode() == gin.TestMode {
-				c.JSON(http.StatusBadRequest, gin.H{
-					"error": "Деление на ноль невозможно",
-				})
-				return
-			}
-			c.HTML(http.StatusBadRequest, "index.html", gin.H{
-				"Error": "Деление на ноль невозможно",
-			})
-			return
-		}
+	assert.Equal(s.T(), http.StatusSeeOther, w.Code)
+	assert.Equal(s.T(), float64(8), s.findResult("subtract").Result)
+}
 
-		// Вычисляем частное
-		quotient := number1 / number2
+// TestSquare тестирует унарную операцию возведения в квадрат
+func (s *APITestSuite) TestSquare() {
+	w := s.postForm("/square", url.Values{"number1": {"-3"}})
 
-		// Создаем новый результат
-		result := bson.M{
-			"number1":    number1,
-			"number2":    number2,
-			"result":     quotient,
-			"operation":  "divide",
-			"created_at": time.Now(),
-		}
+	assert.Equal(s.T(), http.StatusSeeOther, w.Code)
+	result := s.findResult("square")
+	assert.Equal(s.T(), float64(9), result.Result)
+	// У унарной операции один операнд, без заполнителя второго
+	assert.Equal(s.T(), []models.Operand{{Value: -3}}, result.Operands)
+}
 
-		// Сохраняем результат в MongoDB
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-
-		_, err = collection.InsertOne(ctx, result)
-		if err != nil {
-			// Для тестов возвращаем JSON вместо HTML
-			if gin.Mode() == gin.TestMode {
-				c.JSON(http.StatusInternalServerError, gin.H{
-					"error": "Ошибка при сохранении результата: " + err.Error(),
-				})
-				return
-			}
-			c.HTML(http.StatusInternalServerError, "index.html", gin.H{
-				"Error": "Ошибка при сохранении результата: " + err.Error(),
-			})
-			return
-		}
+// TestAPIMultiply тестирует JSON API умножения
+func (s *APITestSuite) TestAPIMultiply() {
+	w := s.postJSON("/api/v1/multiply", `{"number1": 10, "number2": 5}`)
 
-		// Перенаправляем на главную страницу
-		c.Redirect(http.StatusSeeOther, "/")
-	})
+	assert.Equal(s.T(), http.StatusCreated, w.Code)
 
-	return router
-}
+	var result models.Result
+	require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &result))
+	assert.False(s.T(), result.ID.IsZero())
+	assert.Equal(s.T(), float64(50), result.Result)
 
-// TestIndexPage тестирует главную страницу
-func (s *APITestSuite) TestIndexPage() {
-	// Создаем тестовый HTTP-запрос
-	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	// Результат доступен по идентификатору
+	w = s.get("/api/v1/results/" + result.ID.Hex())
+	assert.Equal(s.T(), http.StatusOK, w.Code)
 
-	// Создаем ResponseRecorder для записи ответа
-	w := httptest.NewRecorder()
+	// Ответ v1 сохраняет поля number1 и number2 рядом с массивом operands
+	var doc map[string]any
+	require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &doc))
+	assert.Equal(s.T(), float64(10), doc["number1"])
+	assert.Equal(s.T(), float64(5), doc["number2"])
+	assert.Len(s.T(), doc["operands"], 2)
+}
 
-	// Выполняем запрос
-	s.app.ServeHTTP(w, req)
+// TestAPIErrors тестирует коды ошибок JSON API
+func (s *APITestSuite) TestAPIErrors() {
+	tests := []struct {
+		path, body string
+		status     int
+		code       string
+	}{
+		{"/api/v1/divide", `{"number1": 1, "number2": 0}`, http.StatusUnprocessableEntity, "division_by_zero"},
+		{"/api/v1/add", `{"number1": 1}`, http.StatusBadRequest, "missing_operand"},
+		{"/api/v1/add", `{"number1": "x", "number2": 1}`, http.StatusBadRequest, "invalid_number"},
+		{"/api/v1/add", `{`, http.StatusBadRequest, "invalid_json"},
+		{"/api/v1/evaluate", `{"expression": "1 +"}`, http.StatusBadRequest, "syntax_error"},
+	}
 
-	// Проверяем статус ответа
-	assert.Equal(s.T(), http.StatusOK, w.Code)
+	for _, tt := range tests {
+		w := s.postJSON(tt.path, tt.body)
+		assert.Equal(s.T(), tt.status, w.Code, tt.body)
 
-	// Проверяем, что в ответе содержится HTML с заголовком
-	assert.Contains(s.T(), w.Body.String(), "Математические операции")
+		var response apiErrorResponse
+		require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(s.T(), tt.code, response.Error.Code, tt.body)
+	}
 }
 
-// TestMultiply тестирует эндпоинт умножения
-func (s *APITestSuite) TestMultiply() {
-	// Создаем данные формы
-	form := url.Values{}
-	form.Add("number1", "10")
-	form.Add("number2", "5")
+// TestAPIResultLookup тестирует получение результата по идентификатору
+func (s *APITestSuite) TestAPIResultLookup() {
+	w := s.postJSON("/api/v1/square", `{"number1": 3}`)
+	require.Equal(s.T(), http.StatusCreated, w.Code)
+
+	var result models.Result
+	require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(s.T(), float64(9), result.Result)
+
+	tests := []struct {
+		id     string
+		status int
+		code   string
+	}{
+		{result.ID.Hex(), http.StatusOK, ""},
+		{"abc", http.StatusBadRequest, "invalid_id"},
+		{primitive.NewObjectID().Hex(), http.StatusNotFound, "not_found"},
+	}
 
-	// Создаем тестовый HTTP-запрос
-	req := httptest.NewRequest(http.MethodPost, "/multiply", strings.NewReader(form.Encode()))
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	for _, tt := range tests {
+		w := s.get("/api/v1/results/" + tt.id)
+		assert.Equal(s.T(), tt.status, w.Code, tt.id)
+		if tt.code == "" {
+			continue
+		}
 
-	// Создаем ResponseRecorder для записи ответа
-	w := httptest.NewRecorder()
+		var response apiErrorResponse
+		require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(s.T(), tt.code, response.Error.Code, tt.id)
+	}
+}
 
-	// Выполняем запрос
-	s.app.ServeHTTP(w, req)
+// TestNumericRange тестирует отклонение NaN, бесконечности и чисел
+// вне диапазона float64 в операндах и результатах
+func (s *APITestSuite) TestNumericRange() {
+	tests := []struct {
+		path, body string
+		code       string
+		field      string
+	}{
+		{"/api/v1/multiply", `{"number1": 1e400, "number2": 2}`, "overflow", "number1"},
+		{"/api/v1/multiply", `{"number1": 1e200, "number2": 1e200}`, "overflow", ""},
+		{"/api/v1/multiply", `{"number1": 1e-200, "number2": 1e-200}`, "underflow", ""},
+		{"/api/v1/add", `{"number1": "1", "number2": "1e-400", "precision": "exact"}`, "underflow", "number2"},
+		{"/api/v1/evaluate", `{"expression": "1e400 * 2"}`, "overflow", "expression"},
+		{"/api/v1/evaluate", `{"expression": "1e-200 * 1e-200"}`, "underflow", "expression"},
+	}
+	for _, tt := range tests {
+		w := s.postJSON(tt.path, tt.body)
+		assert.Equal(s.T(), http.StatusUnprocessableEntity, w.Code, tt.body)
+
+		var response apiErrorResponse
+		require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(s.T(), tt.code, response.Error.Code, tt.body)
+		assert.Equal(s.T(), tt.field, response.Error.Field, tt.body)
+	}
 
-	// Проверяем статус ответа (должен быть редирект)
-	assert.Equal(s.T(), http.StatusSeeOther, w.Code)
+	w := s.postForm("/add", url.Values{"number1": {"NaN"}, "number2": {"1"}})
+	assert.Equal(s.T(), http.StatusBadRequest, w.Code)
+	assert.Contains(s.T(), w.Body.String(), "NaN и бесконечность не допускаются")
+	require.Equal(s.T(), http.StatusSeeOther, s.postForm("/add", url.Values{"number1": {"1"}, "number2": {"2"}}).Code)
 
-	// Проверяем, что запись добавлена в базу данных
-	ctx := context.Background()
-	var result bson.M
+	// Класс числа записывается в журнал
+	var classes []string
+	for _, entry := range s.logs() {
+		classes = append(classes, entry.NumericClass)
+	}
+	assert.Equal(s.T(), []string{"overflow", "overflow", "underflow", "underflow", "overflow", "underflow", "non_finite_input", "finite"}, classes)
+}
 
-	err := s.mongoClient.Database("multiply_app").Collection("results").FindOne(
-		ctx,
-		bson.M{"number1": 10, "number2": 5, "operation": "multiply"},
-	).Decode(&result)
+// TestAPIExactPrecision тестирует точный режим вычислений
+func (s *APITestSuite) TestAPIExactPrecision() {
+	w := s.postJSON("/api/v1/add", `{"number1": 0.1, "number2": "0.2", "precision": "exact"}`)
+	assert.Equal(s.T(), http.StatusCreated, w.Code)
 
-	assert.NoError(s.T(), err)
-	assert.Equal(s.T(), float64(50), result["result"])
+	result := s.findResult("add")
+	assert.Equal(s.T(), models.PrecisionExact, result.Precision)
+	assert.Equal(s.T(), "0.3", result.ResultExact)
 }
 
-// TestDivide тестирует эндпоинт деления
-func (s *APITestSuite) TestDivide() {
-	// Создаем данные формы
-	form := url.Values{}
-	form.Add("number1", "10")
-	form.Add("number2", "2")
+// TestLocaleNumbers тестирует ввод и вывод чисел в локали пользователя
+func (s *APITestSuite) TestLocaleNumbers() {
+	w := s.postForm("/multiply", url.Values{"number1": {"1,5"}, "number2": {"1\u00a0000"}})
+	require.Equal(s.T(), http.StatusSeeOther, w.Code)
+	assert.Equal(s.T(), float64(1500), s.findResult("multiply").Result)
 
-	// Создаем тестовый HTTP-запрос
-	req := httptest.NewRequest(http.MethodPost, "/divide", strings.NewReader(form.Encode()))
+	// В английской локали запятая разделяет разряды, "1,5" - неверная запись
+	req := httptest.NewRequest(http.MethodPost, "/add", strings.NewReader(url.Values{"number1": {"1,5"}, "number2": {"1"}}.Encode()))
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
+	w = s.do(req)
+	assert.Equal(s.T(), http.StatusBadRequest, w.Code)
+	assert.Contains(s.T(), w.Body.String(), "Invalid format of the first number")
+
+	// Без настройки пользователя локаль выбирается по языку интерфейса
+	assert.Contains(s.T(), s.get("/").Body.String(), "<td>1\u00a0500</td>")
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
+	assert.Contains(s.T(), s.do(req).Body.String(), "<td>1,500</td>")
+
+	// Настройка пользователя важнее заголовка
+	require.Equal(s.T(), http.StatusSeeOther, s.postForm("/settings/locale", url.Values{"locale": {"en"}}).Code)
+	assert.Contains(s.T(), s.get("/").Body.String(), "<td>1,500</td>")
+	assert.Equal(s.T(), http.StatusBadRequest, s.postForm("/settings/locale", url.Values{"locale": {"xx"}}).Code)
+}
 
-	// Создаем ResponseRecorder для записи ответа
-	w := httptest.NewRecorder()
+// TestLanguage тестирует выбор языка интерфейса и перевод сообщений
+func (s *APITestSuite) TestLanguage() {
+	// Сообщение ошибки API переводится, код остается прежним
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/divide", strings.NewReader(`{"number1": 1, "number2": 0}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept-Language", "de-DE, en;q=0.8, ru;q=0.5")
+	w := s.do(req)
+	require.Equal(s.T(), http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(s.T(), "en", w.Header().Get("Content-Language"))
+	var response apiErrorResponse
+	require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(s.T(), "division_by_zero", response.Error.Code)
+	assert.Equal(s.T(), "Division by zero is not possible", response.Error.Message)
+
+	// В журнал сообщение записывается на русском языке
+	entries := s.logs()
+	require.NotEmpty(s.T(), entries)
+	assert.Equal(s.T(), "Деление на ноль невозможно", entries[len(entries)-1].Error)
+
+	// Параметр lang важнее заголовка и запоминается в cookie
+	req = httptest.NewRequest(http.MethodGet, "/?lang=en", nil)
+	req.Header.Set("Accept-Language", "ru")
+	w = s.do(req)
+	require.Equal(s.T(), http.StatusOK, w.Code)
+	assert.Contains(s.T(), w.Body.String(), `<html lang="en">`)
+	assert.Contains(s.T(), w.Body.String(), "<h1>Math operations</h1>")
+	var cookie *http.Cookie
+	for _, c := range w.Result().Cookies() {
+		if c.Name == langParam {
+			cookie = c
+		}
+	}
+	require.NotNil(s.T(), cookie)
+	assert.Equal(s.T(), "en", cookie.Value)
 
-	// Выполняем запрос
-	s.app.ServeHTTP(w, req)
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(cookie)
+	body := s.do(req).Body.String()
+	assert.Contains(s.T(), body, "Result history")
+	assert.NotContains(s.T(), body, "История результатов")
 
-	// Проверяем статус ответа (должен быть редирект)
-	assert.Equal(s.T(), http.StatusSeeOther, w.Code)
+	// Ошибка выражения переводится вместе с вложенным сообщением
+	req = httptest.NewRequest(http.MethodPost, "/evaluate", strings.NewReader(url.Values{"expression": {"2 + x"}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(cookie)
+	w = s.do(req)
+	assert.Equal(s.T(), http.StatusBadRequest, w.Code)
+	assert.Contains(s.T(), w.Body.String(), "Error in expression: Unknown identifier &#34;x&#34; (position 5)")
 
-	// Проверяем, что запись добавлена в базу данных
-	ctx := context.Background()
-	var result bson.M
+	// По умолчанию интерфейс на русском языке
+	assert.Contains(s.T(), s.get("/").Body.String(), "<h1>Математические операции</h1>")
+}
 
-	err := s.mongoClient.Database("multiply_app").Collection("results").FindOne(
-		ctx,
-		bson.M{"number1": 10, "number2": 2, "operation": "divide"},
-	).Decode(&result)
+// TestNumberFormat тестирует запись результатов по формату из конфигурации
+// в журнале, таблице результатов и выгрузке
+func (s *APITestSuite) TestNumberFormat() {
+	// По умолчанию малые числа не теряются при записи в журнал
+	require.Equal(s.T(), http.StatusSeeOther, s.postForm("/multiply", url.Values{"number1": {"1e-9"}, "number2": {"1"}}).Code)
+	entries := s.logs()
+	require.Len(s.T(), entries, 1)
+	assert.Equal(s.T(), "1e-09 * 1", entries[0].Input)
+	assert.Equal(s.T(), "1e-09", entries[0].Result)
+
+	defaultFormat := appConfig.NumberFormat
+	defer func() { appConfig.NumberFormat = defaultFormat }()
+	appConfig.NumberFormat = config.NumberFormatConfig{Notation: "fixed", Precision: 2, Rounding: "half_up"}
+
+	require.Equal(s.T(), http.StatusSeeOther, s.postForm("/divide", url.Values{"number1": {"2"}, "number2": {"3"}}).Code)
+	entries = s.logs()
+	require.Len(s.T(), entries, 2)
+	assert.Equal(s.T(), "2.00 / 3.00", entries[1].Input)
+	assert.Equal(s.T(), "0.67", entries[1].Result)
+
+	// Таблица записывает числа по формату и в локали пользователя
+	body := s.get("/?operation=divide").Body.String()
+	assert.Contains(s.T(), body, "<td>2,00</td>")
+	assert.Contains(s.T(), body, "<td>0,67</td>")
+
+	// Выгрузка CSV записывает числа без округления и импортируется обратно,
+	// а в столбцах *_formatted - по формату
+	export := s.get("/export?operation=divide").Body.String()
+	records, err := csv.NewReader(strings.NewReader(export)).ReadAll()
+	require.NoError(s.T(), err)
+	require.Len(s.T(), records, 2)
+	columns := map[string]string{}
+	for i, name := range records[0] {
+		columns[name] = records[1][i]
+	}
+	assert.Equal(s.T(), []string{"2", "3", "0.6666666666666666"}, []string{columns["number1"], columns["number2"], columns["result"]})
+	assert.Equal(s.T(), []string{"2.00", "3.00", "0.67"},
+		[]string{columns["number1_formatted"], columns["number2_formatted"], columns["result_formatted"]})
 
-	assert.NoError(s.T(), err)
-	assert.Equal(s.T(), float64(5), result["result"])
+	report := s.apiImport("export.csv", export)
+	assert.Equal(s.T(), 1, report.Imported)
+	assert.Empty(s.T(), report.Errors)
 }
 
-// TestDivideByZero тестирует обработку ошибки при делении на ноль
-func (s *APITestSuite) TestDivideByZero() {
-	// Создаем данные формы
-	form := url.Values{}
-	form.Add("number1", "10")
-	form.Add("number2", "0")
+// TestEvaluate тестирует вычисление выражения из формы
+func (s *APITestSuite) TestEvaluate() {
+	w := s.postForm("/evaluate", url.Values{"expression": {"(1+2)*3"}})
 
-	// Создаем тестовый HTTP-запрос
-	req := httptest.NewRequest(http.MethodPost, "/divide", strings.NewReader(form.Encode()))
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-
-	// Создаем ResponseRecorder для записи ответа
-	w := httptest.NewRecorder()
+	assert.Equal(s.T(), http.StatusSeeOther, w.Code)
+	result := s.findResult(models.OperationEvaluate)
+	assert.Equal(s.T(), float64(9), result.Result)
+	assert.Equal(s.T(), "(1 + 2) * 3", result.Normalized)
+}
 
-	// Выполняем запрос
-	s.app.ServeHTTP(w, req)
+// get выполняет GET-запрос по указанному адресу
+func (s *APITestSuite) get(path string) *httptest.ResponseRecorder {
+	return s.do(httptest.NewRequest(http.MethodGet, path, nil))
+}
 
-	// Проверяем статус ответа (должен быть ошибка)
-	assert.Equal(s.T(), http.StatusBadRequest, w.Code)
+// seedResults сохраняет n результатов операции пользователя s.user
+// с интервалом в минуту
+func (s *APITestSuite) seedResults(operation string, n int, start time.Time) {
+	s.seedUserResults(s.user.ID, operation, n, start)
+}
 
-	// Проверяем, что в ответе содержится сообщение об ошибке в JSON формате
-	var response map[string]interface{}
-	assert.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &response))
-	assert.Equal(s.T(), "Деление на ноль невозможно", response["error"])
+// seedUserResults сохраняет n результатов операции пользователя userID
+// с интервалом в минуту
+func (s *APITestSuite) seedUserResults(userID primitive.ObjectID, operation string, n int, start time.Time) {
+	for i := 0; i < n; i++ {
+		result := models.Result{
+			Operands:  []models.Operand{{Value: float64(i)}},
+			Result:    float64(i),
+			Operation: operation,
+			CreatedAt: start.Add(time.Duration(i) * time.Minute),
+			UserID:    userID,
+		}
+		require.NoError(s.T(), s.store.InsertResult(context.Background(), &result))
+	}
 }
 
-// TestInvalidInput1 тестирует обработку неверного формата ввода первого числа
-func (s *APITestSuite) TestInvalidInput1() {
-	// Создаем данные формы с некорректным значением
-	form := url.Values{}
-	form.Add("number1", "abc") // не число
-	form.Add("number2", "5")
+// TestAPIResultsPagination тестирует постраничную выдачу истории через API
+func (s *APITestSuite) TestAPIResultsPagination() {
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	s.seedResults("add", 25, start)
+	s.seedResults("multiply", 5, start.AddDate(0, 0, 1))
 
-	// Создаем тестовый HTTP-запрос
-	req := httptest.NewRequest(http.MethodPost, "/multiply", strings.NewReader(form.Encode()))
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	var response struct {
+		Results    []models.Result `json:"results"`
+		Pagination pagination      `json:"pagination"`
+	}
 
-	// Создаем ResponseRecorder для записи ответа
-	w := httptest.NewRecorder()
+	w := s.get("/api/v1/results?operation=add&size=10&page=3")
+	require.Equal(s.T(), http.StatusOK, w.Code)
+	require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(s.T(), pagination{Page: 3, Size: 10, Total: 25, Pages: 3}, response.Pagination)
+	require.Len(s.T(), response.Results, 5)
+	// По умолчанию новые записи первыми, на последней странице - самые старые
+	assert.Equal(s.T(), float64(4), response.Results[0].OperandValue(0))
+	assert.Equal(s.T(), float64(0), response.Results[4].OperandValue(0))
+
+	w = s.get("/api/v1/results?sort=number1&order=asc&from=2024-06-02&to=2024-06-02")
+	require.Equal(s.T(), http.StatusOK, w.Code)
+	require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(s.T(), int64(5), response.Pagination.Total)
+	require.Len(s.T(), response.Results, 5)
+	assert.Equal(s.T(), "multiply", response.Results[0].Operation)
+	assert.Equal(s.T(), float64(0), response.Results[0].OperandValue(0))
+}
 
-	// Выполняем запрос
-	s.app.ServeHTTP(w, req)
+// TestAPIResultsInvalidQuery тестирует ошибки в параметрах истории
+func (s *APITestSuite) TestAPIResultsInvalidQuery() {
+	tests := map[string]string{
+		"page=0":                        "page",
+		"page=9223372036854775807":      "page",
+		"page=100001&size=100":          "page",
+		"size=1000":                     "size",
+		"sort=password":                 "sort",
+		"order=up":                      "order",
+		"operation=pow":                 "operation",
+		"from=01.06.2024":               "from",
+		"from=2024-06-02&to=2024-06-01": "from",
+	}
 
-	// Проверяем статус ответа (должен быть ошибка)
-	assert.Equal(s.T(), http.StatusBadRequest, w.Code)
+	for query, field := range tests {
+		w := s.get("/api/v1/results?" + query)
+		assert.Equal(s.T(), http.StatusBadRequest, w.Code, query)
 
-	// Проверяем, что в ответе содержится сообщение об ошибке в JSON формате
-	var response map[string]interface{}
-	assert.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &response))
-	assert.Equal(s.T(), "Неверный формат первого числа", response["error"])
+		var response apiErrorResponse
+		require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(s.T(), "invalid_query", response.Error.Code, query)
+		assert.Equal(s.T(), field, response.Error.Field, query)
+	}
 }
 
-// TestInvalidInput2 тестирует обработку неверного формата ввода второго числа
-func (s *APITestSuite) TestInvalidInput2() {
-	// Создаем данные формы с некорректным значением
-	form := url.Values{}
-	form.Add("number1", "12")
-	form.Add("number2", "выа") // не число
-
-	// Создаем тестовый HTTP-запрос
-	req := httptest.NewRequest(http.MethodPost, "/multiply", strings.NewReader(form.Encode()))
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+// TestIndexPagination тестирует ссылки пагинации на главной странице
+func (s *APITestSuite) TestIndexPagination() {
+	s.seedResults("add", 15, time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
 
-	// Создаем ResponseRecorder для записи ответа
-	w := httptest.NewRecorder()
-
-	// Выполняем запрос
-	s.app.ServeHTTP(w, req)
+	w := s.get("/?size=10&sort=result")
+	require.Equal(s.T(), http.StatusOK, w.Code)
+	body := w.Body.String()
+	assert.Contains(s.T(), body, "Страница 1 из 2")
+	assert.Contains(s.T(), body, `href="/?page=2&amp;size=10&amp;sort=result"`)
 
-	// Проверяем статус ответа (должен быть ошибка)
+	w = s.get("/?page=abc")
 	assert.Equal(s.T(), http.StatusBadRequest, w.Code)
-
-	// Проверяем, что в ответе содержится сообщение об ошибке в JSON формате
-	var response map[string]interface{}
-	assert.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &response))
-	assert.Equal(s.T(), "Неверный формат второго числа", response["error"])
+	assert.Contains(s.T(), w.Body.String(), "Номер страницы должен быть положительным целым числом")
 }
 
-// TestDiff - тестирует эндпоинт вычитания
-func (s *APITestSuite) TestDiff() {
-	// Создаем данные формы с некорректным значением
-	form := url.Values{}
-	form.Add("number1", "12")
-	form.Add("number2", "4")
-	// Создаем тестовый HTTP-запрос
-	req := httptest.NewRequest(http.MethodPost, "/subtract", strings.NewReader(form.Encode()))
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+// TestHealth тестирует проверки живости и готовности
+func (s *APITestSuite) TestHealth() {
+	w := s.get("/healthz")
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	assert.JSONEq(s.T(), `{"status": "ok"}`, w.Body.String())
 
-	// Создаем ResponseRecorder для записи ответа
-	w := httptest.NewRecorder()
+	w = s.get("/readyz")
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	assert.JSONEq(s.T(), `{"status": "ok", "checks": {"storage": "ok", "migrations": "ok"}}`, w.Body.String())
+}
 
-	// Выполняем запрос
-	s.app.ServeHTTP(w, req)
+// TestMetrics тестирует метрики операций, хранилища и запросов
+func (s *APITestSuite) TestMetrics() {
+	s.postForm("/multiply", url.Values{"number1": {"2"}, "number2": {"3"}})
+	s.postJSON("/api/v1/divide", `{"number1": 1, "number2": 0}`)
+	s.postJSON("/api/v1/add", `{"number1": "x", "number2": 1}`)
+
+	w := s.get("/metrics")
+	require.Equal(s.T(), http.StatusOK, w.Code)
+	body := w.Body.String()
+
+	assert.Contains(s.T(), body, `multiply_app_operations_total{operation="multiply",outcome="success"} 1`)
+	assert.Contains(s.T(), body, `multiply_app_operations_total{operation="divide",outcome="validation_error"} 1`)
+	assert.Contains(s.T(), body, `multiply_app_operations_total{operation="add",outcome="invalid_input"} 1`)
+	assert.Contains(s.T(), body, `multiply_app_results_stored{operation="multiply"} 1`)
+	assert.Contains(s.T(), body, `multiply_app_results_stored{operation="divide"} 0`)
+	assert.Contains(s.T(), body, `multiply_app_storage_call_duration_seconds_count{call="insert_result",outcome="success"} 1`)
+	assert.Contains(s.T(), body, `multiply_app_http_request_duration_seconds_count{method="POST",route="/multiply",status="303"} 1`)
+}
 
-	// Проверяем статус ответа (должен быть редирект)
-	assert.Equal(s.T(), http.StatusSeeOther, w.Code)
+// TestRequestIDInLogEntry тестирует сохранение X-Request-ID в журнале операций
+func (s *APITestSuite) TestRequestIDInLogEntry() {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/add", strings.NewReader(`{"number1": 1, "number2": 2}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Request-ID", "req-42")
+	w := s.do(req)
 
-	// Проверяем, что запись добавлена в базу данных
-	ctx := context.Background()
-	var result bson.M
+	assert.Equal(s.T(), http.StatusCreated, w.Code)
+	assert.Equal(s.T(), "req-42", w.Header().Get("X-Request-ID"))
 
-	err := s.mongoClient.Database("multiply_app").Collection("results").FindOne(
-		ctx,
-		bson.M{"number1": 12, "number2": 4, "operation": "subtract"},
-	).Decode(&result)
+	logs := s.logs()
+	require.Len(s.T(), logs, 1)
+	assert.Equal(s.T(), "req-42", logs[0].RequestID)
+}
 
-	assert.NoError(s.T(), err)
-	assert.Equal(s.T(), float64(8), result["result"])
+// TestLogFailedAttempts проверяет, что в журнал попадают и неудачные попытки
+func (s *APITestSuite) TestLogFailedAttempts() {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/multiply", strings.NewReader(`{"number1": 3, "number2": 4}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "calc-client/1.0")
+	require.Equal(s.T(), http.StatusCreated, s.do(req).Code)
+
+	require.Equal(s.T(), http.StatusBadRequest, s.postForm("/multiply", url.Values{"number1": {"abc"}, "number2": {"5"}}).Code)
+	require.Equal(s.T(), http.StatusUnprocessableEntity, s.postJSON("/api/v1/divide", `{"number1": 1, "number2": 0}`).Code)
+	require.Equal(s.T(), http.StatusBadRequest, s.postForm("/evaluate", url.Values{"expression": {"1 +"}}).Code)
+
+	logs := s.logs()
+	require.Len(s.T(), logs, 4)
+
+	success := logs[0]
+	assert.Equal(s.T(), models.LogStatusSuccess, success.Status)
+	assert.Empty(s.T(), success.Error)
+	assert.Equal(s.T(), s.findResult("multiply").ID, success.ResultID)
+	assert.Equal(s.T(), "calc-client/1.0", success.UserAgent)
+	assert.Positive(s.T(), success.Duration)
+
+	form := logs[1]
+	assert.Equal(s.T(), "multiply", form.Operation)
+	assert.Equal(s.T(), models.LogStatusValidationError, form.Status)
+	assert.Equal(s.T(), "abc, 5", form.Input)
+	assert.Contains(s.T(), form.Error, "Неверный формат первого числа")
+	assert.True(s.T(), form.ResultID.IsZero())
+
+	divide := logs[2]
+	assert.Equal(s.T(), "divide", divide.Operation)
+	assert.Equal(s.T(), models.LogStatusValidationError, divide.Status)
+	assert.Contains(s.T(), divide.Error, "Деление на ноль невозможно")
+
+	evaluate := logs[3]
+	assert.Equal(s.T(), models.OperationEvaluate, evaluate.Operation)
+	assert.Equal(s.T(), models.LogStatusValidationError, evaluate.Status)
+	assert.Equal(s.T(), "1 +", evaluate.Input)
+	assert.NotEmpty(s.T(), evaluate.Error)
 }
 
 // TestAPITestSuite запускает все тесты в наборе
@@ -0,0 +1,149 @@
+// Package metrics собирает метрики приложения в формате Prometheus:
+// число операций по результату, длительность HTTP запросов и обращений
+// к хранилищу, число сохраненных результатов.
+package metrics
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/igor-fedko/go_multiply_app/storage"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace - префикс имен метрик приложения
+const namespace = "multiply_app"
+
+// Результаты операции для метки outcome
+const (
+	OutcomeSuccess         = "success"
+	OutcomeInvalidInput    = "invalid_input"
+	OutcomeValidationError = "validation_error"
+	OutcomeStorageError    = "storage_error"
+)
+
+// Metrics - набор метрик приложения со своим реестром
+type Metrics struct {
+	registry        *prometheus.Registry
+	operations      *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	storageDuration *prometheus.HistogramVec
+}
+
+// New создает метрики. Число сохраненных результатов по операциям
+// запрашивается у results при каждом сборе метрик с таймаутом timeout;
+// список операций возвращает operationNames.
+func New(results storage.ResultStore, operationNames func() []string, timeout time.Duration) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		operations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "operations_total",
+			Help:      "Число вычислений по операции и результату.",
+		}, []string{"operation", "outcome"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Длительность обработки HTTP запросов.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		storageDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "storage_call_duration_seconds",
+			Help:      "Длительность обращений к хранилищу.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"call", "outcome"}),
+	}
+
+	m.registry.MustRegister(
+		m.operations,
+		m.requestDuration,
+		m.storageDuration,
+		&resultsCollector{results: results, operationNames: operationNames, timeout: timeout},
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// Handler возвращает обработчик /metrics
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// Registry возвращает реестр метрик (используется в тестах)
+func (m *Metrics) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+// ObserveOperation учитывает вычисление операции с результатом outcome
+func (m *Metrics) ObserveOperation(operation, outcome string) {
+	m.operations.WithLabelValues(operation, outcome).Inc()
+}
+
+// Middleware измеряет длительность запросов. Метка route - шаблон
+// маршрута Gin, поэтому идентификаторы в пути не размножают ряды;
+// запросы к неизвестным маршрутам учитываются как "unmatched".
+func (m *Metrics) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		m.requestDuration.
+			WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).
+			Observe(time.Since(start).Seconds())
+	}
+}
+
+// observeStorage учитывает обращение к хранилищу call, начатое в start
+func (m *Metrics) observeStorage(call string, start time.Time, err error) {
+	outcome := OutcomeSuccess
+	if err != nil {
+		outcome = OutcomeStorageError
+	}
+	m.storageDuration.WithLabelValues(call, outcome).Observe(time.Since(start).Seconds())
+}
+
+// resultsCollector отдает число сохраненных результатов по операциям
+type resultsCollector struct {
+	results        storage.ResultStore
+	operationNames func() []string
+	timeout        time.Duration
+}
+
+var resultsStoredDesc = prometheus.NewDesc(
+	prometheus.BuildFQName(namespace, "", "results_stored"),
+	"Число сохраненных результатов по операции.",
+	[]string{"operation"}, nil,
+)
+
+func (rc *resultsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- resultsStoredDesc
+}
+
+// Collect подсчитывает результаты в хранилище; подсчет по операции
+// обслуживается индексом (operation, created_at)
+func (rc *resultsCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), rc.timeout)
+	defer cancel()
+
+	for _, name := range rc.operationNames() {
+		count, err := rc.results.CountResults(ctx, storage.ResultQuery{Scope: storage.AllUsers(), Operation: name})
+		if err != nil {
+			slog.Error("count stored results for metrics", "error", err)
+			ch <- prometheus.NewInvalidMetric(resultsStoredDesc, err)
+			return
+		}
+		ch <- prometheus.MustNewConstMetric(resultsStoredDesc, prometheus.GaugeValue, float64(count), name)
+	}
+}
@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/igor-fedko/go_multiply_app/models"
+	"github.com/igor-fedko/go_multiply_app/storage"
+	"github.com/igor-fedko/go_multiply_app/storage/memory"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func newTestMetrics(s storage.ResultStore) *Metrics {
+	return New(s, func() []string { return []string{"add", "multiply"} }, time.Second)
+}
+
+func TestObserveOperation(t *testing.T) {
+	m := newTestMetrics(memory.New())
+
+	m.ObserveOperation("add", OutcomeSuccess)
+	m.ObserveOperation("add", OutcomeSuccess)
+	m.ObserveOperation("add", OutcomeValidationError)
+
+	assert.Equal(t, float64(2), testutil.ToFloat64(m.operations.WithLabelValues("add", OutcomeSuccess)))
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.operations.WithLabelValues("add", OutcomeValidationError)))
+}
+
+func TestInstrumentStore(t *testing.T) {
+	ctx := context.Background()
+	inner := memory.New()
+	m := newTestMetrics(inner)
+	s := m.InstrumentStore(inner)
+
+	// Хранилище в памяти не поддерживает миграции - обертка тоже
+	_, isMigrator := s.(storage.Migrator)
+	assert.False(t, isMigrator)
+
+	for _, op := range []string{"add", "add", "multiply"} {
+		require.NoError(t, s.InsertResult(ctx, &models.Result{Operation: op, CreatedAt: time.Now()}))
+	}
+	_, err := s.GetResult(ctx, storage.AllUsers(), primitive.NewObjectID())
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+
+	// Два ряда: insert_result и get_result; отсутствие записи не считается ошибкой хранилища
+	assert.Equal(t, 2, testutil.CollectAndCount(m.storageDuration, "multiply_app_storage_call_duration_seconds"))
+
+	expected := `
+# HELP multiply_app_results_stored Число сохраненных результатов по операции.
+# TYPE multiply_app_results_stored gauge
+multiply_app_results_stored{operation="add"} 2
+multiply_app_results_stored{operation="multiply"} 1
+`
+	require.NoError(t, testutil.GatherAndCompare(m.Registry(), strings.NewReader(expected), "multiply_app_results_stored"))
+}
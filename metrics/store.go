@@ -0,0 +1,204 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/igor-fedko/go_multiply_app/models"
+	"github.com/igor-fedko/go_multiply_app/storage"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// InstrumentStore возвращает хранилище, измеряющее длительность обращений
+// к s. Если s поддерживает миграции, их поддерживает и результат.
+func (m *Metrics) InstrumentStore(s storage.Store) storage.Store {
+	instrumented := &instrumentedStore{store: s, metrics: m}
+	if migrator, ok := s.(storage.Migrator); ok {
+		return &instrumentedMigratorStore{instrumentedStore: instrumented, migrator: migrator}
+	}
+	return instrumented
+}
+
+// instrumentedStore измеряет обращения к хранилищу
+type instrumentedStore struct {
+	store   storage.Store
+	metrics *Metrics
+}
+
+var (
+	_ storage.Store    = (*instrumentedStore)(nil)
+	_ storage.Migrator = (*instrumentedMigratorStore)(nil)
+)
+
+func (s *instrumentedStore) InsertResult(ctx context.Context, result *models.Result) error {
+	start := time.Now()
+	err := s.store.InsertResult(ctx, result)
+	s.metrics.observeStorage("insert_result", start, err)
+	return err
+}
+
+func (s *instrumentedStore) InsertResults(ctx context.Context, results []*models.Result) error {
+	start := time.Now()
+	err := s.store.InsertResults(ctx, results)
+	s.metrics.observeStorage("insert_results", start, err)
+	return err
+}
+
+func (s *instrumentedStore) GetResult(ctx context.Context, scope storage.Scope, id primitive.ObjectID) (models.Result, error) {
+	start := time.Now()
+	result, err := s.store.GetResult(ctx, scope, id)
+	s.metrics.observeStorage("get_result", start, ignoreNotFound(err))
+	return result, err
+}
+
+func (s *instrumentedStore) ListResults(ctx context.Context, query storage.ResultQuery) ([]models.Result, error) {
+	start := time.Now()
+	results, err := s.store.ListResults(ctx, query)
+	s.metrics.observeStorage("list_results", start, err)
+	return results, err
+}
+
+func (s *instrumentedStore) CountResults(ctx context.Context, query storage.ResultQuery) (int64, error) {
+	start := time.Now()
+	count, err := s.store.CountResults(ctx, query)
+	s.metrics.observeStorage("count_results", start, err)
+	return count, err
+}
+
+func (s *instrumentedStore) EachResult(ctx context.Context, query storage.ResultQuery, fn func(models.Result) error) error {
+	start := time.Now()
+	err := s.store.EachResult(ctx, query, fn)
+	s.metrics.observeStorage("each_result", start, err)
+	return err
+}
+
+func (s *instrumentedStore) InsertLog(ctx context.Context, entry *models.LogEntry) error {
+	start := time.Now()
+	err := s.store.InsertLog(ctx, entry)
+	s.metrics.observeStorage("insert_log", start, err)
+	return err
+}
+
+func (s *instrumentedStore) GetLog(ctx context.Context, id primitive.ObjectID) (models.LogEntry, error) {
+	start := time.Now()
+	entry, err := s.store.GetLog(ctx, id)
+	s.metrics.observeStorage("get_log", start, ignoreNotFound(err))
+	return entry, err
+}
+
+func (s *instrumentedStore) ListLogs(ctx context.Context, query storage.LogQuery) ([]models.LogEntry, error) {
+	start := time.Now()
+	entries, err := s.store.ListLogs(ctx, query)
+	s.metrics.observeStorage("list_logs", start, err)
+	return entries, err
+}
+
+func (s *instrumentedStore) CountLogs(ctx context.Context, query storage.LogQuery) (int64, error) {
+	start := time.Now()
+	count, err := s.store.CountLogs(ctx, query)
+	s.metrics.observeStorage("count_logs", start, err)
+	return count, err
+}
+
+func (s *instrumentedStore) CreateUser(ctx context.Context, user *models.User) error {
+	start := time.Now()
+	err := s.store.CreateUser(ctx, user)
+	s.metrics.observeStorage("create_user", start, ignoreDuplicate(err))
+	return err
+}
+
+func (s *instrumentedStore) GetUser(ctx context.Context, id primitive.ObjectID) (models.User, error) {
+	start := time.Now()
+	user, err := s.store.GetUser(ctx, id)
+	s.metrics.observeStorage("get_user", start, ignoreNotFound(err))
+	return user, err
+}
+
+func (s *instrumentedStore) GetUserByUsername(ctx context.Context, username string) (models.User, error) {
+	start := time.Now()
+	user, err := s.store.GetUserByUsername(ctx, username)
+	s.metrics.observeStorage("get_user_by_username", start, ignoreNotFound(err))
+	return user, err
+}
+
+func (s *instrumentedStore) CreateToken(ctx context.Context, token *models.Token) error {
+	start := time.Now()
+	err := s.store.CreateToken(ctx, token)
+	s.metrics.observeStorage("create_token", start, err)
+	return err
+}
+
+func (s *instrumentedStore) GetTokenByHash(ctx context.Context, hash string) (models.Token, error) {
+	start := time.Now()
+	token, err := s.store.GetTokenByHash(ctx, hash)
+	s.metrics.observeStorage("get_token", start, ignoreNotFound(err))
+	return token, err
+}
+
+func (s *instrumentedStore) DeleteToken(ctx context.Context, hash string) error {
+	start := time.Now()
+	err := s.store.DeleteToken(ctx, hash)
+	s.metrics.observeStorage("delete_token", start, err)
+	return err
+}
+
+func (s *instrumentedStore) SetUserRole(ctx context.Context, username, role string) error {
+	start := time.Now()
+	err := s.store.SetUserRole(ctx, username, role)
+	s.metrics.observeStorage("set_user_role", start, ignoreNotFound(err))
+	return err
+}
+
+func (s *instrumentedStore) SetUserLocale(ctx context.Context, id primitive.ObjectID, locale string) error {
+	start := time.Now()
+	err := s.store.SetUserLocale(ctx, id, locale)
+	s.metrics.observeStorage("set_user_locale", start, ignoreNotFound(err))
+	return err
+}
+
+func (s *instrumentedStore) Ping(ctx context.Context) error {
+	start := time.Now()
+	err := s.store.Ping(ctx)
+	s.metrics.observeStorage("ping", start, err)
+	return err
+}
+
+func (s *instrumentedStore) Close(ctx context.Context) error {
+	return s.store.Close(ctx)
+}
+
+// instrumentedMigratorStore - instrumentedStore для хранилищ с миграциями.
+// Миграции выполняются редко и не измеряются.
+type instrumentedMigratorStore struct {
+	*instrumentedStore
+	migrator storage.Migrator
+}
+
+func (s *instrumentedMigratorStore) MigrationStatus(ctx context.Context) ([]storage.MigrationStatus, error) {
+	return s.migrator.MigrationStatus(ctx)
+}
+
+func (s *instrumentedMigratorStore) MigrateUp(ctx context.Context) error {
+	return s.migrator.MigrateUp(ctx)
+}
+
+func (s *instrumentedMigratorStore) MigrateDown(ctx context.Context) error {
+	return s.migrator.MigrateDown(ctx)
+}
+
+// ignoreNotFound не считает отсутствие записи ошибкой хранилища
+func ignoreNotFound(err error) error {
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// ignoreDuplicate не считает занятое имя пользователя ошибкой хранилища
+func ignoreDuplicate(err error) error {
+	if errors.Is(err, storage.ErrDuplicate) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"text/tabwriter"
+
+	"github.com/igor-fedko/go_multiply_app/storage"
+)
+
+// migrateUsage - справка по подкоманде migrate
+const migrateUsage = "использование: migrate up|down|status"
+
+// errNoMigrations возвращается, если хранилище не поддерживает миграции
+var errNoMigrations = errors.New("хранилище не поддерживает миграции схемы")
+
+// migrateOnStart применяет непримененные миграции при запуске сервера.
+// Хранилища без схемы (в памяти) пропускаются.
+func migrateOnStart(ctx context.Context, s storage.Store) error {
+	migrator, ok := s.(storage.Migrator)
+	if !ok {
+		return nil
+	}
+	if err := migrator.MigrateUp(ctx); err != nil {
+		return err
+	}
+	slog.Info("storage schema is up to date")
+	return nil
+}
+
+// runMigrateCommand выполняет подкоманду migrate: up применяет все
+// непримененные миграции, down откатывает последнюю, status выводит
+// состояние миграций в out
+func runMigrateCommand(ctx context.Context, s storage.Store, args []string, out io.Writer) error {
+	if len(args) != 1 {
+		return errors.New(migrateUsage)
+	}
+
+	migrator, ok := s.(storage.Migrator)
+	if !ok {
+		return errNoMigrations
+	}
+
+	switch args[0] {
+	case "up":
+		if err := migrator.MigrateUp(ctx); err != nil {
+			return err
+		}
+	case "down":
+		if err := migrator.MigrateDown(ctx); err != nil {
+			return err
+		}
+	case "status":
+	default:
+		return errors.New(migrateUsage)
+	}
+
+	statuses, err := migrator.MigrationStatus(ctx)
+	if err != nil {
+		return err
+	}
+	return printMigrationStatus(out, statuses)
+}
+
+// printMigrationStatus выводит таблицу состояния миграций
+func printMigrationStatus(out io.Writer, statuses []storage.MigrationStatus) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ВЕРСИЯ\tИМЯ\tСОСТОЯНИЕ\tПРИМЕНЕНА")
+	for _, st := range statuses {
+		state, appliedAt := "ожидает", "-"
+		if st.Applied {
+			state = "применена"
+			appliedAt = st.AppliedAt.UTC().Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", st.Version, st.Name, state, appliedAt)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/igor-fedko/go_multiply_app/storage/memory"
+	"github.com/igor-fedko/go_multiply_app/storage/sqlstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMigrateCommand тестирует подкоманду migrate на SQLite
+func TestMigrateCommand(t *testing.T) {
+	ctx := context.Background()
+	s, err := sqlstore.Open(ctx, sqlstore.DriverSQLite, filepath.Join(t.TempDir(), "migrate.db"))
+	require.NoError(t, err)
+	defer s.Close(ctx)
+
+	var out bytes.Buffer
+	require.NoError(t, runMigrateCommand(ctx, s, []string{"status"}, &out))
+	assert.Contains(t, out.String(), "ожидает")
+	assert.NotContains(t, out.String(), "применена")
+
+	out.Reset()
+	require.NoError(t, runMigrateCommand(ctx, s, []string{"up"}, &out))
+	assert.Contains(t, out.String(), "0001")
+	assert.NotContains(t, out.String(), "ожидает")
+
+	// Повторный запуск ничего не меняет
+	require.NoError(t, migrateOnStart(ctx, s))
+
+	out.Reset()
+	require.NoError(t, runMigrateCommand(ctx, s, []string{"down"}, &out))
+	assert.Contains(t, out.String(), "ожидает")
+
+	assert.EqualError(t, runMigrateCommand(ctx, s, []string{"sideways"}, &out), migrateUsage)
+	assert.EqualError(t, runMigrateCommand(ctx, s, nil, &out), migrateUsage)
+}
+
+// TestMigrateCommandMemory тестирует migrate для хранилища без схемы
+func TestMigrateCommandMemory(t *testing.T) {
+	ctx := context.Background()
+
+	assert.ErrorIs(t, runMigrateCommand(ctx, memory.New(), []string{"up"}, &bytes.Buffer{}), errNoMigrations)
+	assert.NoError(t, migrateOnStart(ctx, memory.New()))
+}
+
+// TestReadyzPendingMigrations тестирует, что /readyz не готов до применения миграций
+func TestReadyzPendingMigrations(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	s, err := sqlstore.Open(ctx, sqlstore.DriverSQLite, filepath.Join(t.TempDir(), "ready.db"))
+	require.NoError(t, err)
+	defer s.Close(ctx)
+
+	previous := store
+	store = s
+	defer func() { store = previous }()
+
+	router := gin.New()
+	router.GET("/readyz", readyzHandler)
+
+	probe := func() int {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusServiceUnavailable, probe())
+	require.NoError(t, s.MigrateUp(ctx))
+	assert.Equal(t, http.StatusOK, probe())
+}
@@ -6,7 +6,19 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-// LogEntry представляет запись в журнале операций
+// Статусы попыток операций в журнале
+const (
+	// LogStatusSuccess - результат вычислен и сохранен
+	LogStatusSuccess = "success"
+	// LogStatusValidationError - неверные входные данные: формат числа,
+	// деление на ноль, ошибка в выражении
+	LogStatusValidationError = "validation_error"
+	// LogStatusStorageError - результат вычислен, но не сохранен
+	LogStatusStorageError = "storage_error"
+)
+
+// LogEntry представляет запись в журнале операций. В журнал записывается
+// каждая попытка операции, в том числе неудачная.
 type LogEntry struct {
 	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
 	Operation string             `bson:"operation" json:"operation"`
@@ -14,4 +26,23 @@ type LogEntry struct {
 	Result    string             `bson:"result" json:"result"`
 	UserIP    string             `bson:"user_ip" json:"user_ip"`
 	Timestamp time.Time          `bson:"timestamp" json:"timestamp"`
+	// RequestID - идентификатор HTTP запроса (заголовок X-Request-ID)
+	RequestID string `bson:"request_id,omitempty" json:"request_id,omitempty"`
+	// UserID - пользователь, выполнивший операцию
+	UserID primitive.ObjectID `bson:"user_id,omitempty" json:"user_id,omitempty"`
+	// ResultID - сохраненный результат операции; пуст у неудачных попыток
+	ResultID primitive.ObjectID `bson:"result_id,omitempty" json:"result_id,omitempty"`
+	// Status - итог попытки: LogStatusSuccess, LogStatusValidationError
+	// или LogStatusStorageError; пуст у записей, сохраненных до его появления
+	Status string `bson:"status,omitempty" json:"status,omitempty"`
+	// Error - сообщение об ошибке неудачной попытки
+	Error string `bson:"error,omitempty" json:"error,omitempty"`
+	// UserAgent - заголовок User-Agent запроса
+	UserAgent string `bson:"user_agent,omitempty" json:"user_agent,omitempty"`
+	// NumericClass - класс чисел попытки: "finite" у успешных операций
+	// или код ошибки числового диапазона ("non_finite_input", "overflow",
+	// "underflow", "non_finite_result"); пуст у остальных ошибок
+	NumericClass string `bson:"numeric_class,omitempty" json:"numeric_class,omitempty"`
+	// Duration - длительность обработки попытки
+	Duration time.Duration `bson:"duration_ns,omitempty" json:"duration_ns,omitempty"`
 }
@@ -1,17 +1,189 @@
 package models
 
 import (
+	"encoding/json"
+	"strconv"
+	"strings"
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-// Result представляет собой результат математической операции над двумя числами
+// Режимы точности вычислений
+const (
+	// PrecisionFloat - вычисления в float64 (режим по умолчанию)
+	PrecisionFloat = "float"
+	// PrecisionExact - точные вычисления на big.Rat с хранением десятичных строк
+	PrecisionExact = "exact"
+)
+
+// Виды записей результатов
+const (
+	// KindOperation - результат операции над операндами
+	KindOperation = "operation"
+	// KindExpression - результат вычисления выражения
+	KindExpression = "expression"
+)
+
+// OperationEvaluate - значение поля Operation для вычисленных выражений
+const OperationEvaluate = "evaluate"
+
+// Версии схемы документа результата
+const (
+	// ResultSchemaLegacy - операнды в полях number1 и number2
+	ResultSchemaLegacy = 1
+	// ResultSchemaOperands - операнды в массиве operands
+	ResultSchemaOperands = 2
+)
+
+// ResultSchemaVersion - версия схемы, в которой сохраняются новые результаты
+const ResultSchemaVersion = ResultSchemaOperands
+
+// Operand - операнд вычисления
+type Operand struct {
+	// Name - имя параметра для операций с именованными параметрами;
+	// позиционные операнды имени не имеют
+	Name  string  `bson:"name,omitempty" json:"name,omitempty"`
+	Value float64 `bson:"value" json:"value"`
+	// Exact - операнд в виде точной десятичной строки (только "exact")
+	Exact string `bson:"exact,omitempty" json:"exact,omitempty"`
+}
+
+// String возвращает точную запись операнда, если она есть
+func (o Operand) String() string {
+	if o.Exact != "" {
+		return o.Exact
+	}
+	return strconv.FormatFloat(o.Value, 'g', -1, 64)
+}
+
+// Result представляет собой результат математической операции над операндами
 type Result struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
-	Number1   float64            `bson:"number1" json:"number1"`
-	Number2   float64            `bson:"number2" json:"number2"`
-	Result    float64            `bson:"result" json:"result"`
-	Operation string             `bson:"operation" json:"operation"`
-	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
-} 
\ No newline at end of file
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	SchemaVersion int                `bson:"schema_version" json:"schema_version"`
+	Operands      []Operand          `bson:"operands,omitempty" json:"operands,omitempty"`
+	Result        float64            `bson:"result" json:"result"`
+	Operation     string             `bson:"operation" json:"operation"`
+	CreatedAt     time.Time          `bson:"created_at" json:"created_at"`
+	Kind          string             `bson:"kind,omitempty" json:"kind,omitempty"`
+
+	// Поля точного режима: результат в виде десятичной строки (операнды
+	// хранят точную запись в Operand.Exact). Поле Result при этом содержит
+	// ближайшее к нему значение.
+	Precision   string `bson:"precision,omitempty" json:"precision,omitempty"`
+	ResultExact string `bson:"result_exact,omitempty" json:"result_exact,omitempty"`
+
+	// Поля вычисленного выражения: исходная и нормализованная запись
+	Expression string `bson:"expression,omitempty" json:"expression,omitempty"`
+	Normalized string `bson:"normalized,omitempty" json:"normalized,omitempty"`
+
+	// UserID - пользователь, выполнивший вычисление
+	UserID primitive.ObjectID `bson:"user_id,omitempty" json:"user_id,omitempty"`
+}
+
+// Operand возвращает i-й операнд и признак его наличия
+func (r Result) Operand(i int) (Operand, bool) {
+	if i < 0 || i >= len(r.Operands) {
+		return Operand{}, false
+	}
+	return r.Operands[i], true
+}
+
+// OperandValue возвращает значение i-го операнда или 0, если его нет
+func (r Result) OperandValue(i int) float64 {
+	o, _ := r.Operand(i)
+	return o.Value
+}
+
+// OperandText возвращает запись i-го операнда или пустую строку, если его нет
+func (r Result) OperandText(i int) string {
+	if o, ok := r.Operand(i); ok {
+		return o.String()
+	}
+	return ""
+}
+
+// OperandsText возвращает операнды через запятую; именованные параметры
+// записываются как name=value
+func (r Result) OperandsText() string {
+	parts := make([]string, len(r.Operands))
+	for i, o := range r.Operands {
+		parts[i] = o.String()
+		if o.Name != "" {
+			parts[i] = o.Name + "=" + parts[i]
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// MarshalJSON добавляет к результату поля number1, number2, number1_exact
+// и number2_exact из первых двух операндов, чтобы ответы /api/v1 и выгрузка
+// сохраняли прежний вид; массив operands передается рядом с ними.
+// Отсутствующий операнд, как и раньше, записывается нулем.
+func (r Result) MarshalJSON() ([]byte, error) {
+	type plain Result
+	first, _ := r.Operand(0)
+	second, _ := r.Operand(1)
+	return json.Marshal(struct {
+		plain
+		Number1      float64 `json:"number1"`
+		Number2      float64 `json:"number2"`
+		Number1Exact string  `json:"number1_exact,omitempty"`
+		Number2Exact string  `json:"number2_exact,omitempty"`
+	}{plain(r), first.Value, second.Value, first.Exact, second.Exact})
+}
+
+// legacyUnaryOperations - унарные операции, для которых документы первой
+// версии схемы хранили в number2 заполнитель 0
+var legacyUnaryOperations = map[string]bool{"square": true}
+
+// legacyResult - поля операндов в документах первой версии схемы
+type legacyResult struct {
+	Number1      *float64 `bson:"number1"`
+	Number2      *float64 `bson:"number2"`
+	Number1Exact string   `bson:"number1_exact"`
+	Number2Exact string   `bson:"number2_exact"`
+}
+
+// LegacyOperands переводит операнды из полей number1/number2 первой
+// версии схемы в массив. Заполнитель number2 унарных операций
+// и выражений отбрасывается.
+func LegacyOperands(operation string, number1, number2 *float64, number1Exact, number2Exact string) []Operand {
+	if operation == OperationEvaluate {
+		return nil
+	}
+	var operands []Operand
+	if number1 != nil {
+		operands = append(operands, Operand{Value: *number1, Exact: number1Exact})
+	}
+	if number2 != nil && !legacyUnaryOperations[operation] {
+		operands = append(operands, Operand{Value: *number2, Exact: number2Exact})
+	}
+	return operands
+}
+
+// upgrade заполняет Operands из полей первой версии схемы
+func (r *Result) upgrade(legacy legacyResult) {
+	if r.SchemaVersion >= ResultSchemaOperands || len(r.Operands) > 0 {
+		return
+	}
+	r.Operands = LegacyOperands(r.Operation, legacy.Number1, legacy.Number2, legacy.Number1Exact, legacy.Number2Exact)
+	r.SchemaVersion = ResultSchemaLegacy
+}
+
+// UnmarshalBSON читает документы обеих версий схемы: операнды документов
+// первой версии переносятся из number1/number2 в Operands
+func (r *Result) UnmarshalBSON(data []byte) error {
+	type plain Result
+	var doc struct {
+		Result plain        `bson:",inline"`
+		Legacy legacyResult `bson:",inline"`
+	}
+	if err := bson.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	*r = Result(doc.Result)
+	r.upgrade(doc.Legacy)
+	return nil
+}
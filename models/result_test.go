@@ -0,0 +1,115 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestResultUnmarshalLegacyBSON(t *testing.T) {
+	created := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		doc  bson.M
+		want []Operand
+	}{
+		{
+			name: "binary",
+			doc: bson.M{"number1": 0.1, "number2": 0.2, "result": 0.3, "operation": "add",
+				"number1_exact": "0.1", "number2_exact": "0.2"},
+			want: []Operand{{Value: 0.1, Exact: "0.1"}, {Value: 0.2, Exact: "0.2"}},
+		},
+		{
+			name: "unary",
+			doc:  bson.M{"number1": -3.0, "number2": 0.0, "result": 9.0, "operation": "square"},
+			want: []Operand{{Value: -3}},
+		},
+		{
+			name: "expression",
+			doc:  bson.M{"number1": 0.0, "number2": 0.0, "result": 9.0, "operation": OperationEvaluate},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.doc["created_at"] = created
+			data, err := bson.Marshal(tt.doc)
+			require.NoError(t, err)
+
+			var r Result
+			require.NoError(t, bson.Unmarshal(data, &r))
+			assert.Equal(t, ResultSchemaLegacy, r.SchemaVersion)
+			assert.Equal(t, tt.want, r.Operands)
+			assert.Equal(t, created, r.CreatedAt.UTC())
+		})
+	}
+}
+
+func TestResultBSONRoundTrip(t *testing.T) {
+	r := Result{
+		SchemaVersion: ResultSchemaVersion,
+		Operands:      []Operand{{Value: 2}, {Name: "digits", Value: 3}},
+		Result:        6,
+		Operation:     "multiply",
+		CreatedAt:     time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+	}
+	data, err := bson.Marshal(r)
+	require.NoError(t, err)
+
+	var got Result
+	require.NoError(t, bson.Unmarshal(data, &got))
+	got.CreatedAt = got.CreatedAt.UTC()
+	assert.Equal(t, r, got)
+	assert.Equal(t, "2, digits=3", got.OperandsText())
+}
+
+func TestResultMarshalJSON(t *testing.T) {
+	tests := []struct {
+		name   string
+		result Result
+		want   map[string]any
+	}{
+		{
+			name: "binary",
+			result: Result{Operands: []Operand{{Value: 0.1, Exact: "0.1"}, {Value: 0.2, Exact: "0.2"}},
+				Result: 0.3, Operation: "add", Precision: PrecisionExact},
+			want: map[string]any{"number1": 0.1, "number2": 0.2, "number1_exact": "0.1", "number2_exact": "0.2"},
+		},
+		{
+			name:   "unary",
+			result: Result{Operands: []Operand{{Value: -3}}, Result: 9, Operation: "square"},
+			want:   map[string]any{"number1": -3.0, "number2": 0.0},
+		},
+		{
+			name:   "expression",
+			result: Result{Result: 9, Operation: OperationEvaluate, Expression: "(1+2)*3"},
+			want:   map[string]any{"number1": 0.0, "number2": 0.0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.result.SchemaVersion = ResultSchemaVersion
+			data, err := json.Marshal(tt.result)
+			require.NoError(t, err)
+
+			var doc map[string]any
+			require.NoError(t, json.Unmarshal(data, &doc))
+			for key, value := range tt.want {
+				assert.Equal(t, value, doc[key], key)
+			}
+			if _, ok := tt.want["number1_exact"]; !ok {
+				assert.NotContains(t, doc, "number1_exact")
+			}
+			assert.Equal(t, float64(ResultSchemaVersion), doc["schema_version"])
+			assert.Equal(t, tt.result.Result, doc["result"])
+
+			// Массив operands читается обратно без потерь
+			var got Result
+			require.NoError(t, json.Unmarshal(data, &got))
+			assert.Equal(t, tt.result.Operands, got.Operands)
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Роли пользователей
+const (
+	// RoleUser видит только собственную историю
+	RoleUser = "user"
+	// RoleAdmin видит историю всех пользователей
+	RoleAdmin = "admin"
+)
+
+// User - учетная запись пользователя
+type User struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Username     string             `bson:"username" json:"username"`
+	PasswordHash string             `bson:"password_hash" json:"-"`
+	// Role - RoleUser или RoleAdmin; пустое значение равнозначно RoleUser
+	Role string `bson:"role" json:"role"`
+	// Locale - код языка записи чисел ("ru", "en"); пустое значение -
+	// по заголовку Accept-Language браузера
+	Locale    string    `bson:"locale,omitempty" json:"locale,omitempty"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+}
+
+// IsAdmin сообщает, является ли пользователь администратором
+func (u User) IsAdmin() bool {
+	return u.Role == RoleAdmin
+}
+
+// ValidRole сообщает, существует ли роль
+func ValidRole(role string) bool {
+	return role == RoleUser || role == RoleAdmin
+}
+
+// Виды токенов доступа
+const (
+	// TokenSession - токен сессии HTML интерфейса, передается в cookie
+	TokenSession = "session"
+	// TokenAPI - токен JSON API, передается в заголовке Authorization: Bearer
+	TokenAPI = "api"
+)
+
+// Token - токен доступа пользователя. Хранится только хеш токена,
+// сам токен выдается пользователю один раз при создании.
+type Token struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	UserID    primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Kind      string             `bson:"kind" json:"kind"`
+	Name      string             `bson:"name,omitempty" json:"name,omitempty"`
+	Hash      string             `bson:"hash" json:"-"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	// ExpiresAt - время истечения; нулевое значение - бессрочный токен
+	ExpiresAt time.Time `bson:"expires_at,omitempty" json:"expires_at,omitempty"`
+}
+
+// Expired сообщает, истек ли токен к моменту now
+func (t Token) Expired(now time.Time) bool {
+	return !t.ExpiresAt.IsZero() && !now.Before(t.ExpiresAt)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"github.com/igor-fedko/go_multiply_app/locale"
+	"github.com/igor-fedko/go_multiply_app/models"
+	"github.com/igor-fedko/go_multiply_app/numfmt"
+)
+
+// numberFormat возвращает формат записи результатов из конфигурации.
+// По нему числа записываются в журнал операций, таблицы результатов
+// и столбцы *_formatted выгрузки CSV; остальные столбцы выгрузки
+// записывают числа без округления.
+func numberFormat() numfmt.Format {
+	return appConfig.NumberFormat.Format()
+}
+
+// formatOperands записывает операнды по формату для журнала
+func formatOperands(operands []float64) []string {
+	f := numberFormat()
+	text := make([]string, len(operands))
+	for i, v := range operands {
+		text[i] = f.Float(v)
+	}
+	return text
+}
+
+// formatResult записывает результат по формату f: точный результат -
+// по его десятичной записи, без погрешности float64
+func formatResult(f numfmt.Format, r models.Result) string {
+	if r.ResultExact != "" {
+		return f.Decimal(r.ResultExact)
+	}
+	return f.Float(r.Result)
+}
+
+// numberView записывает числа на HTML страницах: по формату из
+// конфигурации и в локали пользователя
+type numberView struct {
+	format numfmt.Format
+	locale locale.Locale
+}
+
+// newNumberView возвращает numberView для локали l
+func newNumberView(l locale.Locale) numberView {
+	return numberView{format: numberFormat(), locale: l}
+}
+
+// Float записывает число float64
+func (v numberView) Float(x float64) string {
+	return v.locale.Localize(v.format.Float(x))
+}
+
+// Number записывает число, заданное десятичной строкой, например
+// точный операнд или результат models.Result.OperandText
+func (v numberView) Number(s string) string {
+	return v.locale.Localize(v.format.Decimal(s))
+}
+
+// Result записывает результат вычисления
+func (v numberView) Result(r models.Result) string {
+	return v.locale.Localize(formatResult(v.format, r))
+}
+
+// Exact записывает точный результат полностью, без округления по формату
+func (v numberView) Exact(r models.Result) string {
+	return v.locale.Localize(r.ResultExact)
+}
@@ -0,0 +1,289 @@
+// Package numfmt записывает числа по настраиваемому формату: с
+// фиксированным числом знаков после точки, в научной и инженерной
+// записи или с заданным числом значащих цифр, с выбранным правилом
+// округления. Числа округляются по десятичной записи, поэтому
+// 2.675 с двумя знаками и округлением half_up дает 2.68, а не 2.67,
+// как при округлении двоичного значения float64.
+//
+// Результат записывается с десятичной точкой и без разделителей разрядов;
+// запись в локали пользователя - задача пакета locale.
+package numfmt
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Notation - способ записи числа
+type Notation string
+
+// Поддерживаемые способы записи
+const (
+	// Fixed - Precision знаков после точки: 1234.50
+	Fixed Notation = "fixed"
+	// Scientific - мантисса от 1 до 10 и Precision знаков после точки: 1.23e+03
+	Scientific Notation = "scientific"
+	// Engineering - порядок кратен трем, мантисса от 1 до 1000: 12.35e+03
+	Engineering Notation = "engineering"
+	// Significant - Precision значащих цифр без конечных нулей, как %g в fmt:
+	// обычная запись, а для очень больших и очень малых чисел - научная
+	Significant Notation = "significant"
+)
+
+// Rounding - правило округления отбрасываемых цифр
+type Rounding string
+
+// Поддерживаемые правила округления
+const (
+	// HalfEven - к ближайшему, половина - к четному (банковское): 2.5 -> 2, 3.5 -> 4
+	HalfEven Rounding = "half_even"
+	// HalfUp - к ближайшему, половина - от нуля: 2.5 -> 3, -2.5 -> -3
+	HalfUp Rounding = "half_up"
+	// TowardZero - отбрасывание цифр: 2.9 -> 2, -2.9 -> -2
+	TowardZero Rounding = "toward_zero"
+)
+
+// MaxPrecision - наибольшее допустимое значение Precision
+const MaxPrecision = 100
+
+// Format - формат записи чисел
+type Format struct {
+	Notation Notation
+	// Precision - число знаков после точки для Fixed, Scientific и
+	// Engineering или число значащих цифр для Significant
+	Precision int
+	Rounding  Rounding
+}
+
+// Default - формат по умолчанию: 15 значащих цифр с банковским округлением.
+// 15 цифр float64 передает без погрешности, поэтому 0.1 + 0.2 записывается как 0.3.
+var Default = Format{Notation: Significant, Precision: 15, Rounding: HalfEven}
+
+// Validate проверяет способ записи, точность и правило округления
+func (f Format) Validate() error {
+	switch f.Notation {
+	case Fixed, Scientific, Engineering:
+		if f.Precision < 0 || f.Precision > MaxPrecision {
+			return fmt.Errorf("precision: ожидается от 0 до %d, получено %d", MaxPrecision, f.Precision)
+		}
+	case Significant:
+		if f.Precision < 1 || f.Precision > MaxPrecision {
+			return fmt.Errorf("precision: ожидается от 1 до %d значащих цифр, получено %d", MaxPrecision, f.Precision)
+		}
+	default:
+		return fmt.Errorf("notation: недопустимое значение %q (fixed, scientific, engineering или significant)", f.Notation)
+	}
+	switch f.Rounding {
+	case HalfEven, HalfUp, TowardZero:
+	default:
+		return fmt.Errorf("rounding: недопустимое значение %q (half_even, half_up или toward_zero)", f.Rounding)
+	}
+	return nil
+}
+
+// Float записывает число float64. Округляется кратчайшая десятичная
+// запись, однозначно задающая число, - та же, что выводит strconv.
+// NaN и бесконечности записываются как в strconv: "NaN", "+Inf", "-Inf".
+func (f Format) Float(v float64) string {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	d, _ := parseDecimal(strconv.FormatFloat(v, 'e', -1, 64))
+	return f.format(d)
+}
+
+// Decimal записывает число, заданное десятичной строкой, например точный
+// результат "0.3333333333" или запись strconv "1e-09". Строка, не
+// являющаяся числом, возвращается без изменений.
+func (f Format) Decimal(s string) string {
+	d, ok := parseDecimal(s)
+	if !ok {
+		return s
+	}
+	return f.format(d)
+}
+
+// format записывает разобранное число по формату
+func (f Format) format(d decimal) string {
+	switch f.Notation {
+	case Fixed:
+		d = d.round(d.point+f.Precision, f.Rounding)
+		return d.fixed(f.Precision)
+	case Scientific:
+		d = d.round(f.Precision+1, f.Rounding)
+		return d.exponential(f.Precision, 1)
+	case Engineering:
+		d = d.round(engIntDigits(d)+f.Precision, f.Rounding)
+		return d.exponential(f.Precision, engIntDigits(d))
+	default:
+		d = d.round(f.Precision, f.Rounding)
+		exp := d.point - 1
+		if d.isZero() || (exp >= -4 && exp < f.Precision) {
+			return d.fixed(max(len(d.digits)-d.point, 0))
+		}
+		return d.exponential(len(d.digits)-1, 1)
+	}
+}
+
+// decimal - десятичное число 0.digits * 10^point. Цифры без
+// начальных и конечных нулей; у нуля digits пуст.
+type decimal struct {
+	neg    bool
+	digits []byte
+	point  int
+}
+
+// parseDecimal разбирает десятичную запись со знаком, точкой и экспонентой
+func parseDecimal(s string) (decimal, bool) {
+	var d decimal
+	if s == "" {
+		return d, false
+	}
+	switch s[0] {
+	case '-':
+		d.neg = true
+		s = s[1:]
+	case '+':
+		s = s[1:]
+	}
+
+	mantissa, exponent := s, 0
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		exp, err := strconv.Atoi(s[i+1:])
+		if err != nil {
+			return d, false
+		}
+		mantissa, exponent = s[:i], exp
+	}
+	intPart, fracPart, _ := strings.Cut(mantissa, ".")
+	if intPart == "" && fracPart == "" {
+		return d, false
+	}
+	for _, r := range intPart + fracPart {
+		if r < '0' || r > '9' {
+			return d, false
+		}
+	}
+
+	digits := strings.TrimLeft(intPart+fracPart, "0")
+	d.point = len(intPart) - (len(intPart+fracPart) - len(digits)) + exponent
+	d.digits = []byte(strings.TrimRight(digits, "0"))
+	if len(d.digits) == 0 {
+		d.point = 0
+	}
+	return d, true
+}
+
+func (d decimal) isZero() bool {
+	return len(d.digits) == 0
+}
+
+// digit возвращает i-ю цифру числа или '0' за пределами записи
+func (d decimal) digit(i int) byte {
+	if i >= 0 && i < len(d.digits) {
+		return d.digits[i]
+	}
+	return '0'
+}
+
+// round оставляет n первых значащих цифр. При n <= 0 отбрасываются все
+// цифры: число округляется до нуля или до единицы разряда 10^(point-n).
+func (d decimal) round(n int, mode Rounding) decimal {
+	if n >= len(d.digits) {
+		return d
+	}
+	if n < 0 {
+		return decimal{}
+	}
+
+	up := false
+	switch mode {
+	case HalfUp:
+		up = d.digits[n] >= '5'
+	case HalfEven:
+		rest := strings.TrimRight(string(d.digits[n+1:]), "0") != ""
+		up = d.digits[n] > '5' || (d.digits[n] == '5' && (rest || (d.digit(n-1)-'0')%2 == 1))
+	}
+
+	r := decimal{neg: d.neg, digits: append([]byte(nil), d.digits[:n]...), point: d.point}
+	if up {
+		i := len(r.digits) - 1
+		for i >= 0 && r.digits[i] == '9' {
+			i--
+		}
+		if i < 0 {
+			// Все оставленные цифры - девятки (или их нет): 9.99 -> 10
+			r.digits = []byte{'1'}
+			r.point++
+		} else {
+			r.digits[i]++
+			r.digits = r.digits[:i+1]
+		}
+	}
+	r.digits = []byte(strings.TrimRight(string(r.digits), "0"))
+	if len(r.digits) == 0 {
+		return decimal{}
+	}
+	return r
+}
+
+// fixed записывает число без экспоненты с frac знаками после точки
+func (d decimal) fixed(frac int) string {
+	var b strings.Builder
+	if d.neg && !d.isZero() {
+		b.WriteByte('-')
+	}
+	if d.point <= 0 {
+		b.WriteByte('0')
+	}
+	for i := 0; i < d.point; i++ {
+		b.WriteByte(d.digit(i))
+	}
+	if frac > 0 {
+		b.WriteByte('.')
+		for i := 0; i < frac; i++ {
+			b.WriteByte(d.digit(d.point + i))
+		}
+	}
+	return b.String()
+}
+
+// exponential записывает число с intDigits цифрами мантиссы до точки,
+// frac после нее и экспонентой в виде strconv: 1.5e+03, 2e-09
+func (d decimal) exponential(frac, intDigits int) string {
+	exp := 0
+	if !d.isZero() {
+		exp = d.point - intDigits
+	}
+	m := decimal{neg: d.neg, digits: d.digits, point: intDigits}
+	if d.isZero() {
+		m.point = 0
+	}
+
+	sign := '+'
+	if exp < 0 {
+		sign, exp = '-', -exp
+	}
+	return fmt.Sprintf("%se%c%02d", m.fixed(frac), sign, exp)
+}
+
+// engIntDigits возвращает число цифр мантиссы до точки в инженерной
+// записи: порядок кратен трем, поэтому мантисса от 1 до 999
+func engIntDigits(d decimal) int {
+	if d.isZero() {
+		return 1
+	}
+	exp := d.point - 1
+	return exp - floorDiv(exp, 3)*3 + 1
+}
+
+// floorDiv делит с округлением вниз: floorDiv(-1, 3) = -1
+func floorDiv(a, b int) int {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
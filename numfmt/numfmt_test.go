@@ -0,0 +1,90 @@
+package numfmt
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFloat(t *testing.T) {
+	tests := []struct {
+		format Format
+		value  float64
+		want   string
+	}{
+		{Default, 0.1 + 0.2, "0.3"},
+		{Default, 1e-9, "1e-09"},
+		{Default, 1500, "1500"},
+		{Default, -2.5e300, "-2.5e+300"},
+		{Default, 0, "0"},
+		{Format{Significant, 3, HalfEven}, 1234, "1.23e+03"},
+		{Format{Significant, 3, HalfUp}, 0.00012345, "0.000123"},
+
+		{Format{Fixed, 2, HalfUp}, 2.675, "2.68"},
+		{Format{Fixed, 2, HalfEven}, 2.675, "2.68"},
+		{Format{Fixed, 2, HalfEven}, 2.665, "2.66"},
+		{Format{Fixed, 2, TowardZero}, 2.679, "2.67"},
+		{Format{Fixed, 2, HalfUp}, -2.675, "-2.68"},
+		{Format{Fixed, 0, HalfEven}, 2.5, "2"},
+		{Format{Fixed, 0, HalfEven}, 3.5, "4"},
+		{Format{Fixed, 0, HalfUp}, 2.5, "3"},
+		{Format{Fixed, 0, HalfEven}, 0.5, "0"},
+		{Format{Fixed, 0, HalfUp}, 0.5, "1"},
+		{Format{Fixed, 2, HalfUp}, 9.999, "10.00"},
+		{Format{Fixed, 6, HalfEven}, 1e-9, "0.000000"},
+		{Format{Fixed, 2, HalfUp}, -0.001, "0.00"},
+		{Format{Fixed, 3, HalfEven}, 1500, "1500.000"},
+
+		{Format{Scientific, 2, HalfEven}, 1234.5, "1.23e+03"},
+		{Format{Scientific, 2, HalfUp}, 9.995, "1.00e+01"},
+		{Format{Scientific, 3, HalfEven}, 1e-9, "1.000e-09"},
+		{Format{Scientific, 1, TowardZero}, -0.0789, "-7.8e-02"},
+		{Format{Scientific, 2, HalfEven}, 0, "0.00e+00"},
+
+		{Format{Engineering, 2, HalfUp}, 12345, "12.35e+03"},
+		{Format{Engineering, 2, HalfEven}, 12345, "12.34e+03"},
+		{Format{Engineering, 1, HalfEven}, 0.00012, "120.0e-06"},
+		{Format{Engineering, 1, HalfUp}, 999.96, "1.0e+03"},
+		{Format{Engineering, 0, HalfEven}, 1e-9, "1e-09"},
+		{Format{Engineering, 0, HalfEven}, 0, "0e+00"},
+
+		{Default, math.Inf(1), "+Inf"},
+		{Format{Fixed, 2, HalfUp}, math.NaN(), "NaN"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, tt.format.Float(tt.value), "%+v %v", tt.format, tt.value)
+	}
+}
+
+func TestDecimal(t *testing.T) {
+	tests := []struct {
+		format Format
+		value  string
+		want   string
+	}{
+		{Format{Fixed, 2, HalfEven}, "0.125", "0.12"},
+		{Format{Fixed, 2, HalfUp}, "0.125", "0.13"},
+		{Format{Fixed, 2, HalfEven}, "0.1250000000001", "0.13"},
+		{Format{Fixed, 4, TowardZero}, "0.33333333333333333333", "0.3333"},
+		{Format{Significant, 5, HalfEven}, "123456789012345678901234567890", "1.2346e+29"},
+		{Format{Scientific, 1, HalfEven}, "-00120.50", "-1.2e+02"},
+		{Format{Fixed, 1, HalfEven}, "1e3", "1000.0"},
+		{Default, "not a number", "not a number"},
+		{Default, "", ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, tt.format.Decimal(tt.value), "%+v %s", tt.format, tt.value)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Default.Validate())
+	assert.NoError(t, Format{Fixed, 0, TowardZero}.Validate())
+
+	assert.Error(t, Format{"roman", 2, HalfEven}.Validate())
+	assert.Error(t, Format{Significant, 0, HalfEven}.Validate())
+	assert.Error(t, Format{Fixed, -1, HalfEven}.Validate())
+	assert.Error(t, Format{Fixed, MaxPrecision + 1, HalfEven}.Validate())
+	assert.Error(t, Format{Fixed, 2, "ceiling"}.Validate())
+}
@@ -0,0 +1,95 @@
+package operations
+
+import "math/big"
+
+func init() {
+	// Порядок регистрации определяет порядок кнопок в интерфейсе
+	Register(Add{})
+	Register(Subtract{})
+	Register(Multiply{})
+	Register(Divide{})
+	Register(Square{})
+}
+
+// Multiply - умножение двух чисел
+type Multiply struct{}
+
+func (Multiply) Name() string                  { return "multiply" }
+func (Multiply) Arity() int                    { return 2 }
+func (Multiply) Validate(_ []float64) error    { return nil }
+func (Multiply) Compute(ops []float64) float64 { return ops[0] * ops[1] }
+func (Multiply) Format(ops []string) string    { return ops[0] + " * " + ops[1] }
+func (Multiply) ComputeExact(ops []*big.Rat) (*big.Rat, error) {
+	return new(big.Rat).Mul(ops[0], ops[1]), nil
+}
+func (Multiply) Labels() Labels {
+	return Labels{Title: "Умножение", Button: "Умножить", Filter: "Только умножение"}
+}
+
+// Divide - деление первого числа на второе
+type Divide struct{}
+
+func (Divide) Name() string { return "divide" }
+func (Divide) Arity() int   { return 2 }
+func (Divide) Validate(ops []float64) error {
+	if ops[1] == 0 {
+		return ErrDivisionByZero
+	}
+	return nil
+}
+func (Divide) Compute(ops []float64) float64 { return ops[0] / ops[1] }
+func (Divide) Format(ops []string) string    { return ops[0] + " / " + ops[1] }
+func (Divide) ComputeExact(ops []*big.Rat) (*big.Rat, error) {
+	if ops[1].Sign() == 0 {
+		return nil, ErrDivisionByZero
+	}
+	return new(big.Rat).Quo(ops[0], ops[1]), nil
+}
+func (Divide) Labels() Labels {
+	return Labels{Title: "Деление", Button: "Разделить", Filter: "Только деление"}
+}
+
+// Add - сложение двух чисел
+type Add struct{}
+
+func (Add) Name() string                  { return "add" }
+func (Add) Arity() int                    { return 2 }
+func (Add) Validate(_ []float64) error    { return nil }
+func (Add) Compute(ops []float64) float64 { return ops[0] + ops[1] }
+func (Add) Format(ops []string) string    { return ops[0] + " + " + ops[1] }
+func (Add) ComputeExact(ops []*big.Rat) (*big.Rat, error) {
+	return new(big.Rat).Add(ops[0], ops[1]), nil
+}
+func (Add) Labels() Labels {
+	return Labels{Title: "Сложение", Button: "Сложить", Filter: "Только сложение"}
+}
+
+// Subtract - вычитание второго числа из первого
+type Subtract struct{}
+
+func (Subtract) Name() string                  { return "subtract" }
+func (Subtract) Arity() int                    { return 2 }
+func (Subtract) Validate(_ []float64) error    { return nil }
+func (Subtract) Compute(ops []float64) float64 { return ops[0] - ops[1] }
+func (Subtract) Format(ops []string) string    { return ops[0] + " - " + ops[1] }
+func (Subtract) ComputeExact(ops []*big.Rat) (*big.Rat, error) {
+	return new(big.Rat).Sub(ops[0], ops[1]), nil
+}
+func (Subtract) Labels() Labels {
+	return Labels{Title: "Вычитание", Button: "Вычесть", Filter: "Только вычитание"}
+}
+
+// Square - возведение числа в квадрат
+type Square struct{}
+
+func (Square) Name() string                  { return "square" }
+func (Square) Arity() int                    { return 1 }
+func (Square) Validate(_ []float64) error    { return nil }
+func (Square) Compute(ops []float64) float64 { return ops[0] * ops[0] }
+func (Square) Format(ops []string) string    { return ops[0] + "²" }
+func (Square) ComputeExact(ops []*big.Rat) (*big.Rat, error) {
+	return new(big.Rat).Mul(ops[0], ops[0]), nil
+}
+func (Square) Labels() Labels {
+	return Labels{Title: "Возведение в квадрат", Button: "Квадрат", Filter: "Только возведение в квадрат"}
+}
@@ -0,0 +1,89 @@
+package operations
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+)
+
+// ExactOperation - операция, поддерживающая точные вычисления на big.Rat.
+// Операции без этой реализации доступны только в режиме float64.
+type ExactOperation interface {
+	Operation
+	// ComputeExact проверяет операнды и вычисляет точный результат
+	ComputeExact(operands []*big.Rat) (*big.Rat, error)
+}
+
+// MaxFractionDigits - число знаков после запятой для непериодических
+// представлений бесконечных дробей (например, 1/3)
+const MaxFractionDigits = 50
+
+// ErrExactNotSupported возвращается, если операция не поддерживает точный режим
+var ErrExactNotSupported = &ValidationError{
+	Code:    "exact_not_supported",
+	Message: "Операция не поддерживает точный режим вычислений",
+	Operand: NoOperand,
+}
+
+var errInvalidDecimal = errors.New("неверный формат десятичного числа")
+
+// ParseExact разбирает десятичное число (допускается экспонента) без потери точности
+func ParseExact(s string) (*big.Rat, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || strings.Contains(s, "/") {
+		return nil, errInvalidDecimal
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return nil, errInvalidDecimal
+	}
+	return r, nil
+}
+
+// FormatExact возвращает десятичную запись числа. Конечные дроби
+// записываются точно, бесконечные округляются до MaxFractionDigits знаков.
+func FormatExact(r *big.Rat) string {
+	if r.IsInt() {
+		return r.Num().String()
+	}
+
+	// Дробь конечна, если знаменатель имеет вид 2^a * 5^b;
+	// тогда для точной записи нужно max(a, b) знаков после запятой
+	denom := new(big.Int).Set(r.Denom())
+	twos := removeFactor(denom, 2)
+	fives := removeFactor(denom, 5)
+	if denom.Cmp(big.NewInt(1)) == 0 {
+		return r.FloatString(max(twos, fives))
+	}
+
+	s := r.FloatString(MaxFractionDigits)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// removeFactor делит n на factor, пока делится, и возвращает число делений
+func removeFactor(n *big.Int, factor int64) int {
+	f := big.NewInt(factor)
+	q, m := new(big.Int), new(big.Int)
+	count := 0
+	for {
+		q.QuoRem(n, f, m)
+		if m.Sign() != 0 {
+			return count
+		}
+		n.Set(q)
+		count++
+	}
+}
+
+// RunExact вычисляет результат операции в точном режиме
+func RunExact(op Operation, operands []*big.Rat) (*big.Rat, error) {
+	exact, ok := op.(ExactOperation)
+	if !ok {
+		return nil, ErrExactNotSupported
+	}
+	if len(operands) != op.Arity() {
+		return nil, arityError(op, len(operands))
+	}
+	return exact.ComputeExact(operands)
+}
@@ -0,0 +1,62 @@
+package operations
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mustParse(t *testing.T, s string) *big.Rat {
+	t.Helper()
+	r, err := ParseExact(s)
+	require.NoError(t, err)
+	return r
+}
+
+func TestRunExact(t *testing.T) {
+	tests := []struct {
+		op   Operation
+		a, b string
+		want string
+	}{
+		{Add{}, "0.1", "0.2", "0.3"},
+		{Subtract{}, "1", "0.9", "0.1"},
+		{Multiply{}, "123456789012345678901234567890", "987654321098765432109876543210", "121932631137021795226185032733622923332237463801111263526900"},
+		{Multiply{}, "1.5e3", "2", "3000"},
+		{Divide{}, "1", "8", "0.125"},
+		{Divide{}, "1", "3", "0.33333333333333333333333333333333333333333333333333"},
+		{Divide{}, "-2", "3", "-0.66666666666666666666666666666666666666666666666667"},
+	}
+
+	for _, tt := range tests {
+		got, err := RunExact(tt.op, []*big.Rat{mustParse(t, tt.a), mustParse(t, tt.b)})
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, FormatExact(got), "%s(%s, %s)", tt.op.Name(), tt.a, tt.b)
+	}
+}
+
+func TestRunExactSquare(t *testing.T) {
+	got, err := RunExact(Square{}, []*big.Rat{mustParse(t, "0.1")})
+	require.NoError(t, err)
+	assert.Equal(t, "0.01", FormatExact(got))
+}
+
+func TestRunExactDivisionByZero(t *testing.T) {
+	_, err := RunExact(Divide{}, []*big.Rat{mustParse(t, "1"), mustParse(t, "0.000")})
+	assert.True(t, errors.Is(err, ErrDivisionByZero))
+}
+
+func TestRunExactNotSupported(t *testing.T) {
+	_, err := RunExact(cube{}, []*big.Rat{mustParse(t, "2")})
+	assert.True(t, errors.Is(err, ErrExactNotSupported))
+}
+
+func TestParseExactRejectsInvalid(t *testing.T) {
+	for _, s := range []string{"", "abc", "1/3", "1..2"} {
+		_, err := ParseExact(s)
+		assert.Error(t, err, s)
+	}
+}
@@ -0,0 +1,194 @@
+package operations
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Коды ошибок числового диапазона. Коды совпадают с классом числа,
+// который записывается в журнал операций.
+const (
+	// CodeNonFiniteInput - операнд равен NaN или бесконечности
+	CodeNonFiniteInput = "non_finite_input"
+	// CodeOverflow - число по модулю больше максимального float64
+	CodeOverflow = "overflow"
+	// CodeUnderflow - ненулевое число по модулю меньше минимального float64
+	// и округляется до нуля
+	CodeUnderflow = "underflow"
+	// CodeNonFiniteResult - операция над конечными числами дала NaN
+	CodeNonFiniteResult = "non_finite_result"
+)
+
+// ClassFinite - класс конечного числа в допустимом диапазоне
+const ClassFinite = "finite"
+
+// NoOperand - значение ValidationError.Operand для ошибок, не связанных
+// с операндом: ошибок результата и чисел в записи выражения
+const NoOperand = -1
+
+// NonFiniteInputError сообщает, что операнд operand равен NaN или бесконечности
+func NonFiniteInputError(operand int) *ValidationError {
+	return &ValidationError{
+		Code:    CodeNonFiniteInput,
+		Message: "Число должно быть конечным: NaN и бесконечность не допускаются",
+		Operand: operand,
+	}
+}
+
+// OverflowError сообщает, что операнд operand не помещается в float64
+func OverflowError(operand int) *ValidationError {
+	return &ValidationError{
+		Code:    CodeOverflow,
+		Message: "Число слишком велико: допустимы значения до ±1.8e308",
+		Operand: operand,
+	}
+}
+
+// UnderflowError сообщает, что ненулевой операнд operand слишком мал
+// по модулю и округляется до нуля
+func UnderflowError(operand int) *ValidationError {
+	return &ValidationError{
+		Code:    CodeUnderflow,
+		Message: "Число слишком мало по модулю и округляется до нуля",
+		Operand: operand,
+	}
+}
+
+// Ошибки числового диапазона результата
+var (
+	ErrResultOverflow = &ValidationError{
+		Code:    CodeOverflow,
+		Message: "Результат слишком велик: выходит за пределы ±1.8e308",
+		Operand: NoOperand,
+	}
+	ErrResultUnderflow = &ValidationError{
+		Code:    CodeUnderflow,
+		Message: "Результат слишком мал по модулю и округляется до нуля",
+		Operand: NoOperand,
+	}
+	ErrNonFiniteResult = &ValidationError{
+		Code:    CodeNonFiniteResult,
+		Message: "Результат не является конечным числом",
+		Operand: NoOperand,
+	}
+)
+
+// NumericClass возвращает класс числа из ошибки err: код ошибки числового
+// диапазона или пустую строку для остальных ошибок
+func NumericClass(err error) string {
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) && IsNumericCode(validationErr.Code) {
+		return validationErr.Code
+	}
+	return ""
+}
+
+// IsNumericCode сообщает, что code - код ошибки числового диапазона
+func IsNumericCode(code string) bool {
+	switch code {
+	case CodeNonFiniteInput, CodeOverflow, CodeUnderflow, CodeNonFiniteResult:
+		return true
+	}
+	return false
+}
+
+// ParseFloat разбирает операнд с индексом operand в float64. "NaN", "Inf"
+// и числа вне диапазона float64 (например, "1e400" или "1e-400")
+// отклоняются ошибкой *ValidationError; ошибка формата числа
+// возвращается как *strconv.NumError.
+func ParseFloat(s string, operand int) (float64, error) {
+	value, err := strconv.ParseFloat(s, 64)
+	if errors.Is(err, strconv.ErrRange) {
+		return 0, OverflowError(operand)
+	}
+	if err != nil {
+		return 0, err
+	}
+	// strconv.ParseFloat без ошибки округляет до нуля слишком малые числа
+	if value == 0 && hasNonZeroMantissa(s) {
+		return 0, UnderflowError(operand)
+	}
+	if err := CheckOperand(value, operand); err != nil {
+		return 0, err
+	}
+	return value, nil
+}
+
+// hasNonZeroMantissa сообщает, что в записи числа до экспоненты есть
+// ненулевая цифра. Запись уже проверена strconv.ParseFloat.
+func hasNonZeroMantissa(s string) bool {
+	s = strings.ToLower(strings.TrimLeft(s, "+-"))
+	digits, exponent := "123456789", "e"
+	if strings.HasPrefix(s, "0x") {
+		s = s[2:]
+		digits, exponent = "123456789abcdef", "p"
+	}
+	mantissa, _, _ := strings.Cut(s, exponent)
+	return strings.ContainsAny(mantissa, digits)
+}
+
+// CheckOperand отклоняет операнд, равный NaN или бесконечности
+func CheckOperand(value float64, operand int) error {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return NonFiniteInputError(operand)
+	}
+	return nil
+}
+
+// ExactFloat64 возвращает ближайшее к точному операнду operand значение
+// float64. Числа, которые не помещаются в float64 или округляются до нуля,
+// отклоняются как переполнение и потеря значимости.
+func ExactFloat64(r *big.Rat, operand int) (float64, error) {
+	value, err := ExactResultFloat64(r)
+	switch {
+	case errors.Is(err, ErrResultOverflow):
+		return 0, OverflowError(operand)
+	case errors.Is(err, ErrResultUnderflow):
+		return 0, UnderflowError(operand)
+	}
+	return value, nil
+}
+
+// ExactResultFloat64 возвращает ближайшее к точному результату значение
+// float64 или ошибку ErrResultOverflow, ErrResultUnderflow
+func ExactResultFloat64(r *big.Rat) (float64, error) {
+	value, _ := r.Float64()
+	switch {
+	case math.IsInf(value, 0):
+		return 0, ErrResultOverflow
+	case value == 0 && r.Sign() != 0:
+		return 0, ErrResultUnderflow
+	}
+	return value, nil
+}
+
+// checkResult классифицирует результат операции над конечными операндами:
+// бесконечность означает переполнение, NaN - неопределенный результат.
+// Ноль проверяется точным вычислением, если операция его поддерживает:
+// ненулевой точный результат означает потерю значимости.
+func checkResult(op Operation, operands []float64, value float64) error {
+	switch {
+	case math.IsNaN(value):
+		return ErrNonFiniteResult
+	case math.IsInf(value, 0):
+		return ErrResultOverflow
+	case value != 0:
+		return nil
+	}
+
+	exact, ok := op.(ExactOperation)
+	if !ok {
+		return nil
+	}
+	rats := make([]*big.Rat, len(operands))
+	for i, v := range operands {
+		rats[i] = new(big.Rat).SetFloat64(v)
+	}
+	if r, err := exact.ComputeExact(rats); err == nil && r.Sign() != 0 {
+		return ErrResultUnderflow
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package operations
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFloat(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+		code string
+	}{
+		{"12.5", 12.5, ""},
+		{"-1e308", -1e308, ""},
+		{"5e-324", 5e-324, ""},
+		{"NaN", 0, CodeNonFiniteInput},
+		{"-Inf", 0, CodeNonFiniteInput},
+		{"infinity", 0, CodeNonFiniteInput},
+		{"1e400", 0, CodeOverflow},
+		{"-1e400", 0, CodeOverflow},
+		{"1e-400", 0, CodeUnderflow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseFloat(tt.in, 1)
+			if tt.code == "" {
+				require.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+				return
+			}
+			var validationErr *ValidationError
+			require.ErrorAs(t, err, &validationErr)
+			assert.Equal(t, tt.code, validationErr.Code)
+			assert.Equal(t, 1, validationErr.Operand)
+			assert.Equal(t, tt.code, NumericClass(err))
+		})
+	}
+
+	_, err := ParseFloat("abc", 0)
+	var numErr *strconv.NumError
+	assert.ErrorAs(t, err, &numErr)
+	assert.Empty(t, NumericClass(err))
+}
+
+func TestRunNumericRange(t *testing.T) {
+	tests := []struct {
+		name     string
+		op       Operation
+		operands []float64
+		want     error
+	}{
+		{"overflow", Multiply{}, []float64{1e200, 1e200}, ErrResultOverflow},
+		{"underflow", Multiply{}, []float64{1e-200, 1e-200}, ErrResultUnderflow},
+		{"square underflow", Square{}, []float64{1e-200}, ErrResultUnderflow},
+		{"exact zero", Subtract{}, []float64{1e-320, 1e-320}, nil},
+		{"infinite operand", Add{}, []float64{math.Inf(1), 1}, NonFiniteInputError(0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Run(tt.op, tt.operands)
+			if tt.want == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.Equal(t, tt.want, err)
+		})
+	}
+}
+
+func TestExactFloat64(t *testing.T) {
+	huge, _ := new(big.Rat).SetString("1e400")
+	tiny, _ := new(big.Rat).SetString("1e-400")
+
+	_, err := ExactFloat64(huge, 0)
+	assert.Equal(t, OverflowError(0), err)
+	_, err = ExactFloat64(tiny, 1)
+	assert.Equal(t, UnderflowError(1), err)
+
+	_, err = ExactResultFloat64(huge)
+	assert.True(t, errors.Is(err, ErrResultOverflow))
+
+	value, err := ExactFloat64(big.NewRat(1, 4), 0)
+	require.NoError(t, err)
+	assert.Equal(t, 0.25, value)
+}
@@ -0,0 +1,46 @@
+package operations
+
+// Operation описывает математическую операцию над фиксированным числом операндов.
+// Чтобы добавить новую операцию, достаточно реализовать этот интерфейс
+// и зарегистрировать тип в реестре: маршруты и элементы интерфейса
+// создаются автоматически.
+type Operation interface {
+	// Name - идентификатор операции: часть URL и значение поля operation в БД
+	Name() string
+	// Arity - количество операндов
+	Arity() int
+	// Validate проверяет операнды перед вычислением
+	Validate(operands []float64) error
+	// Compute вычисляет результат над уже проверенными операндами
+	Compute(operands []float64) float64
+	// Format возвращает текстовую запись операции для журнала по уже
+	// записанным операндам, например "5 * 3"
+	Format(operands []string) string
+	// Labels возвращает подписи для пользовательского интерфейса
+	Labels() Labels
+}
+
+// Labels содержит подписи операции для пользовательского интерфейса
+type Labels struct {
+	Title  string // название в таблице результатов, например "Умножение"
+	Button string // надпись на кнопке, например "Умножить"
+	Filter string // пункт фильтра, например "Только умножение"
+}
+
+// ValidationError - ошибка проверки операндов со стабильным кодом
+type ValidationError struct {
+	Code    string
+	Message string
+	Operand int // индекс операнда, вызвавшего ошибку, или -1, если ошибка не связана с операндом
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+// ErrDivisionByZero возвращается при попытке деления на ноль
+var ErrDivisionByZero = &ValidationError{
+	Code:    "division_by_zero",
+	Message: "Деление на ноль невозможно",
+	Operand: 1,
+}
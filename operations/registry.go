@@ -0,0 +1,106 @@
+package operations
+
+import (
+	"fmt"
+	"sync"
+)
+
+// MaxArity - максимальное число операндов операции. Хранилища, JSON API
+// и пакетные вычисления принимают любое число операндов, но форма главной
+// страницы и таблица истории показывают два операнда, а выгрузка CSV
+// отводит операндам столбцы number1..numberN по MaxArity. Операцию
+// с большим числом операндов нельзя было бы вызвать из формы.
+const MaxArity = 2
+
+// Registry хранит зарегистрированные операции в порядке регистрации
+type Registry struct {
+	mu    sync.RWMutex
+	byKey map[string]Operation
+	order []Operation
+}
+
+// NewRegistry создает пустой реестр операций
+func NewRegistry() *Registry {
+	return &Registry{byKey: make(map[string]Operation)}
+}
+
+// Register добавляет операцию в реестр
+func (r *Registry) Register(op Operation) error {
+	if op.Name() == "" {
+		return fmt.Errorf("операция без имени")
+	}
+	if op.Arity() < 1 || op.Arity() > MaxArity {
+		return fmt.Errorf("операция %q: недопустимое число операндов %d", op.Name(), op.Arity())
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.byKey[op.Name()]; exists {
+		return fmt.Errorf("операция %q уже зарегистрирована", op.Name())
+	}
+	r.byKey[op.Name()] = op
+	r.order = append(r.order, op)
+	return nil
+}
+
+// MustRegister добавляет операцию и паникует при ошибке
+func (r *Registry) MustRegister(op Operation) {
+	if err := r.Register(op); err != nil {
+		panic(err)
+	}
+}
+
+// Get возвращает операцию по имени
+func (r *Registry) Get(name string) (Operation, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	op, ok := r.byKey[name]
+	return op, ok
+}
+
+// All возвращает все операции в порядке регистрации
+func (r *Registry) All() []Operation {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ops := make([]Operation, len(r.order))
+	copy(ops, r.order)
+	return ops
+}
+
+// Default - реестр, используемый приложением
+var Default = NewRegistry()
+
+// Register добавляет операцию в реестр по умолчанию
+func Register(op Operation) {
+	Default.MustRegister(op)
+}
+
+// Run проверяет операнды и вычисляет результат операции. NaN и бесконечность
+// в операндах, а также переполнение и потеря значимости результата
+// возвращаются как *ValidationError.
+func Run(op Operation, operands []float64) (float64, error) {
+	if len(operands) != op.Arity() {
+		return 0, arityError(op, len(operands))
+	}
+	for i, v := range operands {
+		if err := CheckOperand(v, i); err != nil {
+			return 0, err
+		}
+	}
+	if err := op.Validate(operands); err != nil {
+		return 0, err
+	}
+	value := op.Compute(operands)
+	if err := checkResult(op, operands, value); err != nil {
+		return 0, err
+	}
+	return value, nil
+}
+
+// arityError сообщает о несовпадении числа операндов
+func arityError(op Operation, got int) error {
+	return fmt.Errorf("операция %q ожидает %d операнд(а), получено %d", op.Name(), op.Arity(), got)
+}
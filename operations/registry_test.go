@@ -0,0 +1,79 @@
+package operations
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// cube - тестовая операция для проверки регистрации новых типов
+type cube struct{}
+
+func (cube) Name() string                  { return "cube" }
+func (cube) Arity() int                    { return 1 }
+func (cube) Validate(_ []float64) error    { return nil }
+func (cube) Compute(ops []float64) float64 { return ops[0] * ops[0] * ops[0] }
+func (cube) Format(ops []string) string    { return ops[0] + "³" }
+func (cube) Labels() Labels {
+	return Labels{Title: "Куб", Button: "Куб", Filter: "Только куб"}
+}
+
+func TestDefaultRegistryOrder(t *testing.T) {
+	var names []string
+	for _, op := range Default.All() {
+		names = append(names, op.Name())
+	}
+
+	assert.Equal(t, []string{"add", "subtract", "multiply", "divide", "square"}, names)
+}
+
+func TestRegistryRegister(t *testing.T) {
+	r := NewRegistry()
+	require.NoError(t, r.Register(cube{}))
+
+	op, ok := r.Get("cube")
+	require.True(t, ok)
+	assert.Equal(t, 1, op.Arity())
+
+	// Повторная регистрация запрещена
+	assert.Error(t, r.Register(cube{}))
+
+	_, ok = r.Get("unknown")
+	assert.False(t, ok)
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		op       Operation
+		operands []float64
+		want     float64
+	}{
+		{Add{}, []float64{10.5, 5}, 15.5},
+		{Subtract{}, []float64{10, 3}, 7},
+		{Multiply{}, []float64{10, 5}, 50},
+		{Divide{}, []float64{10, 4}, 2.5},
+		{Square{}, []float64{-3}, 9},
+	}
+
+	for _, tt := range tests {
+		got, err := Run(tt.op, tt.operands)
+		require.NoError(t, err, tt.op.Name())
+		assert.Equal(t, tt.want, got, tt.op.Name())
+	}
+}
+
+func TestRunDivisionByZero(t *testing.T) {
+	_, err := Run(Divide{}, []float64{10, 0})
+
+	var validationErr *ValidationError
+	require.True(t, errors.As(err, &validationErr))
+	assert.Equal(t, "division_by_zero", validationErr.Code)
+	assert.Equal(t, 1, validationErr.Operand)
+}
+
+func TestRunWrongArity(t *testing.T) {
+	_, err := Run(Multiply{}, []float64{1})
+	assert.Error(t, err)
+}
@@ -0,0 +1,72 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval - как часто MemoryStore удаляет заполненные корзины
+const sweepInterval = time.Minute
+
+// MemoryStore хранит корзины в памяти процесса. Лимит действует
+// отдельно в каждой реплике приложения.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// bucket - состояние корзины
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	// full - момент, когда корзина заполнится и ее можно удалить
+	full time.Time
+}
+
+// NewMemoryStore создает пустое хранилище корзин
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*bucket)}
+}
+
+// Take пытается взять токен из корзины key
+func (s *MemoryStore) Take(_ context.Context, key string, limit Limit, now time.Time) (Decision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Requests), updated: now}
+		s.buckets[key] = b
+	}
+
+	var decision Decision
+	decision, b.tokens = decide(refill(b.tokens, now.Sub(b.updated), limit), limit)
+	b.updated = now
+	b.full = now.Add(time.Duration((float64(limit.Requests) - b.tokens) / limit.rate() * float64(time.Second)))
+	return decision, nil
+}
+
+// sweep удаляет заполненные корзины: новая корзина будет такой же,
+// поэтому память занимают только клиенты, недавно выполнявшие запросы
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for key, b := range s.buckets {
+		if !now.Before(b.full) {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+// Len возвращает число хранимых корзин (используется в тестах)
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.buckets)
+}
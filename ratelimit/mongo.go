@@ -0,0 +1,70 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoStore хранит корзины в коллекции MongoDB, поэтому лимит общий
+// для всех реплик приложения. Корзина - документ с ключом _id; токен
+// берется одним атомарным findOneAndUpdate с конвейером агрегации.
+// Заполненные корзины удаляет TTL индекс по expires_at.
+type MongoStore struct {
+	coll *mongo.Collection
+}
+
+// NewMongoStore создает хранилище корзин в коллекции coll
+func NewMongoStore(coll *mongo.Collection) *MongoStore {
+	return &MongoStore{coll: coll}
+}
+
+// mongoBucket - состояние корзины после обновления
+type mongoBucket struct {
+	Tokens  float64 `bson:"tokens"`
+	Allowed bool    `bson:"allowed"`
+}
+
+// Take пытается взять токен из корзины key
+func (s *MongoStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (Decision, error) {
+	burst := float64(limit.Requests)
+	elapsedSeconds := bson.M{"$divide": bson.A{
+		bson.M{"$max": bson.A{0, bson.M{"$subtract": bson.A{now, bson.M{"$ifNull": bson.A{"$updated_at", now}}}}}},
+		1000,
+	}}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"tokens": bson.M{"$min": bson.A{burst, bson.M{"$add": bson.A{
+				bson.M{"$ifNull": bson.A{"$tokens", burst}},
+				bson.M{"$multiply": bson.A{elapsedSeconds, limit.rate()}},
+			}}}},
+			"updated_at": now,
+		}}},
+		{{Key: "$set", Value: bson.M{"allowed": bson.M{"$gte": bson.A{"$tokens", 1}}}}},
+		{{Key: "$set", Value: bson.M{
+			"tokens":     bson.M{"$cond": bson.A{"$allowed", bson.M{"$subtract": bson.A{"$tokens", 1}}, "$tokens"}},
+			"expires_at": now.Add(limit.Per),
+		}}},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var b mongoBucket
+	err := s.coll.FindOneAndUpdate(ctx, bson.M{"_id": key}, pipeline, opts).Decode(&b)
+	if mongo.IsDuplicateKeyError(err) {
+		// Корзину одновременно создал другой запрос - повторяем как обновление
+		err = s.coll.FindOneAndUpdate(ctx, bson.M{"_id": key}, pipeline, opts).Decode(&b)
+	}
+	if err != nil {
+		return Decision{}, err
+	}
+
+	if b.Allowed {
+		return Decision{Allowed: true, Remaining: int(b.Tokens)}, nil
+	}
+	decision, _ := decide(b.Tokens, limit)
+	return decision, nil
+}
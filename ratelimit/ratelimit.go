@@ -0,0 +1,136 @@
+// Package ratelimit ограничивает частоту запросов алгоритмом token bucket:
+// у каждого клиента на каждом маршруте есть корзина на Requests запросов,
+// которая полностью пополняется за Per. Состояние корзин хранится в памяти
+// процесса (MemoryStore) или в MongoDB (MongoStore) - тогда лимит общий
+// для всех реплик приложения.
+package ratelimit
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Limit - лимит запросов: не больше Requests запросов подряд,
+// корзина пополняется равномерно и заполняется целиком за Per.
+// Нулевое значение Requests означает отсутствие лимита.
+type Limit struct {
+	Requests int
+	Per      time.Duration
+}
+
+// Unlimited сообщает, что лимит не задан
+func (l Limit) Unlimited() bool {
+	return l.Requests <= 0 || l.Per <= 0
+}
+
+// rate возвращает скорость пополнения корзины в запросах в секунду
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Per.Seconds()
+}
+
+// Decision - результат попытки выполнить запрос
+type Decision struct {
+	// Allowed - запрос разрешен, из корзины взят один токен
+	Allowed bool
+	// Remaining - число запросов, которые можно выполнить сразу
+	Remaining int
+	// RetryAfter - через сколько в корзине появится токен (если запрос отклонен)
+	RetryAfter time.Duration
+}
+
+// Store хранит состояние корзин
+type Store interface {
+	// Take пытается взять токен из корзины key с лимитом limit в момент now
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (Decision, error)
+}
+
+// refill возвращает число токенов в корзине через elapsed после того,
+// как в ней было tokens токенов
+func refill(tokens float64, elapsed time.Duration, limit Limit) float64 {
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	return math.Min(float64(limit.Requests), tokens+elapsed.Seconds()*limit.rate())
+}
+
+// decide берет токен из корзины, если он есть, и возвращает решение
+// и оставшееся число токенов
+func decide(tokens float64, limit Limit) (Decision, float64) {
+	if tokens >= 1 {
+		tokens--
+		return Decision{Allowed: true, Remaining: int(tokens)}, tokens
+	}
+	wait := time.Duration((1 - tokens) / limit.rate() * float64(time.Second))
+	return Decision{RetryAfter: wait}, tokens
+}
+
+// Limiter применяет лимиты к маршрутам Gin
+type Limiter struct {
+	store  Store
+	def    Limit
+	routes map[string]Limit
+	now    func() time.Time
+}
+
+// New создает ограничитель с лимитом def и лимитами отдельных маршрутов
+// routes (ключ - шаблон маршрута Gin, например /api/v1/results/:id)
+func New(store Store, def Limit, routes map[string]Limit) *Limiter {
+	return &Limiter{store: store, def: def, routes: routes, now: time.Now}
+}
+
+// limit возвращает лимит маршрута route
+func (l *Limiter) limit(route string) Limit {
+	if limit, ok := l.routes[route]; ok {
+		return limit
+	}
+	return l.def
+}
+
+// Middleware ограничивает запросы клиента, определяемого функцией client
+// (например, IP-адрес или пользователь), к текущему маршруту. Отклоненный
+// запрос получает заголовок Retry-After и передается в reject, который
+// должен ответить 429 и прервать обработку. Если хранилище лимитов
+// недоступно, запрос пропускается: ограничение частоты не должно
+// останавливать приложение.
+func (l *Limiter) Middleware(client func(*gin.Context) string, reject func(*gin.Context, time.Duration)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		limit := l.limit(route)
+		if limit.Unlimited() {
+			c.Next()
+			return
+		}
+
+		key := c.Request.Method + " " + route + " " + client(c)
+		decision, err := l.store.Take(c.Request.Context(), key, limit, l.now())
+		if err != nil {
+			slog.ErrorContext(c.Request.Context(), "check rate limit", "error", err, "route", route)
+			c.Next()
+			return
+		}
+
+		c.Header("X-RateLimit-Limit", strconv.Itoa(limit.Requests))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+		if !decision.Allowed {
+			c.Header("Retry-After", strconv.Itoa(RetryAfterSeconds(decision.RetryAfter)))
+			reject(c, decision.RetryAfter)
+			if !c.IsAborted() {
+				c.AbortWithStatus(http.StatusTooManyRequests)
+			}
+			return
+		}
+		c.Next()
+	}
+}
+
+// RetryAfterSeconds округляет ожидание вверх до целых секунд (не меньше 1)
+// для заголовка Retry-After
+func RetryAfterSeconds(d time.Duration) int {
+	return max(1, int(math.Ceil(d.Seconds())))
+}
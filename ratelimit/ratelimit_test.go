@@ -0,0 +1,123 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStoreRefill(t *testing.T) {
+	s := NewMemoryStore()
+	limit := Limit{Requests: 2, Per: 2 * time.Second}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i, remaining := range []int{1, 0} {
+		d, err := s.Take(context.Background(), "k", limit, now)
+		require.NoError(t, err)
+		assert.True(t, d.Allowed, i)
+		assert.Equal(t, remaining, d.Remaining, i)
+	}
+
+	d, err := s.Take(context.Background(), "k", limit, now.Add(250*time.Millisecond))
+	require.NoError(t, err)
+	assert.False(t, d.Allowed)
+	assert.Equal(t, 750*time.Millisecond, d.RetryAfter)
+
+	// Другой ключ не зависит от исчерпанной корзины
+	d, err = s.Take(context.Background(), "other", limit, now)
+	require.NoError(t, err)
+	assert.True(t, d.Allowed)
+
+	// За секунду корзина пополняется на один токен
+	d, err = s.Take(context.Background(), "k", limit, now.Add(time.Second))
+	require.NoError(t, err)
+	assert.True(t, d.Allowed)
+	assert.Equal(t, 0, d.Remaining)
+}
+
+func TestMemoryStoreSweep(t *testing.T) {
+	s := NewMemoryStore()
+	limit := Limit{Requests: 5, Per: time.Second}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	_, err := s.Take(context.Background(), "a", limit, now)
+	require.NoError(t, err)
+	_, err = s.Take(context.Background(), "b", limit, now)
+	require.NoError(t, err)
+	assert.Equal(t, 2, s.Len())
+
+	// Через sweepInterval обе корзины заполнены и удаляются
+	_, err = s.Take(context.Background(), "c", limit, now.Add(sweepInterval))
+	require.NoError(t, err)
+	assert.Equal(t, 1, s.Len())
+}
+
+func TestRetryAfterSeconds(t *testing.T) {
+	assert.Equal(t, 1, RetryAfterSeconds(0))
+	assert.Equal(t, 1, RetryAfterSeconds(300*time.Millisecond))
+	assert.Equal(t, 2, RetryAfterSeconds(1100*time.Millisecond))
+}
+
+// failingStore - хранилище, недоступное для проверки лимита
+type failingStore struct{}
+
+func (failingStore) Take(context.Context, string, Limit, time.Time) (Decision, error) {
+	return Decision{}, errors.New("unavailable")
+}
+
+func newTestRouter(l *Limiter) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	client := func(c *gin.Context) string { return c.GetHeader("X-Client") }
+	reject := func(c *gin.Context, retryAfter time.Duration) {
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"retry_after": RetryAfterSeconds(retryAfter)})
+	}
+	router.Use(l.Middleware(client, reject))
+	router.GET("/limited", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/open", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return router
+}
+
+func request(router *gin.Engine, path, client string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("X-Client", client)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestMiddleware(t *testing.T) {
+	l := New(NewMemoryStore(), Limit{Requests: 1, Per: time.Minute}, map[string]Limit{"/open": {}})
+	router := newTestRouter(l)
+
+	w := request(router, "/limited", "alice")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "1", w.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "0", w.Header().Get("X-RateLimit-Remaining"))
+
+	w = request(router, "/limited", "alice")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "60", w.Header().Get("Retry-After"))
+	assert.JSONEq(t, `{"retry_after": 60}`, w.Body.String())
+
+	// Лимит у каждого клиента свой, маршрут без лимита не ограничен
+	assert.Equal(t, http.StatusOK, request(router, "/limited", "bob").Code)
+	for range 3 {
+		assert.Equal(t, http.StatusOK, request(router, "/open", "alice").Code)
+	}
+}
+
+func TestMiddlewareFailsOpen(t *testing.T) {
+	router := newTestRouter(New(failingStore{}, Limit{Requests: 1, Per: time.Minute}, nil))
+
+	for range 3 {
+		assert.Equal(t, http.StatusOK, request(router, "/limited", "alice").Code)
+	}
+}
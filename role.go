@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/igor-fedko/go_multiply_app/models"
+	"github.com/igor-fedko/go_multiply_app/storage"
+)
+
+// roleUsage - справка по подкоманде role
+const roleUsage = "использование: role <имя пользователя> admin|user"
+
+// runRoleCommand выполняет подкоманду role: назначает пользователю роль
+// администратора (admin) или обычного пользователя (user)
+func runRoleCommand(ctx context.Context, s storage.UserStore, args []string, out io.Writer) error {
+	if len(args) != 2 || !models.ValidRole(args[1]) {
+		return errors.New(roleUsage)
+	}
+
+	username, role := args[0], args[1]
+	if err := s.SetUserRole(ctx, username, role); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return fmt.Errorf("пользователь %s не найден", username)
+		}
+		return err
+	}
+
+	_, err := fmt.Fprintf(out, "пользователю %s назначена роль %s\n", username, role)
+	return err
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/igor-fedko/go_multiply_app/models"
+	"github.com/igor-fedko/go_multiply_app/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRoleCommand тестирует подкоманду role
+func TestRoleCommand(t *testing.T) {
+	ctx := context.Background()
+	s := memory.New()
+	require.NoError(t, s.CreateUser(ctx, &models.User{Username: "alice", Role: models.RoleUser}))
+
+	var out bytes.Buffer
+	require.NoError(t, runRoleCommand(ctx, s, []string{"alice", "admin"}, &out))
+	assert.Contains(t, out.String(), "admin")
+
+	user, err := s.GetUserByUsername(ctx, "alice")
+	require.NoError(t, err)
+	assert.True(t, user.IsAdmin())
+
+	assert.EqualError(t, runRoleCommand(ctx, s, []string{"bob", "admin"}, &out), "пользователь bob не найден")
+	assert.EqualError(t, runRoleCommand(ctx, s, []string{"alice", "root"}, &out), roleUsage)
+	assert.EqualError(t, runRoleCommand(ctx, s, []string{"alice"}, &out), roleUsage)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/igor-fedko/go_multiply_app/locale"
+)
+
+// errUnknownLocale возвращается при выборе неподдерживаемой локали
+var errUnknownLocale = errors.New("Неизвестный формат чисел")
+
+// requestLocale возвращает локаль записи чисел для запроса: выбранную
+// пользователем в настройках или локаль языка интерфейса
+func requestLocale(c *gin.Context) locale.Locale {
+	if user, ok := currentUser(c); ok {
+		if l, ok := locale.Lookup(user.Locale); ok {
+			return l
+		}
+	}
+	if l, ok := locale.Lookup(printer(c).Lang()); ok {
+		return l
+	}
+	return locale.Default
+}
+
+// localeSettingHandler сохраняет локаль записи чисел пользователя из формы.
+// Пустое значение возвращает выбор по языку интерфейса.
+func localeSettingHandler(c *gin.Context) {
+	tag := c.PostForm("locale")
+	if tag != "" {
+		l, ok := locale.Lookup(tag)
+		if !ok {
+			renderIndex(c, http.StatusBadRequest, gin.H{
+				"Error": errUnknownLocale.Error(),
+			})
+			return
+		}
+		tag = l.Tag
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), appConfig.Timeouts.Write.Duration)
+	defer cancel()
+
+	if err := store.SetUserLocale(ctx, currentUserID(c), tag); err != nil {
+		slog.ErrorContext(c.Request.Context(), "save user locale", "error", err)
+		renderIndex(c, http.StatusInternalServerError, gin.H{
+			"Error": "Ошибка при сохранении настроек: " + err.Error(),
+		})
+		return
+	}
+
+	c.Redirect(http.StatusSeeOther, "/")
+}
@@ -0,0 +1,391 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/igor-fedko/go_multiply_app/models"
+	"github.com/igor-fedko/go_multiply_app/storage"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Store - хранилище в памяти процесса. Данные теряются при перезапуске;
+// используется для разработки и тестов без MongoDB.
+type Store struct {
+	mu      sync.RWMutex
+	results []models.Result
+	logs    []models.LogEntry
+	users   []models.User
+	tokens  []models.Token
+}
+
+var _ storage.Store = (*Store)(nil)
+
+// New создает пустое хранилище в памяти
+func New() *Store {
+	return &Store{}
+}
+
+// InsertResult сохраняет результат и заполняет его ID
+func (s *Store) InsertResult(_ context.Context, result *models.Result) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if result.ID.IsZero() {
+		result.ID = primitive.NewObjectID()
+	}
+	s.results = append(s.results, *result)
+	return nil
+}
+
+// InsertResults сохраняет результаты и заполняет их ID
+func (s *Store) InsertResults(_ context.Context, results []*models.Result) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, result := range results {
+		if result.ID.IsZero() {
+			result.ID = primitive.NewObjectID()
+		}
+		s.results = append(s.results, *result)
+	}
+	return nil
+}
+
+// GetResult возвращает результат по ID, если он входит в область scope
+func (s *Store) GetResult(_ context.Context, scope storage.Scope, id primitive.ObjectID) (models.Result, error) {
+	if err := scope.Validate(); err != nil {
+		return models.Result{}, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, r := range s.results {
+		if r.ID == id && scope.Contains(r.UserID) {
+			return r, nil
+		}
+	}
+	return models.Result{}, storage.ErrNotFound
+}
+
+// ListResults возвращает отфильтрованную, отсортированную страницу результатов
+func (s *Store) ListResults(_ context.Context, query storage.ResultQuery) ([]models.Result, error) {
+	if err := query.Scope.Validate(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	matched := s.filter(query)
+	s.mu.RUnlock()
+
+	sortResults(matched, query)
+
+	if query.Offset >= len(matched) {
+		return []models.Result{}, nil
+	}
+	matched = matched[query.Offset:]
+	if query.Limit > 0 && query.Limit < len(matched) {
+		matched = matched[:query.Limit]
+	}
+	return matched, nil
+}
+
+// EachResult передает в fn результаты запроса. Выборка копируется
+// под блокировкой, поэтому fn может обращаться к хранилищу.
+func (s *Store) EachResult(ctx context.Context, query storage.ResultQuery, fn func(models.Result) error) error {
+	results, err := s.ListResults(ctx, query)
+	if err != nil {
+		return err
+	}
+	for _, result := range results {
+		if err := fn(result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CountResults возвращает число результатов, удовлетворяющих фильтрам
+func (s *Store) CountResults(_ context.Context, query storage.ResultQuery) (int64, error) {
+	if err := query.Scope.Validate(); err != nil {
+		return 0, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return int64(len(s.filter(query))), nil
+}
+
+// InsertLog сохраняет запись журнала
+func (s *Store) InsertLog(_ context.Context, entry *models.LogEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if entry.ID.IsZero() {
+		entry.ID = primitive.NewObjectID()
+	}
+	s.logs = append(s.logs, *entry)
+	return nil
+}
+
+// GetLog возвращает запись журнала по ID
+func (s *Store) GetLog(_ context.Context, id primitive.ObjectID) (models.LogEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, e := range s.logs {
+		if e.ID == id {
+			return e, nil
+		}
+	}
+	return models.LogEntry{}, storage.ErrNotFound
+}
+
+// ListLogs возвращает страницу записей журнала, новые первыми
+func (s *Store) ListLogs(_ context.Context, query storage.LogQuery) ([]models.LogEntry, error) {
+	s.mu.RLock()
+	matched := s.filterLogs(query)
+	s.mu.RUnlock()
+
+	sort.SliceStable(matched, func(i, j int) bool {
+		if c := matched[i].Timestamp.Compare(matched[j].Timestamp); c != 0 {
+			return c > 0
+		}
+		return matched[i].ID.Hex() > matched[j].ID.Hex()
+	})
+
+	if query.Offset >= len(matched) {
+		return []models.LogEntry{}, nil
+	}
+	matched = matched[query.Offset:]
+	if query.Limit > 0 && query.Limit < len(matched) {
+		matched = matched[:query.Limit]
+	}
+	return matched, nil
+}
+
+// CountLogs возвращает число записей журнала, удовлетворяющих фильтрам
+func (s *Store) CountLogs(_ context.Context, query storage.LogQuery) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return int64(len(s.filterLogs(query))), nil
+}
+
+// Logs возвращает копию журнала операций (используется в тестах)
+func (s *Store) Logs() []models.LogEntry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	logs := make([]models.LogEntry, len(s.logs))
+	copy(logs, s.logs)
+	return logs
+}
+
+// CreateUser сохраняет пользователя, если имя свободно
+func (s *Store) CreateUser(_ context.Context, user *models.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, u := range s.users {
+		if u.Username == user.Username {
+			return storage.ErrDuplicate
+		}
+	}
+	if user.ID.IsZero() {
+		user.ID = primitive.NewObjectID()
+	}
+	s.users = append(s.users, *user)
+	return nil
+}
+
+// GetUser возвращает пользователя по ID
+func (s *Store) GetUser(_ context.Context, id primitive.ObjectID) (models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, u := range s.users {
+		if u.ID == id {
+			return u, nil
+		}
+	}
+	return models.User{}, storage.ErrNotFound
+}
+
+// GetUserByUsername возвращает пользователя по имени
+func (s *Store) GetUserByUsername(_ context.Context, username string) (models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, u := range s.users {
+		if u.Username == username {
+			return u, nil
+		}
+	}
+	return models.User{}, storage.ErrNotFound
+}
+
+// CreateToken сохраняет токен
+func (s *Store) CreateToken(_ context.Context, token *models.Token) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if token.ID.IsZero() {
+		token.ID = primitive.NewObjectID()
+	}
+	s.tokens = append(s.tokens, *token)
+	return nil
+}
+
+// GetTokenByHash возвращает токен по хешу
+func (s *Store) GetTokenByHash(_ context.Context, hash string) (models.Token, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, t := range s.tokens {
+		if t.Hash == hash {
+			return t, nil
+		}
+	}
+	return models.Token{}, storage.ErrNotFound
+}
+
+// DeleteToken удаляет токен по хешу
+func (s *Store) DeleteToken(_ context.Context, hash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, t := range s.tokens {
+		if t.Hash == hash {
+			s.tokens = append(s.tokens[:i], s.tokens[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+// SetUserRole назначает роль пользователю
+func (s *Store) SetUserRole(_ context.Context, username, role string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.users {
+		if s.users[i].Username == username {
+			s.users[i].Role = role
+			return nil
+		}
+	}
+	return storage.ErrNotFound
+}
+
+// SetUserLocale сохраняет локаль пользователя
+func (s *Store) SetUserLocale(_ context.Context, id primitive.ObjectID, locale string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.users {
+		if s.users[i].ID == id {
+			s.users[i].Locale = locale
+			return nil
+		}
+	}
+	return storage.ErrNotFound
+}
+
+// Ping всегда успешен
+func (s *Store) Ping(context.Context) error { return nil }
+
+// Close ничего не делает
+func (s *Store) Close(context.Context) error { return nil }
+
+// filter возвращает копии результатов, удовлетворяющих фильтрам запроса.
+// Вызывается под блокировкой.
+func (s *Store) filter(query storage.ResultQuery) []models.Result {
+	matched := make([]models.Result, 0, len(s.results))
+	for _, r := range s.results {
+		if !query.Scope.Contains(r.UserID) {
+			continue
+		}
+		if query.Operation != "" && r.Operation != query.Operation {
+			continue
+		}
+		if !query.From.IsZero() && r.CreatedAt.Before(query.From) {
+			continue
+		}
+		if !query.To.IsZero() && r.CreatedAt.After(query.To) {
+			continue
+		}
+		matched = append(matched, r)
+	}
+	return matched
+}
+
+// filterLogs возвращает копии записей журнала, удовлетворяющих фильтрам
+// запроса. Вызывается под блокировкой.
+func (s *Store) filterLogs(query storage.LogQuery) []models.LogEntry {
+	text := strings.ToLower(query.Text)
+	matched := make([]models.LogEntry, 0, len(s.logs))
+	for _, e := range s.logs {
+		if query.Operation != "" && e.Operation != query.Operation {
+			continue
+		}
+		if query.UserIP != "" && e.UserIP != query.UserIP {
+			continue
+		}
+		if !query.From.IsZero() && e.Timestamp.Before(query.From) {
+			continue
+		}
+		if !query.To.IsZero() && e.Timestamp.After(query.To) {
+			continue
+		}
+		if text != "" && !strings.Contains(strings.ToLower(e.Input), text) {
+			continue
+		}
+		matched = append(matched, e)
+	}
+	return matched
+}
+
+// sortResults сортирует результаты так же, как MongoDB: по выбранному полю,
+// при равенстве - по _id в том же направлении
+func sortResults(results []models.Result, query storage.ResultQuery) {
+	less := func(a, b models.Result) int {
+		switch query.SortBy {
+		case storage.SortNumber1:
+			return compareFloat(a.OperandValue(0), b.OperandValue(0))
+		case storage.SortNumber2:
+			return compareFloat(a.OperandValue(1), b.OperandValue(1))
+		case storage.SortResult:
+			return compareFloat(a.Result, b.Result)
+		case storage.SortOperation:
+			return strings.Compare(a.Operation, b.Operation)
+		default:
+			return a.CreatedAt.Compare(b.CreatedAt)
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		c := less(results[i], results[j])
+		if c == 0 {
+			c = strings.Compare(results[i].ID.Hex(), results[j].ID.Hex())
+		}
+		if query.SortAsc {
+			return c < 0
+		}
+		return c > 0
+	})
+}
+
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
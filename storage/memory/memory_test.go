@@ -0,0 +1,178 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/igor-fedko/go_multiply_app/models"
+	"github.com/igor-fedko/go_multiply_app/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func seed(t *testing.T) (*Store, time.Time) {
+	t.Helper()
+	s := New()
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	results := []models.Result{
+		{Operands: []models.Operand{{Value: 2}, {Value: 3}}, Result: 6, Operation: "multiply", CreatedAt: base},
+		{Operands: []models.Operand{{Value: 10}, {Value: 2}}, Result: 5, Operation: "divide", CreatedAt: base.Add(time.Hour)},
+		{Operands: []models.Operand{{Value: 1}, {Value: 1}}, Result: 2, Operation: "add", CreatedAt: base.Add(2 * time.Hour)},
+		{Operands: []models.Operand{{Value: 4}, {Value: 5}}, Result: 20, Operation: "multiply", CreatedAt: base.Add(3 * time.Hour)},
+	}
+	for i := range results {
+		require.NoError(t, s.InsertResult(context.Background(), &results[i]))
+		require.False(t, results[i].ID.IsZero())
+	}
+	return s, base
+}
+
+func TestListResultsDefaultOrder(t *testing.T) {
+	s, _ := seed(t)
+
+	results, err := s.ListResults(context.Background(), storage.ResultQuery{Scope: storage.AllUsers()})
+	require.NoError(t, err)
+	require.Len(t, results, 4)
+
+	// По умолчанию новые записи первыми
+	assert.Equal(t, float64(20), results[0].Result)
+	assert.Equal(t, float64(6), results[3].Result)
+}
+
+func TestListResultsFilterSortPaginate(t *testing.T) {
+	s, base := seed(t)
+	ctx := context.Background()
+
+	query := storage.ResultQuery{Scope: storage.AllUsers(), Operation: "multiply", SortBy: storage.SortResult, SortAsc: true}
+	results, err := s.ListResults(ctx, query)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, float64(6), results[0].Result)
+	assert.Equal(t, float64(20), results[1].Result)
+
+	query = storage.ResultQuery{Scope: storage.AllUsers(), From: base.Add(time.Hour), To: base.Add(2 * time.Hour)}
+	count, err := s.CountResults(ctx, query)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	query = storage.ResultQuery{Scope: storage.AllUsers(), SortBy: storage.SortNumber1, Offset: 1, Limit: 2}
+	results, err = s.ListResults(ctx, query)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, float64(4), results[0].OperandValue(0))
+	assert.Equal(t, float64(2), results[1].OperandValue(0))
+
+	// Счетчик не зависит от страницы
+	count, err = s.CountResults(ctx, query)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), count)
+
+	results, err = s.ListResults(ctx, storage.ResultQuery{Scope: storage.AllUsers(), Offset: 10})
+	require.NoError(t, err)
+	assert.Empty(t, results)
+}
+
+func TestGetResult(t *testing.T) {
+	s, _ := seed(t)
+	ctx := context.Background()
+
+	all, err := s.ListResults(ctx, storage.ResultQuery{Scope: storage.AllUsers()})
+	require.NoError(t, err)
+
+	got, err := s.GetResult(ctx, storage.AllUsers(), all[1].ID)
+	require.NoError(t, err)
+	assert.Equal(t, all[1], got)
+
+	_, err = s.GetResult(ctx, storage.AllUsers(), [12]byte{1})
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+}
+
+func TestScope(t *testing.T) {
+	s := New()
+	ctx := context.Background()
+	alice, bob := primitive.NewObjectID(), primitive.NewObjectID()
+
+	for _, owner := range []primitive.ObjectID{alice, alice, bob, primitive.NilObjectID} {
+		require.NoError(t, s.InsertResult(ctx, &models.Result{Operation: "add", UserID: owner}))
+	}
+
+	count, err := s.CountResults(ctx, storage.ResultQuery{Scope: storage.UserScope(alice)})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	// Результаты без владельца видны только во всей истории
+	count, err = s.CountResults(ctx, storage.ResultQuery{Scope: storage.AllUsers()})
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), count)
+
+	bobs, err := s.ListResults(ctx, storage.ResultQuery{Scope: storage.UserScope(bob)})
+	require.NoError(t, err)
+	require.Len(t, bobs, 1)
+	_, err = s.GetResult(ctx, storage.UserScope(alice), bobs[0].ID)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+
+	// Запрос без области видимости отклоняется
+	_, err = s.ListResults(ctx, storage.ResultQuery{})
+	assert.ErrorIs(t, err, storage.ErrNoScope)
+	_, err = s.CountResults(ctx, storage.ResultQuery{Scope: storage.UserScope(primitive.NilObjectID)})
+	assert.ErrorIs(t, err, storage.ErrNoScope)
+}
+
+func TestUsers(t *testing.T) {
+	s := New()
+	ctx := context.Background()
+
+	user := models.User{Username: "alice", PasswordHash: "hash"}
+	require.NoError(t, s.CreateUser(ctx, &user))
+	assert.False(t, user.ID.IsZero())
+	assert.ErrorIs(t, s.CreateUser(ctx, &models.User{Username: "alice"}), storage.ErrDuplicate)
+
+	got, err := s.GetUser(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, user, got)
+
+	token := models.Token{UserID: user.ID, Kind: models.TokenAPI, Hash: "h"}
+	require.NoError(t, s.CreateToken(ctx, &token))
+	gotToken, err := s.GetTokenByHash(ctx, "h")
+	require.NoError(t, err)
+	assert.Equal(t, token, gotToken)
+
+	require.NoError(t, s.DeleteToken(ctx, "h"))
+	_, err = s.GetTokenByHash(ctx, "h")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+}
+
+func TestLogs(t *testing.T) {
+	s := New()
+	ctx := context.Background()
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	entries := []models.LogEntry{
+		{Operation: "add", Input: "1 + 2", UserIP: "127.0.0.1", Timestamp: base},
+		{Operation: "evaluate", Input: "SQRT(16) + 1", UserIP: "10.0.0.1", Timestamp: base.Add(time.Hour)},
+		{Operation: "add", Input: "3 + 4", UserIP: "10.0.0.1", Timestamp: base.Add(2 * time.Hour)},
+	}
+	for i := range entries {
+		require.NoError(t, s.InsertLog(ctx, &entries[i]))
+	}
+
+	list, err := s.ListLogs(ctx, storage.LogQuery{UserIP: "10.0.0.1"})
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, entries[2].ID, list[0].ID)
+
+	count, err := s.CountLogs(ctx, storage.LogQuery{Operation: "add", Text: "+ 2"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	count, err = s.CountLogs(ctx, storage.LogQuery{Text: "sqrt", To: base})
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), count)
+
+	entry, err := s.GetLog(ctx, entries[1].ID)
+	require.NoError(t, err)
+	assert.Equal(t, "evaluate", entry.Operation)
+}
@@ -0,0 +1,467 @@
+package mongostore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/igor-fedko/go_multiply_app/models"
+	"github.com/igor-fedko/go_multiply_app/storage"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MigrationsCollection - коллекция учета примененных миграций
+const MigrationsCollection = "migrations"
+
+// Migration - версия схемы MongoDB. Миграции не удаляют коллекции
+// и документы: валидаторы меняются через collMod, индексы создаются
+// и удаляются по одному.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(ctx context.Context, s *Store) error
+	Down    func(ctx context.Context, s *Store) error
+}
+
+// migrationRecord - документ коллекции migrations
+type migrationRecord struct {
+	Version   int       `bson:"_id"`
+	Name      string    `bson:"name"`
+	AppliedAt time.Time `bson:"applied_at"`
+}
+
+// Имена индексов, которые MongoDB назначает по ключам
+const (
+	resultsOperationIndex  = "operation_1_created_at_-1"
+	logsOperationIndex     = "operation_1_timestamp_-1"
+	usersUsernameIndex     = "username_1"
+	tokensHashIndex        = "hash_1"
+	tokensExpiresIndex     = "expires_at_1"
+	resultsUserIndex       = "user_id_1_created_at_-1"
+	rateLimitsExpiresIndex = "expires_at_1"
+	logsTimestampIndex     = "timestamp_-1"
+	logsUserIPIndex        = "user_ip_1_timestamp_-1"
+	resultsOperationPage   = "operation_1_created_at_-1__id_-1"
+	resultsUserPage        = "user_id_1_created_at_-1__id_-1"
+)
+
+// migrationBatchSize - сколько документов переписывается за один запрос bulkWrite
+const migrationBatchSize = 500
+
+// migrations - миграции схемы по возрастанию версии
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "init",
+		Up: func(ctx context.Context, s *Store) error {
+			if err := setValidator(ctx, s.results, resultsValidator); err != nil {
+				return err
+			}
+			if err := createIndex(ctx, s.results, bson.D{{Key: "operation", Value: 1}, {Key: "created_at", Value: -1}}, nil); err != nil {
+				return err
+			}
+			if err := setValidator(ctx, s.logs, logsValidator); err != nil {
+				return err
+			}
+			return createIndex(ctx, s.logs, bson.D{{Key: "operation", Value: 1}, {Key: "timestamp", Value: -1}}, nil)
+		},
+		Down: func(ctx context.Context, s *Store) error {
+			if err := dropIndex(ctx, s.logs, logsOperationIndex); err != nil {
+				return err
+			}
+			if err := setValidator(ctx, s.logs, bson.M{}); err != nil {
+				return err
+			}
+			if err := dropIndex(ctx, s.results, resultsOperationIndex); err != nil {
+				return err
+			}
+			return setValidator(ctx, s.results, bson.M{})
+		},
+	},
+	{
+		Version: 2,
+		Name:    "users",
+		Up: func(ctx context.Context, s *Store) error {
+			if err := setValidator(ctx, s.users, usersValidator); err != nil {
+				return err
+			}
+			if err := createIndex(ctx, s.users, bson.D{{Key: "username", Value: 1}}, options.Index().SetUnique(true)); err != nil {
+				return err
+			}
+			if err := createIndex(ctx, s.tokens, bson.D{{Key: "hash", Value: 1}}, options.Index().SetUnique(true)); err != nil {
+				return err
+			}
+			// Истекшие сессии удаляет сама MongoDB; бессрочные токены без expires_at не затрагиваются
+			return createIndex(ctx, s.tokens, bson.D{{Key: "expires_at", Value: 1}}, options.Index().SetExpireAfterSeconds(0))
+		},
+		Down: func(ctx context.Context, s *Store) error {
+			for _, name := range []string{tokensExpiresIndex, tokensHashIndex} {
+				if err := dropIndex(ctx, s.tokens, name); err != nil {
+					return err
+				}
+			}
+			if err := dropIndex(ctx, s.users, usersUsernameIndex); err != nil {
+				return err
+			}
+			return setValidator(ctx, s.users, bson.M{})
+		},
+	},
+	{
+		Version: 3,
+		Name:    "results_by_user",
+		Up: func(ctx context.Context, s *Store) error {
+			return createIndex(ctx, s.results, bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}, nil)
+		},
+		Down: func(ctx context.Context, s *Store) error {
+			return dropIndex(ctx, s.results, resultsUserIndex)
+		},
+	},
+	{
+		Version: 4,
+		Name:    "rate_limits",
+		Up: func(ctx context.Context, s *Store) error {
+			// Корзина удаляется, когда успевает заполниться целиком
+			return createIndex(ctx, s.rateLimits, bson.D{{Key: "expires_at", Value: 1}}, options.Index().SetExpireAfterSeconds(0))
+		},
+		Down: func(ctx context.Context, s *Store) error {
+			return dropIndex(ctx, s.rateLimits, rateLimitsExpiresIndex)
+		},
+	},
+	{
+		Version: 5,
+		Name:    "logs_search",
+		Up: func(ctx context.Context, s *Store) error {
+			// Просмотр журнала без фильтра по операции и поиск по IP
+			if err := createIndex(ctx, s.logs, bson.D{{Key: "timestamp", Value: -1}}, nil); err != nil {
+				return err
+			}
+			return createIndex(ctx, s.logs, bson.D{{Key: "user_ip", Value: 1}, {Key: "timestamp", Value: -1}}, nil)
+		},
+		Down: func(ctx context.Context, s *Store) error {
+			for _, name := range []string{logsUserIPIndex, logsTimestampIndex} {
+				if err := dropIndex(ctx, s.logs, name); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 6,
+		Name:    "result_operands",
+		Up: func(ctx context.Context, s *Store) error {
+			// Старые документы не проходят новую схему, поэтому уровень moderate
+			// не проверяет их обновления
+			if err := setValidator(ctx, s.results, resultsOperandsValidator); err != nil {
+				return err
+			}
+			return rewriteResults(ctx, s, bson.M{"schema_version": bson.M{"$exists": false}}, upgradeResult)
+		},
+		Down: func(ctx context.Context, s *Store) error {
+			if err := setValidator(ctx, s.results, resultsValidator); err != nil {
+				return err
+			}
+			return rewriteResults(ctx, s, bson.M{"schema_version": bson.M{"$exists": true}}, downgradeResult)
+		},
+	},
+	{
+		Version: 7,
+		Name:    "results_pages",
+		Up: func(ctx context.Context, s *Store) error {
+			// Страницы истории сортируются по (created_at, _id): без _id в ключе
+			// индекса MongoDB сортирует всю выборку в памяти
+			if err := createIndex(ctx, s.results, bson.D{{Key: "operation", Value: 1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}, nil); err != nil {
+				return err
+			}
+			return createIndex(ctx, s.results, bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}, nil)
+		},
+		Down: func(ctx context.Context, s *Store) error {
+			for _, name := range []string{resultsUserPage, resultsOperationPage} {
+				if err := dropIndex(ctx, s.results, name); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+}
+
+// usersValidator - схема документов коллекции пользователей
+var usersValidator = bson.M{
+	"$jsonSchema": bson.M{
+		"bsonType": "object",
+		"required": []string{"username", "password_hash", "created_at"},
+		"properties": bson.M{
+			"username":      bson.M{"bsonType": "string"},
+			"password_hash": bson.M{"bsonType": "string"},
+			"created_at":    bson.M{"bsonType": "date"},
+		},
+	},
+}
+
+// resultsValidator - схема документов коллекции результатов
+var resultsValidator = bson.M{
+	"$jsonSchema": bson.M{
+		"bsonType": "object",
+		"required": []string{"number1", "number2", "result", "operation", "created_at"},
+		"properties": bson.M{
+			"number1":   bson.M{"bsonType": "double"},
+			"number2":   bson.M{"bsonType": "double"},
+			"result":    bson.M{"bsonType": "double"},
+			"operation": bson.M{"bsonType": "string"},
+			"created_at": bson.M{
+				"bsonType":    "date",
+				"description": "timestamp of result creation",
+			},
+		},
+	},
+}
+
+// resultsOperandsValidator - схема документов результатов с массивом operands
+var resultsOperandsValidator = bson.M{
+	"$jsonSchema": bson.M{
+		"bsonType": "object",
+		"required": []string{"schema_version", "result", "operation", "created_at"},
+		"properties": bson.M{
+			"schema_version": bson.M{"bsonType": []string{"int", "long"}},
+			"operands": bson.M{
+				"bsonType": "array",
+				"items": bson.M{
+					"bsonType": "object",
+					"required": []string{"value"},
+					"properties": bson.M{
+						"name":  bson.M{"bsonType": "string"},
+						"value": bson.M{"bsonType": "double"},
+						"exact": bson.M{"bsonType": "string"},
+					},
+				},
+			},
+			"result":    bson.M{"bsonType": "double"},
+			"operation": bson.M{"bsonType": "string"},
+			"created_at": bson.M{
+				"bsonType":    "date",
+				"description": "timestamp of result creation",
+			},
+		},
+	},
+}
+
+// logsValidator - схема документов журнала операций
+var logsValidator = bson.M{
+	"$jsonSchema": bson.M{
+		"bsonType": "object",
+		"required": []string{"operation", "input", "result", "user_ip", "timestamp"},
+		"properties": bson.M{
+			"operation": bson.M{"bsonType": "string"},
+			"input":     bson.M{"bsonType": "string"},
+			"result":    bson.M{"bsonType": "string"},
+			"user_ip":   bson.M{"bsonType": "string"},
+			"timestamp": bson.M{
+				"bsonType":    "date",
+				"description": "timestamp of the log entry",
+			},
+		},
+	},
+}
+
+// setValidator задает валидатор коллекции, создавая ее при необходимости.
+// Уровень moderate не мешает обновлять документы, записанные до появления схемы.
+// Пустой validator снимает проверку.
+func setValidator(ctx context.Context, coll *mongo.Collection, validator bson.M) error {
+	db := coll.Database()
+	names, err := db.ListCollectionNames(ctx, bson.M{"name": coll.Name()})
+	if err != nil {
+		return err
+	}
+
+	if len(names) == 0 {
+		return db.CreateCollection(ctx, coll.Name(), options.CreateCollection().
+			SetValidator(validator).
+			SetValidationLevel("moderate"))
+	}
+
+	return db.RunCommand(ctx, bson.D{
+		{Key: "collMod", Value: coll.Name()},
+		{Key: "validator", Value: validator},
+		{Key: "validationLevel", Value: "moderate"},
+	}).Err()
+}
+
+// createIndex создает индекс с параметрами opts (может быть nil); существующий индекс с теми же ключами не пересоздается
+func createIndex(ctx context.Context, coll *mongo.Collection, keys bson.D, opts *options.IndexOptions) error {
+	_, err := coll.Indexes().CreateOne(ctx, mongo.IndexModel{Keys: keys, Options: opts})
+	return err
+}
+
+// dropIndex удаляет индекс, если он существует
+func dropIndex(ctx context.Context, coll *mongo.Collection, name string) error {
+	_, err := coll.Indexes().DropOne(ctx, name)
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) && (cmdErr.Name == "IndexNotFound" || cmdErr.Name == "NamespaceNotFound") {
+		return nil
+	}
+	return err
+}
+
+// rewriteResults переписывает документы результатов, подходящие под filter,
+// пачками по migrationBatchSize; update возвращает изменение документа.
+// Переписанный документ не должен подходить под filter, поэтому прерванную
+// миграцию можно продолжить повторным запуском.
+func rewriteResults(ctx context.Context, s *Store, filter bson.M, update func(models.Result) (bson.M, error)) error {
+	for {
+		cursor, err := s.results.Find(ctx, filter, options.Find().SetLimit(migrationBatchSize))
+		if err != nil {
+			return err
+		}
+		var docs []models.Result
+		if err := cursor.All(ctx, &docs); err != nil {
+			return err
+		}
+		if len(docs) == 0 {
+			return nil
+		}
+
+		writes := make([]mongo.WriteModel, len(docs))
+		for i, doc := range docs {
+			change, err := update(doc)
+			if err != nil {
+				return err
+			}
+			writes[i] = mongo.NewUpdateOneModel().SetFilter(bson.M{"_id": doc.ID}).SetUpdate(change)
+		}
+		if _, err := s.results.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false)); err != nil {
+			return err
+		}
+		if len(docs) < migrationBatchSize {
+			return nil
+		}
+	}
+}
+
+// upgradeResult переносит операнды документа первой версии схемы
+// из number1/number2 в массив operands. Документ уже прочитан
+// models.Result, который выполняет перенос при декодировании.
+func upgradeResult(r models.Result) (bson.M, error) {
+	set := bson.M{"schema_version": models.ResultSchemaOperands}
+	if len(r.Operands) > 0 {
+		set["operands"] = r.Operands
+	}
+	return bson.M{
+		"$set":   set,
+		"$unset": bson.M{"number1": "", "number2": "", "number1_exact": "", "number2_exact": ""},
+	}, nil
+}
+
+// downgradeResult возвращает операнды в поля number1/number2. Первая
+// версия схемы требует оба поля, поэтому недостающие операнды равны 0.
+func downgradeResult(r models.Result) (bson.M, error) {
+	if len(r.Operands) > 2 {
+		return nil, fmt.Errorf("результат %s содержит %d операндов", r.ID.Hex(), len(r.Operands))
+	}
+	set := bson.M{"number1": r.OperandValue(0), "number2": r.OperandValue(1)}
+	for i, field := range []string{"number1_exact", "number2_exact"} {
+		if o, ok := r.Operand(i); ok && o.Exact != "" {
+			set[field] = o.Exact
+		}
+	}
+	return bson.M{
+		"$set":   set,
+		"$unset": bson.M{"operands": "", "schema_version": ""},
+	}, nil
+}
+
+// appliedMigrations возвращает записи о примененных миграциях по версиям
+func (s *Store) appliedMigrations(ctx context.Context) (map[int]migrationRecord, error) {
+	cursor, err := s.migrations.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var records []migrationRecord
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int]migrationRecord, len(records))
+	for _, r := range records {
+		applied[r.Version] = r
+	}
+	return applied, nil
+}
+
+// MigrationStatus возвращает состояние всех известных миграций
+func (s *Store) MigrationStatus(ctx context.Context) ([]storage.MigrationStatus, error) {
+	applied, err := s.appliedMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]storage.MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		record, ok := applied[m.Version]
+		statuses = append(statuses, storage.MigrationStatus{
+			Version:   m.Version,
+			Name:      m.Name,
+			Applied:   ok,
+			AppliedAt: record.AppliedAt,
+		})
+	}
+	return statuses, nil
+}
+
+// MigrateUp применяет все непримененные миграции по порядку.
+// Шаги миграций идемпотентны, поэтому прерванную миграцию можно
+// безопасно запустить повторно.
+func (s *Store) MigrateUp(ctx context.Context) error {
+	applied, err := s.appliedMigrations(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+		if err := m.Up(ctx, s); err != nil {
+			return fmt.Errorf("миграция %04d_%s: %w", m.Version, m.Name, err)
+		}
+
+		record := migrationRecord{Version: m.Version, Name: m.Name, AppliedAt: time.Now().UTC()}
+		_, err := s.migrations.InsertOne(ctx, record)
+		// Миграцию мог одновременно применить другой экземпляр приложения
+		if err != nil && !mongo.IsDuplicateKeyError(err) {
+			return fmt.Errorf("миграция %04d_%s: %w", m.Version, m.Name, err)
+		}
+	}
+	return nil
+}
+
+// MigrateDown откатывает последнюю примененную миграцию
+func (s *Store) MigrateDown(ctx context.Context) error {
+	applied, err := s.appliedMigrations(ctx)
+	if err != nil {
+		return err
+	}
+
+	for i := len(migrations) - 1; i >= 0; i-- {
+		m := migrations[i]
+		if _, ok := applied[m.Version]; !ok {
+			continue
+		}
+		if m.Down == nil {
+			return fmt.Errorf("миграция %04d_%s не поддерживает откат", m.Version, m.Name)
+		}
+		if err := m.Down(ctx, s); err != nil {
+			return fmt.Errorf("откат миграции %04d_%s: %w", m.Version, m.Name, err)
+		}
+		if _, err := s.migrations.DeleteOne(ctx, bson.M{"_id": m.Version}); err != nil {
+			return fmt.Errorf("откат миграции %04d_%s: %w", m.Version, m.Name, err)
+		}
+		return nil
+	}
+	return nil
+}
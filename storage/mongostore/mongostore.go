@@ -0,0 +1,428 @@
+package mongostore
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"time"
+
+	"github.com/igor-fedko/go_multiply_app/models"
+	"github.com/igor-fedko/go_multiply_app/storage"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Store - хранилище в MongoDB
+type Store struct {
+	client     *mongo.Client
+	results    *mongo.Collection
+	logs       *mongo.Collection
+	users      *mongo.Collection
+	tokens     *mongo.Collection
+	rateLimits *mongo.Collection
+	migrations *mongo.Collection
+}
+
+// Коллекции пользователей, токенов доступа и корзин ограничения частоты
+const (
+	UsersCollection      = "users"
+	TokensCollection     = "tokens"
+	RateLimitsCollection = "rate_limits"
+)
+
+var (
+	_ storage.Store    = (*Store)(nil)
+	_ storage.Migrator = (*Store)(nil)
+)
+
+// New создает хранилище поверх подключенного клиента MongoDB.
+// Схема коллекций создается миграциями (MigrateUp).
+func New(client *mongo.Client, database, resultsCollection, logsCollection string) *Store {
+	db := client.Database(database)
+	return &Store{
+		client:     client,
+		results:    db.Collection(resultsCollection),
+		logs:       db.Collection(logsCollection),
+		users:      db.Collection(UsersCollection),
+		tokens:     db.Collection(TokensCollection),
+		rateLimits: db.Collection(RateLimitsCollection),
+		migrations: db.Collection(MigrationsCollection),
+	}
+}
+
+// RateLimits возвращает коллекцию корзин ограничения частоты запросов,
+// общую для всех реплик (см. ratelimit.MongoStore)
+func (s *Store) RateLimits() *mongo.Collection {
+	return s.rateLimits
+}
+
+// InsertResult сохраняет результат и заполняет его ID
+func (s *Store) InsertResult(ctx context.Context, result *models.Result) error {
+	res, err := s.results.InsertOne(ctx, result)
+	if err != nil {
+		return err
+	}
+	if id, ok := res.InsertedID.(primitive.ObjectID); ok {
+		result.ID = id
+	}
+	return nil
+}
+
+// insertCheckTimeout ограничивает проверку, какие результаты сохранены
+// прерванным insertMany
+const insertCheckTimeout = 5 * time.Second
+
+// InsertResults сохраняет результаты одним упорядоченным запросом insertMany
+// и заполняет их ID. ID назначаются заранее, поэтому повтор запроса драйвером
+// не создает дубликатов. insertMany не атомарен: при ошибке сохраненное
+// начало среза сообщается через *storage.PartialInsertError.
+func (s *Store) InsertResults(ctx context.Context, results []*models.Result) error {
+	if len(results) == 0 {
+		return nil
+	}
+
+	docs := make([]any, len(results))
+	for i, result := range results {
+		if result.ID.IsZero() {
+			result.ID = primitive.NewObjectID()
+		}
+		docs[i] = result
+	}
+	_, err := s.results.InsertMany(ctx, docs)
+	if err == nil {
+		return nil
+	}
+	inserted := s.insertedPrefix(ctx, results, err)
+	switch {
+	case inserted == len(results):
+		return nil
+	case inserted > 0:
+		return &storage.PartialInsertError{Inserted: inserted, Err: err}
+	}
+	return err
+}
+
+// insertedPrefix возвращает число первых результатов, сохраненных insertMany,
+// завершившимся ошибкой err. Упорядоченная вставка останавливается на первой
+// ошибке записи, поэтому ее индекс и есть число сохраненных результатов.
+// Для других ошибок сохраненные результаты подсчитываются по заранее
+// назначенным ID; если подсчет не удался, возвращается 0.
+func (s *Store) insertedPrefix(ctx context.Context, results []*models.Result, err error) int {
+	var bulkErr mongo.BulkWriteException
+	if errors.As(err, &bulkErr) && bulkErr.WriteConcernError == nil && len(bulkErr.WriteErrors) > 0 {
+		return bulkErr.WriteErrors[0].Index
+	}
+
+	// Исходный контекст мог истечь - именно это часто и прерывает вставку
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), insertCheckTimeout)
+	defer cancel()
+
+	ids := make([]primitive.ObjectID, len(results))
+	for i, result := range results {
+		ids[i] = result.ID
+	}
+	count, countErr := s.results.CountDocuments(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if countErr != nil {
+		return 0
+	}
+	return int(count)
+}
+
+// GetResult возвращает результат по ID, если он входит в область scope
+func (s *Store) GetResult(ctx context.Context, scope storage.Scope, id primitive.ObjectID) (models.Result, error) {
+	filter, err := scopeFilter(scope)
+	if err != nil {
+		return models.Result{}, err
+	}
+	filter["_id"] = id
+	return findOne[models.Result](ctx, s.results, filter)
+}
+
+// ListResults возвращает страницу результатов, удовлетворяющих запросу
+func (s *Store) ListResults(ctx context.Context, query storage.ResultQuery) ([]models.Result, error) {
+	filter, err := resultFilter(query)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := s.results.Find(ctx, filter, findOptions(query))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	results := []models.Result{}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// EachResult читает результаты запроса курсором и передает их в fn по одному
+func (s *Store) EachResult(ctx context.Context, query storage.ResultQuery, fn func(models.Result) error) error {
+	filter, err := resultFilter(query)
+	if err != nil {
+		return err
+	}
+
+	cursor, err := s.results.Find(ctx, filter, findOptions(query))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var result models.Result
+		if err := cursor.Decode(&result); err != nil {
+			return err
+		}
+		if err := fn(result); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
+// findOptions возвращает сортировку и страницу выборки результатов
+func findOptions(query storage.ResultQuery) *options.FindOptions {
+	sortField := query.SortBy
+	switch sortField {
+	case "":
+		sortField = storage.SortCreatedAt
+	case storage.SortNumber1:
+		sortField = "operands.0.value"
+	case storage.SortNumber2:
+		sortField = "operands.1.value"
+	}
+	direction := -1
+	if query.SortAsc {
+		direction = 1
+	}
+
+	// _id разрешает равенство значений поля сортировки (в том числе
+	// одинаковый created_at), чтобы порядок страниц был устойчивым
+	// и совпадал с хранилищем в памяти. Сортировку по дате обслуживают
+	// индексы (operation, created_at, _id) и (user_id, created_at, _id).
+	sort := bson.D{{Key: sortField, Value: direction}, {Key: "_id", Value: direction}}
+
+	opts := options.Find().
+		SetSort(sort).
+		SetSkip(int64(query.Offset))
+	if query.Limit > 0 {
+		opts.SetLimit(int64(query.Limit))
+	}
+	return opts
+}
+
+// CountResults возвращает число результатов, удовлетворяющих фильтрам
+func (s *Store) CountResults(ctx context.Context, query storage.ResultQuery) (int64, error) {
+	filter, err := resultFilter(query)
+	if err != nil {
+		return 0, err
+	}
+	return s.results.CountDocuments(ctx, filter)
+}
+
+// InsertLog сохраняет запись журнала
+func (s *Store) InsertLog(ctx context.Context, entry *models.LogEntry) error {
+	res, err := s.logs.InsertOne(ctx, entry)
+	if err != nil {
+		return err
+	}
+	if id, ok := res.InsertedID.(primitive.ObjectID); ok {
+		entry.ID = id
+	}
+	return nil
+}
+
+// GetLog возвращает запись журнала по ID
+func (s *Store) GetLog(ctx context.Context, id primitive.ObjectID) (models.LogEntry, error) {
+	return findOne[models.LogEntry](ctx, s.logs, bson.M{"_id": id})
+}
+
+// ListLogs возвращает страницу записей журнала, новые первыми
+func (s *Store) ListLogs(ctx context.Context, query storage.LogQuery) ([]models.LogEntry, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "timestamp", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(int64(query.Offset))
+	if query.Limit > 0 {
+		opts.SetLimit(int64(query.Limit))
+	}
+
+	cursor, err := s.logs.Find(ctx, logFilter(query), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	entries := []models.LogEntry{}
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// CountLogs возвращает число записей журнала, удовлетворяющих фильтрам
+func (s *Store) CountLogs(ctx context.Context, query storage.LogQuery) (int64, error) {
+	return s.logs.CountDocuments(ctx, logFilter(query))
+}
+
+// CreateUser сохраняет пользователя; уникальность имени обеспечивает индекс
+func (s *Store) CreateUser(ctx context.Context, user *models.User) error {
+	res, err := s.users.InsertOne(ctx, user)
+	if mongo.IsDuplicateKeyError(err) {
+		return storage.ErrDuplicate
+	}
+	if err != nil {
+		return err
+	}
+	if id, ok := res.InsertedID.(primitive.ObjectID); ok {
+		user.ID = id
+	}
+	return nil
+}
+
+// GetUser возвращает пользователя по ID
+func (s *Store) GetUser(ctx context.Context, id primitive.ObjectID) (models.User, error) {
+	return findOne[models.User](ctx, s.users, bson.M{"_id": id})
+}
+
+// GetUserByUsername возвращает пользователя по имени
+func (s *Store) GetUserByUsername(ctx context.Context, username string) (models.User, error) {
+	return findOne[models.User](ctx, s.users, bson.M{"username": username})
+}
+
+// CreateToken сохраняет токен
+func (s *Store) CreateToken(ctx context.Context, token *models.Token) error {
+	res, err := s.tokens.InsertOne(ctx, token)
+	if err != nil {
+		return err
+	}
+	if id, ok := res.InsertedID.(primitive.ObjectID); ok {
+		token.ID = id
+	}
+	return nil
+}
+
+// GetTokenByHash возвращает токен по хешу
+func (s *Store) GetTokenByHash(ctx context.Context, hash string) (models.Token, error) {
+	return findOne[models.Token](ctx, s.tokens, bson.M{"hash": hash})
+}
+
+// DeleteToken удаляет токен по хешу
+func (s *Store) DeleteToken(ctx context.Context, hash string) error {
+	_, err := s.tokens.DeleteOne(ctx, bson.M{"hash": hash})
+	return err
+}
+
+// SetUserRole назначает роль пользователю
+func (s *Store) SetUserRole(ctx context.Context, username, role string) error {
+	res, err := s.users.UpdateOne(ctx, bson.M{"username": username}, bson.M{"$set": bson.M{"role": role}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+// SetUserLocale сохраняет локаль пользователя
+func (s *Store) SetUserLocale(ctx context.Context, id primitive.ObjectID, locale string) error {
+	res, err := s.users.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"locale": locale}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+// Ping проверяет соединение с MongoDB
+func (s *Store) Ping(ctx context.Context) error {
+	return s.client.Ping(ctx, nil)
+}
+
+// Close закрывает соединение с MongoDB
+func (s *Store) Close(ctx context.Context) error {
+	return s.client.Disconnect(ctx)
+}
+
+// resultFilter строит фильтр MongoDB по параметрам запроса
+func resultFilter(query storage.ResultQuery) (bson.M, error) {
+	filter, err := scopeFilter(query.Scope)
+	if err != nil {
+		return nil, err
+	}
+	if query.Operation != "" {
+		filter["operation"] = query.Operation
+	}
+
+	createdAt := bson.M{}
+	if !query.From.IsZero() {
+		createdAt["$gte"] = query.From
+	}
+	if !query.To.IsZero() {
+		createdAt["$lte"] = query.To
+	}
+	if len(createdAt) > 0 {
+		filter["created_at"] = createdAt
+	}
+	return filter, nil
+}
+
+// logFilter строит фильтр MongoDB по параметрам поиска в журнале.
+// Поиск текста в input не использует индекс и просматривает записи,
+// отобранные остальными фильтрами.
+func logFilter(query storage.LogQuery) bson.M {
+	filter := bson.M{}
+	if query.Operation != "" {
+		filter["operation"] = query.Operation
+	}
+	if query.UserIP != "" {
+		filter["user_ip"] = query.UserIP
+	}
+
+	timestamp := bson.M{}
+	if !query.From.IsZero() {
+		timestamp["$gte"] = query.From
+	}
+	if !query.To.IsZero() {
+		timestamp["$lte"] = query.To
+	}
+	if len(timestamp) > 0 {
+		filter["timestamp"] = timestamp
+	}
+
+	if query.Text != "" {
+		filter["input"] = bson.M{"$regex": regexp.QuoteMeta(query.Text), "$options": "i"}
+	}
+	return filter
+}
+
+// scopeFilter возвращает фильтр по владельцу результатов. Выборку
+// пользователя с сортировкой по дате обслуживает индекс (user_id, created_at, _id).
+func scopeFilter(scope storage.Scope) (bson.M, error) {
+	if err := scope.Validate(); err != nil {
+		return nil, err
+	}
+	filter := bson.M{}
+	if owner, restricted := scope.Owner(); restricted {
+		filter["user_id"] = owner
+	}
+	return filter, nil
+}
+
+// findOne возвращает первый документ, подходящий под фильтр, или storage.ErrNotFound
+func findOne[T any](ctx context.Context, coll *mongo.Collection, filter bson.M) (T, error) {
+	var doc T
+	err := coll.FindOne(ctx, filter).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		var zero T
+		return zero, storage.ErrNotFound
+	}
+	return doc, err
+}
@@ -0,0 +1,87 @@
+package sqlstore
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Поддерживаемые SQL драйверы
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+)
+
+// dialect описывает различия между поддерживаемыми СУБД
+type dialect struct {
+	// name - имя каталога миграций
+	name string
+	// driver - имя драйвера database/sql
+	driver string
+	// numberedParams - плейсхолдеры вида $1, $2 вместо ?
+	numberedParams bool
+	// limitRequiredForOffset - OFFSET допустим только вместе с LIMIT
+	limitRequiredForOffset bool
+}
+
+var dialects = map[string]dialect{
+	DriverSQLite:   {name: "sqlite", driver: "sqlite", limitRequiredForOffset: true},
+	DriverPostgres: {name: "postgres", driver: "pgx", numberedParams: true},
+}
+
+func dialectFor(driver string) (dialect, error) {
+	d, ok := dialects[driver]
+	if !ok {
+		return dialect{}, fmt.Errorf("неподдерживаемый SQL драйвер %q (sqlite или postgres)", driver)
+	}
+	return d, nil
+}
+
+// rebind заменяет плейсхолдеры ? на синтаксис диалекта
+func (d dialect) rebind(query string) string {
+	if !d.numberedParams {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// limitOffset возвращает выражение LIMIT/OFFSET; limit = 0 - без ограничения
+func (d dialect) limitOffset(limit, offset int) string {
+	var clause string
+	if limit > 0 {
+		clause = " LIMIT " + strconv.Itoa(limit)
+	} else if offset > 0 && d.limitRequiredForOffset {
+		clause = " LIMIT -1"
+	}
+	if offset > 0 {
+		clause += " OFFSET " + strconv.Itoa(offset)
+	}
+	return clause
+}
+
+// isUniqueViolation сообщает, нарушено ли ограничение уникальности
+func isUniqueViolation(err error) bool {
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE ||
+			sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY
+	}
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
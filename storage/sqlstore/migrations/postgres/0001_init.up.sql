@@ -0,0 +1,29 @@
+CREATE TABLE results (
+    id            TEXT PRIMARY KEY,
+    number1       DOUBLE PRECISION NOT NULL,
+    number2       DOUBLE PRECISION NOT NULL,
+    result        DOUBLE PRECISION NOT NULL,
+    operation     TEXT NOT NULL,
+    created_at    TIMESTAMPTZ NOT NULL,
+    kind          TEXT NOT NULL DEFAULT '',
+    precision     TEXT NOT NULL DEFAULT '',
+    number1_exact TEXT NOT NULL DEFAULT '',
+    number2_exact TEXT NOT NULL DEFAULT '',
+    result_exact  TEXT NOT NULL DEFAULT '',
+    expression    TEXT NOT NULL DEFAULT '',
+    normalized    TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX results_operation_created_at ON results (operation, created_at DESC);
+CREATE INDEX results_created_at ON results (created_at DESC);
+
+CREATE TABLE logs (
+    id        TEXT PRIMARY KEY,
+    operation TEXT NOT NULL,
+    input     TEXT NOT NULL,
+    result    TEXT NOT NULL,
+    user_ip   TEXT NOT NULL,
+    timestamp TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX logs_operation_timestamp ON logs (operation, timestamp DESC);
@@ -0,0 +1 @@
+ALTER TABLE logs ADD COLUMN request_id TEXT NOT NULL DEFAULT '';
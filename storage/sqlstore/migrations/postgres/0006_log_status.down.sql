@@ -0,0 +1,4 @@
+ALTER TABLE logs DROP COLUMN duration_ns;
+ALTER TABLE logs DROP COLUMN user_agent;
+ALTER TABLE logs DROP COLUMN error;
+ALTER TABLE logs DROP COLUMN status;
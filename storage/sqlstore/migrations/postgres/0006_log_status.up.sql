@@ -0,0 +1,4 @@
+ALTER TABLE logs ADD COLUMN status TEXT NOT NULL DEFAULT '';
+ALTER TABLE logs ADD COLUMN error TEXT NOT NULL DEFAULT '';
+ALTER TABLE logs ADD COLUMN user_agent TEXT NOT NULL DEFAULT '';
+ALTER TABLE logs ADD COLUMN duration_ns BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,10 @@
+ALTER TABLE results ADD COLUMN number1_exact TEXT NOT NULL DEFAULT '';
+ALTER TABLE results ADD COLUMN number2_exact TEXT NOT NULL DEFAULT '';
+
+UPDATE results SET
+    number1_exact = COALESCE(operands::jsonb -> 0 ->> 'exact', ''),
+    number2_exact = COALESCE(operands::jsonb -> 1 ->> 'exact', '')
+WHERE operands != '';
+
+ALTER TABLE results DROP COLUMN operands;
+ALTER TABLE results DROP COLUMN schema_version;
@@ -0,0 +1,18 @@
+ALTER TABLE results ADD COLUMN schema_version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE results ADD COLUMN operands TEXT NOT NULL DEFAULT '';
+
+-- Операнды переносятся в массив JSON. number1 и number2 остаются
+-- столбцами сортировки по первому и второму операнду.
+UPDATE results SET
+    operands = CASE
+        WHEN operation = 'evaluate' THEN '[]'
+        WHEN operation = 'square' THEN json_build_array(
+            json_build_object('value', number1, 'exact', number1_exact))::text
+        ELSE json_build_array(
+            json_build_object('value', number1, 'exact', number1_exact),
+            json_build_object('value', number2, 'exact', number2_exact))::text
+    END,
+    schema_version = 2;
+
+ALTER TABLE results DROP COLUMN number2_exact;
+ALTER TABLE results DROP COLUMN number1_exact;
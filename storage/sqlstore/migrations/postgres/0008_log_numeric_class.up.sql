@@ -0,0 +1 @@
+ALTER TABLE logs ADD COLUMN numeric_class TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,4 @@
+ALTER TABLE logs DROP COLUMN user_id;
+ALTER TABLE results DROP COLUMN user_id;
+DROP TABLE tokens;
+DROP TABLE users;
@@ -0,0 +1,2 @@
+DROP INDEX results_user_created_at;
+ALTER TABLE users DROP COLUMN role;
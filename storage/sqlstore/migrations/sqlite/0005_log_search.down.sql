@@ -0,0 +1,3 @@
+DROP INDEX logs_user_ip_timestamp;
+DROP INDEX logs_timestamp;
+ALTER TABLE logs DROP COLUMN result_id;
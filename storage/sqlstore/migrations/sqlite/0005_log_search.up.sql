@@ -0,0 +1,4 @@
+ALTER TABLE logs ADD COLUMN result_id TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX logs_timestamp ON logs (timestamp DESC);
+CREATE INDEX logs_user_ip_timestamp ON logs (user_ip, timestamp DESC);
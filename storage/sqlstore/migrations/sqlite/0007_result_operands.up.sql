@@ -0,0 +1,20 @@
+ALTER TABLE results ADD COLUMN schema_version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE results ADD COLUMN operands TEXT NOT NULL DEFAULT '';
+
+-- Операнды переносятся в массив JSON. number1 и number2 остаются
+-- столбцами сортировки по первому и второму операнду. json_object
+-- записывает REAL с 15 значащими цифрами, поэтому значения передаются
+-- записью с 17 цифрами - ее достаточно, чтобы прочитать float64 без потерь.
+UPDATE results SET
+    operands = CASE
+        WHEN operation = 'evaluate' THEN '[]'
+        WHEN operation = 'square' THEN json_array(
+            json_object('value', json(printf('%!.17g', number1)), 'exact', number1_exact))
+        ELSE json_array(
+            json_object('value', json(printf('%!.17g', number1)), 'exact', number1_exact),
+            json_object('value', json(printf('%!.17g', number2)), 'exact', number2_exact))
+    END,
+    schema_version = 2;
+
+ALTER TABLE results DROP COLUMN number2_exact;
+ALTER TABLE results DROP COLUMN number1_exact;
@@ -0,0 +1,552 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/igor-fedko/go_multiply_app/models"
+	"github.com/igor-fedko/go_multiply_app/storage"
+	_ "github.com/jackc/pgx/v5/stdlib" // драйвер PostgreSQL
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	_ "modernc.org/sqlite" // драйвер SQLite на чистом Go
+)
+
+// Store - хранилище в SQL базе данных (SQLite или PostgreSQL).
+// Идентификаторы записей хранятся как шестнадцатеричные ObjectID,
+// чтобы API не зависел от выбранного хранилища.
+type Store struct {
+	db         *sql.DB
+	dialect    dialect
+	migrations []Migration
+}
+
+var (
+	_ storage.Store    = (*Store)(nil)
+	_ storage.Migrator = (*Store)(nil)
+)
+
+// resultColumns - столбцы результатов. Операнды хранятся массивом JSON
+// в operands; number1 и number2 дублируют первые два операнда для сортировки.
+const resultColumns = "id, schema_version, operands, number1, number2, result, operation, created_at, kind, precision, " +
+	"result_exact, expression, normalized, user_id"
+
+const logColumns = "id, operation, input, result, user_ip, timestamp, request_id, user_id, result_id, " +
+	"status, error, user_agent, duration_ns, numeric_class"
+
+const userColumns = "id, username, password_hash, role, locale, created_at"
+
+const tokenColumns = "id, user_id, kind, name, hash, created_at, expires_at"
+
+// Open открывает соединение с базой данных. Схема не создается:
+// перед использованием нужно вызвать MigrateUp.
+func Open(ctx context.Context, driver, dsn string) (*Store, error) {
+	d, err := dialectFor(driver)
+	if err != nil {
+		return nil, err
+	}
+	migrations, err := loadMigrations(d)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open(d.driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	if driver == DriverSQLite {
+		// SQLite не поддерживает параллельную запись из нескольких соединений
+		db.SetMaxOpenConns(1)
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &Store{db: db, dialect: d, migrations: migrations}, nil
+}
+
+// insertResultSQL - запрос вставки результата
+const insertResultSQL = "INSERT INTO results (" + resultColumns + ") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+
+// execer выполняет запросы вне транзакции (*sql.DB) или в ней (*sql.Tx)
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// InsertResult сохраняет результат и заполняет его ID
+func (s *Store) InsertResult(ctx context.Context, result *models.Result) error {
+	return s.insertResult(ctx, s.db, result)
+}
+
+// InsertResults сохраняет результаты в одной транзакции и заполняет их ID
+func (s *Store) InsertResults(ctx context.Context, results []*models.Result) error {
+	if len(results) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, result := range results {
+		if err := s.insertResult(ctx, tx, result); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// insertResult сохраняет результат через db
+func (s *Store) insertResult(ctx context.Context, db execer, result *models.Result) error {
+	if result.ID.IsZero() {
+		result.ID = primitive.NewObjectID()
+	}
+
+	operands, err := json.Marshal(result.Operands)
+	if err != nil {
+		return err
+	}
+	_, err = db.ExecContext(ctx, s.dialect.rebind(insertResultSQL),
+		result.ID.Hex(), result.SchemaVersion, string(operands),
+		result.OperandValue(0), result.OperandValue(1), result.Result, result.Operation,
+		result.CreatedAt.UTC(), result.Kind, result.Precision, result.ResultExact,
+		result.Expression, result.Normalized, idText(result.UserID),
+	)
+	return err
+}
+
+// GetResult возвращает результат по ID, если он входит в область scope
+func (s *Store) GetResult(ctx context.Context, scope storage.Scope, id primitive.ObjectID) (models.Result, error) {
+	conditions, args, err := scopeConditions(scope)
+	if err != nil {
+		return models.Result{}, err
+	}
+	conditions = append(conditions, "id = ?")
+	args = append(args, id.Hex())
+
+	row := s.db.QueryRowContext(ctx, s.dialect.rebind(
+		"SELECT "+resultColumns+" FROM results WHERE "+strings.Join(conditions, " AND ")), args...)
+
+	result, err := scanResult(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Result{}, storage.ErrNotFound
+	}
+	return result, err
+}
+
+// ListResults возвращает отфильтрованную, отсортированную страницу результатов
+func (s *Store) ListResults(ctx context.Context, query storage.ResultQuery) ([]models.Result, error) {
+	results := []models.Result{}
+	err := s.EachResult(ctx, query, func(result models.Result) error {
+		results = append(results, result)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// EachResult читает результаты запроса построчно и передает их в fn по одному
+func (s *Store) EachResult(ctx context.Context, query storage.ResultQuery, fn func(models.Result) error) error {
+	where, args, err := resultWhere(query)
+	if err != nil {
+		return err
+	}
+
+	sortField := query.SortBy
+	if !storage.ValidSortField(sortField) {
+		sortField = storage.SortCreatedAt
+	}
+	direction := " DESC"
+	if query.SortAsc {
+		direction = " ASC"
+	}
+
+	q := "SELECT " + resultColumns + " FROM results" + where +
+		" ORDER BY " + sortField + direction + ", id" + direction +
+		s.dialect.limitOffset(query.Limit, query.Offset)
+
+	rows, err := s.db.QueryContext(ctx, s.dialect.rebind(q), args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		result, err := scanResult(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(result); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// CountResults возвращает число результатов, удовлетворяющих фильтрам
+func (s *Store) CountResults(ctx context.Context, query storage.ResultQuery) (int64, error) {
+	where, args, err := resultWhere(query)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int64
+	err = s.db.QueryRowContext(ctx, s.dialect.rebind("SELECT COUNT(*) FROM results"+where), args...).Scan(&count)
+	return count, err
+}
+
+// InsertLog сохраняет запись журнала
+func (s *Store) InsertLog(ctx context.Context, entry *models.LogEntry) error {
+	if entry.ID.IsZero() {
+		entry.ID = primitive.NewObjectID()
+	}
+
+	_, err := s.db.ExecContext(ctx, s.dialect.rebind(
+		"INSERT INTO logs ("+logColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"),
+		entry.ID.Hex(), entry.Operation, entry.Input, entry.Result, entry.UserIP, entry.Timestamp.UTC(),
+		entry.RequestID, idText(entry.UserID), idText(entry.ResultID),
+		entry.Status, entry.Error, entry.UserAgent, int64(entry.Duration), entry.NumericClass,
+	)
+	return err
+}
+
+// GetLog возвращает запись журнала по ID
+func (s *Store) GetLog(ctx context.Context, id primitive.ObjectID) (models.LogEntry, error) {
+	row := s.db.QueryRowContext(ctx, s.dialect.rebind("SELECT "+logColumns+" FROM logs WHERE id = ?"), id.Hex())
+
+	entry, err := scanLog(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.LogEntry{}, storage.ErrNotFound
+	}
+	return entry, err
+}
+
+// ListLogs возвращает страницу записей журнала, новые первыми
+func (s *Store) ListLogs(ctx context.Context, query storage.LogQuery) ([]models.LogEntry, error) {
+	where, args := logWhere(query)
+	q := "SELECT " + logColumns + " FROM logs" + where +
+		" ORDER BY timestamp DESC, id DESC" + s.dialect.limitOffset(query.Limit, query.Offset)
+
+	rows, err := s.db.QueryContext(ctx, s.dialect.rebind(q), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []models.LogEntry{}
+	for rows.Next() {
+		entry, err := scanLog(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// CountLogs возвращает число записей журнала, удовлетворяющих фильтрам
+func (s *Store) CountLogs(ctx context.Context, query storage.LogQuery) (int64, error) {
+	where, args := logWhere(query)
+
+	var count int64
+	err := s.db.QueryRowContext(ctx, s.dialect.rebind("SELECT COUNT(*) FROM logs"+where), args...).Scan(&count)
+	return count, err
+}
+
+// CreateUser сохраняет пользователя; уникальность имени обеспечивает ограничение UNIQUE
+func (s *Store) CreateUser(ctx context.Context, user *models.User) error {
+	if user.ID.IsZero() {
+		user.ID = primitive.NewObjectID()
+	}
+
+	_, err := s.db.ExecContext(ctx, s.dialect.rebind(
+		"INSERT INTO users ("+userColumns+") VALUES (?, ?, ?, ?, ?, ?)"),
+		user.ID.Hex(), user.Username, user.PasswordHash, user.Role, user.Locale, user.CreatedAt.UTC(),
+	)
+	if isUniqueViolation(err) {
+		return storage.ErrDuplicate
+	}
+	return err
+}
+
+// GetUser возвращает пользователя по ID
+func (s *Store) GetUser(ctx context.Context, id primitive.ObjectID) (models.User, error) {
+	return s.getUser(ctx, "id", id.Hex())
+}
+
+// GetUserByUsername возвращает пользователя по имени
+func (s *Store) GetUserByUsername(ctx context.Context, username string) (models.User, error) {
+	return s.getUser(ctx, "username", username)
+}
+
+func (s *Store) getUser(ctx context.Context, column, value string) (models.User, error) {
+	row := s.db.QueryRowContext(ctx, s.dialect.rebind("SELECT "+userColumns+" FROM users WHERE "+column+" = ?"), value)
+
+	var u models.User
+	var id string
+	err := row.Scan(&id, &u.Username, &u.PasswordHash, &u.Role, &u.Locale, &u.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.User{}, storage.ErrNotFound
+	}
+	if err != nil {
+		return models.User{}, err
+	}
+	if u.ID, err = primitive.ObjectIDFromHex(id); err != nil {
+		return models.User{}, err
+	}
+	u.CreatedAt = u.CreatedAt.UTC()
+	return u, nil
+}
+
+// CreateToken сохраняет токен
+func (s *Store) CreateToken(ctx context.Context, token *models.Token) error {
+	if token.ID.IsZero() {
+		token.ID = primitive.NewObjectID()
+	}
+
+	var expiresAt sql.NullTime
+	if !token.ExpiresAt.IsZero() {
+		expiresAt = sql.NullTime{Time: token.ExpiresAt.UTC(), Valid: true}
+	}
+
+	_, err := s.db.ExecContext(ctx, s.dialect.rebind(
+		"INSERT INTO tokens ("+tokenColumns+") VALUES (?, ?, ?, ?, ?, ?, ?)"),
+		token.ID.Hex(), token.UserID.Hex(), token.Kind, token.Name, token.Hash, token.CreatedAt.UTC(), expiresAt,
+	)
+	return err
+}
+
+// GetTokenByHash возвращает токен по хешу
+func (s *Store) GetTokenByHash(ctx context.Context, hash string) (models.Token, error) {
+	row := s.db.QueryRowContext(ctx, s.dialect.rebind("SELECT "+tokenColumns+" FROM tokens WHERE hash = ?"), hash)
+
+	var t models.Token
+	var id, userID string
+	var expiresAt sql.NullTime
+	err := row.Scan(&id, &userID, &t.Kind, &t.Name, &t.Hash, &t.CreatedAt, &expiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Token{}, storage.ErrNotFound
+	}
+	if err != nil {
+		return models.Token{}, err
+	}
+
+	if t.ID, err = primitive.ObjectIDFromHex(id); err != nil {
+		return models.Token{}, err
+	}
+	if t.UserID, err = primitive.ObjectIDFromHex(userID); err != nil {
+		return models.Token{}, err
+	}
+	t.CreatedAt = t.CreatedAt.UTC()
+	if expiresAt.Valid {
+		t.ExpiresAt = expiresAt.Time.UTC()
+	}
+	return t, nil
+}
+
+// DeleteToken удаляет токен по хешу
+func (s *Store) DeleteToken(ctx context.Context, hash string) error {
+	_, err := s.db.ExecContext(ctx, s.dialect.rebind("DELETE FROM tokens WHERE hash = ?"), hash)
+	return err
+}
+
+// SetUserRole назначает роль пользователю
+func (s *Store) SetUserRole(ctx context.Context, username, role string) error {
+	res, err := s.db.ExecContext(ctx, s.dialect.rebind("UPDATE users SET role = ? WHERE username = ?"), role, username)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+// SetUserLocale сохраняет локаль пользователя
+func (s *Store) SetUserLocale(ctx context.Context, id primitive.ObjectID, locale string) error {
+	res, err := s.db.ExecContext(ctx, s.dialect.rebind("UPDATE users SET locale = ? WHERE id = ?"), locale, id.Hex())
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+// Ping проверяет соединение с базой данных
+func (s *Store) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+// Close закрывает соединение с базой данных
+func (s *Store) Close(context.Context) error {
+	return s.db.Close()
+}
+
+// resultWhere строит условие WHERE по фильтрам запроса
+func resultWhere(query storage.ResultQuery) (string, []any, error) {
+	conditions, args, err := scopeConditions(query.Scope)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if query.Operation != "" {
+		conditions = append(conditions, "operation = ?")
+		args = append(args, query.Operation)
+	}
+	if !query.From.IsZero() {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, query.From.UTC())
+	}
+	if !query.To.IsZero() {
+		conditions = append(conditions, "created_at <= ?")
+		args = append(args, query.To.UTC())
+	}
+
+	if len(conditions) == 0 {
+		return "", nil, nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args, nil
+}
+
+// logWhere строит условие WHERE по фильтрам поиска в журнале.
+// Текст ищется без учета регистра; SQLite приводит к нижнему регистру
+// только латиницу.
+func logWhere(query storage.LogQuery) (string, []any) {
+	var conditions []string
+	var args []any
+
+	if query.Operation != "" {
+		conditions = append(conditions, "operation = ?")
+		args = append(args, query.Operation)
+	}
+	if query.UserIP != "" {
+		conditions = append(conditions, "user_ip = ?")
+		args = append(args, query.UserIP)
+	}
+	if !query.From.IsZero() {
+		conditions = append(conditions, "timestamp >= ?")
+		args = append(args, query.From.UTC())
+	}
+	if !query.To.IsZero() {
+		conditions = append(conditions, "timestamp <= ?")
+		args = append(args, query.To.UTC())
+	}
+	if query.Text != "" {
+		conditions = append(conditions, `LOWER(input) LIKE ? ESCAPE '\'`)
+		args = append(args, "%"+likeEscaper.Replace(strings.ToLower(query.Text))+"%")
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// likeEscaper экранирует спецсимволы шаблона LIKE
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// scopeConditions возвращает условие по владельцу результатов
+func scopeConditions(scope storage.Scope) ([]string, []any, error) {
+	if err := scope.Validate(); err != nil {
+		return nil, nil, err
+	}
+	if owner, restricted := scope.Owner(); restricted {
+		return []string{"user_id = ?"}, []any{owner.Hex()}, nil
+	}
+	return nil, nil, nil
+}
+
+// rowScanner - общий интерфейс sql.Row и sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanResult(row rowScanner) (models.Result, error) {
+	var r models.Result
+	var id, userID, operands string
+	var number1, number2 float64 // столбцы сортировки
+	var createdAt time.Time
+
+	err := row.Scan(&id, &r.SchemaVersion, &operands, &number1, &number2, &r.Result, &r.Operation, &createdAt,
+		&r.Kind, &r.Precision, &r.ResultExact, &r.Expression, &r.Normalized, &userID)
+	if err != nil {
+		return models.Result{}, err
+	}
+
+	if err := json.Unmarshal([]byte(operands), &r.Operands); err != nil {
+		return models.Result{}, err
+	}
+	if len(r.Operands) == 0 {
+		r.Operands = nil
+	}
+
+	if r.ID, err = primitive.ObjectIDFromHex(id); err != nil {
+		return models.Result{}, err
+	}
+	if userID != "" {
+		if r.UserID, err = primitive.ObjectIDFromHex(userID); err != nil {
+			return models.Result{}, err
+		}
+	}
+	r.CreatedAt = createdAt.UTC()
+	return r, nil
+}
+
+func scanLog(row rowScanner) (models.LogEntry, error) {
+	var e models.LogEntry
+	var id, userID, resultID string
+	var duration int64
+
+	err := row.Scan(&id, &e.Operation, &e.Input, &e.Result, &e.UserIP, &e.Timestamp,
+		&e.RequestID, &userID, &resultID, &e.Status, &e.Error, &e.UserAgent, &duration, &e.NumericClass)
+	if err != nil {
+		return models.LogEntry{}, err
+	}
+
+	if e.ID, err = primitive.ObjectIDFromHex(id); err != nil {
+		return models.LogEntry{}, err
+	}
+	if e.UserID, err = parseIDText(userID); err != nil {
+		return models.LogEntry{}, err
+	}
+	if e.ResultID, err = parseIDText(resultID); err != nil {
+		return models.LogEntry{}, err
+	}
+	e.Timestamp = e.Timestamp.UTC()
+	e.Duration = time.Duration(duration)
+	return e, nil
+}
+
+// parseIDText разбирает ObjectID, записанный idText; пустая строка - нулевой ID
+func parseIDText(s string) (primitive.ObjectID, error) {
+	if s == "" {
+		return primitive.NilObjectID, nil
+	}
+	return primitive.ObjectIDFromHex(s)
+}
+
+// idText возвращает ObjectID в виде строки; нулевой ID - пустая строка
+func idText(id primitive.ObjectID) string {
+	if id.IsZero() {
+		return ""
+	}
+	return id.Hex()
+}
@@ -0,0 +1,328 @@
+package sqlstore
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/igor-fedko/go_multiply_app/models"
+	"github.com/igor-fedko/go_multiply_app/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func openSQLite(t *testing.T) *Store {
+	t.Helper()
+	ctx := context.Background()
+
+	s, err := Open(ctx, DriverSQLite, filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { s.Close(ctx) })
+
+	require.NoError(t, s.MigrateUp(ctx))
+	return s
+}
+
+func TestMigrations(t *testing.T) {
+	s := openSQLite(t)
+	ctx := context.Background()
+
+	// Повторное применение ничего не делает
+	require.NoError(t, s.MigrateUp(ctx))
+
+	statuses, err := s.MigrationStatus(ctx)
+	require.NoError(t, err)
+	require.NotEmpty(t, statuses)
+	for _, st := range statuses {
+		assert.True(t, st.Applied, st.Name)
+	}
+
+	// Откат последней миграции и повторное применение
+	require.NoError(t, s.MigrateDown(ctx))
+	statuses, err = s.MigrationStatus(ctx)
+	require.NoError(t, err)
+	assert.False(t, statuses[len(statuses)-1].Applied)
+	require.NoError(t, s.MigrateUp(ctx))
+}
+
+// migrateDownBelow откатывает миграции, пока версия version не станет непримененной
+func migrateDownBelow(t *testing.T, s *Store, version int) {
+	t.Helper()
+	ctx := context.Background()
+	for {
+		statuses, err := s.MigrationStatus(ctx)
+		require.NoError(t, err)
+		for _, st := range statuses {
+			if st.Version == version && !st.Applied {
+				return
+			}
+		}
+		require.NoError(t, s.MigrateDown(ctx))
+	}
+}
+
+func TestResultOperandsMigration(t *testing.T) {
+	s := openSQLite(t)
+	ctx := context.Background()
+
+	// Строки в схеме до переноса операндов в operands
+	migrateDownBelow(t, s, 7)
+	ids := []primitive.ObjectID{primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()}
+	created := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	rows := [][]any{
+		{ids[0].Hex(), 0.1, 0.2, 0.3, "add", created, models.PrecisionExact, "0.1", "0.2", "0.3", ""},
+		{ids[1].Hex(), -3.0, 0.0, 9.0, "square", created, models.PrecisionFloat, "", "", "", ""},
+		{ids[2].Hex(), 0.0, 0.0, 9.0, models.OperationEvaluate, created, models.PrecisionFloat, "", "", "", "(1 + 2) * 3"},
+		{ids[3].Hex(), 0.30000000000000004, 1e300 / 3, 0.1, "multiply", created, models.PrecisionFloat, "", "", "", ""},
+	}
+	for _, row := range rows {
+		_, err := s.db.ExecContext(ctx, "INSERT INTO results (id, number1, number2, result, operation, created_at, "+
+			"precision, number1_exact, number2_exact, result_exact, normalized) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)", row...)
+		require.NoError(t, err)
+	}
+	require.NoError(t, s.MigrateUp(ctx))
+
+	want := [][]models.Operand{
+		{{Value: 0.1, Exact: "0.1"}, {Value: 0.2, Exact: "0.2"}},
+		{{Value: -3}},
+		nil,
+		// Значения float64 переносятся без округления
+		{{Value: 0.30000000000000004}, {Value: 1e300 / 3}},
+	}
+	for i, id := range ids {
+		got, err := s.GetResult(ctx, storage.AllUsers(), id)
+		require.NoError(t, err)
+		assert.Equal(t, models.ResultSchemaOperands, got.SchemaVersion)
+		assert.Equal(t, want[i], got.Operands)
+	}
+
+	// Откат возвращает точные записи операндов в number1_exact и number2_exact
+	migrateDownBelow(t, s, 7)
+	var exact1, exact2 string
+	require.NoError(t, s.db.QueryRowContext(ctx, "SELECT number1_exact, number2_exact FROM results WHERE id = ?", ids[0].Hex()).
+		Scan(&exact1, &exact2))
+	assert.Equal(t, []string{"0.1", "0.2"}, []string{exact1, exact2})
+}
+
+func TestResults(t *testing.T) {
+	s := openSQLite(t)
+	ctx := context.Background()
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	results := []models.Result{
+		{Operands: []models.Operand{{Value: 2}, {Value: 3}}, Result: 6, Operation: "multiply", CreatedAt: base},
+		{Operands: []models.Operand{{Value: 10}, {Value: 2}}, Result: 5, Operation: "divide", CreatedAt: base.Add(time.Hour)},
+		{Operands: []models.Operand{{Value: 0.1, Exact: "0.1"}, {Value: 0.2, Exact: "0.2"}}, Result: 0.3, Operation: "add",
+			CreatedAt: base.Add(2 * time.Hour), Precision: models.PrecisionExact, ResultExact: "0.3",
+			UserID: primitive.NewObjectID()},
+		{Operands: []models.Operand{{Value: 4}, {Value: 5}}, Result: 20, Operation: "multiply", CreatedAt: base.Add(3 * time.Hour)},
+	}
+	for i := range results {
+		require.NoError(t, s.InsertResult(ctx, &results[i]))
+	}
+
+	got, err := s.GetResult(ctx, storage.AllUsers(), results[2].ID)
+	require.NoError(t, err)
+	assert.Equal(t, results[2], got)
+
+	_, err = s.GetResult(ctx, storage.AllUsers(), [12]byte{1})
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+
+	list, err := s.ListResults(ctx, storage.ResultQuery{Scope: storage.AllUsers()})
+	require.NoError(t, err)
+	require.Len(t, list, 4)
+	assert.Equal(t, float64(20), list[0].Result)
+
+	list, err = s.ListResults(ctx, storage.ResultQuery{Scope: storage.AllUsers(), Operation: "multiply", SortBy: storage.SortResult, SortAsc: true})
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, float64(6), list[0].Result)
+
+	list, err = s.ListResults(ctx, storage.ResultQuery{Scope: storage.AllUsers(), SortBy: storage.SortNumber1, Offset: 1})
+	require.NoError(t, err)
+	require.Len(t, list, 3)
+	assert.Equal(t, float64(4), list[0].OperandValue(0))
+
+	count, err := s.CountResults(ctx, storage.ResultQuery{Scope: storage.AllUsers(), From: base.Add(time.Hour), To: base.Add(2 * time.Hour), Limit: 1})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	// Область пользователя: только его результаты
+	scope := storage.UserScope(results[2].UserID)
+	count, err = s.CountResults(ctx, storage.ResultQuery{Scope: scope})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+	_, err = s.GetResult(ctx, scope, results[0].ID)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+	_, err = s.ListResults(ctx, storage.ResultQuery{})
+	assert.ErrorIs(t, err, storage.ErrNoScope)
+}
+
+func TestInsertResults(t *testing.T) {
+	s := openSQLite(t)
+	ctx := context.Background()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	results := []*models.Result{
+		{Operands: []models.Operand{{Value: 2}, {Value: 3}}, Result: 6, Operation: "multiply", CreatedAt: now},
+		{Operands: []models.Operand{{Value: 1}, {Value: 2}}, Result: 3, Operation: "add", CreatedAt: now},
+	}
+	require.NoError(t, s.InsertResults(ctx, results))
+	for _, r := range results {
+		assert.False(t, r.ID.IsZero())
+	}
+
+	count, err := s.CountResults(ctx, storage.ResultQuery{Scope: storage.AllUsers()})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	// При ошибке транзакция откатывается целиком
+	duplicate := []*models.Result{
+		{Operation: "add", CreatedAt: now},
+		{ID: results[0].ID, Operation: "add", CreatedAt: now},
+	}
+	assert.Error(t, s.InsertResults(ctx, duplicate))
+	count, err = s.CountResults(ctx, storage.ResultQuery{Scope: storage.AllUsers()})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+}
+
+func TestEachResult(t *testing.T) {
+	s := openSQLite(t)
+	ctx := context.Background()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	for i := range 5 {
+		require.NoError(t, s.InsertResult(ctx, &models.Result{Result: float64(i), Operation: "add", CreatedAt: now}))
+	}
+
+	var values []float64
+	query := storage.ResultQuery{Scope: storage.AllUsers(), SortBy: storage.SortResult, SortAsc: true}
+	require.NoError(t, s.EachResult(ctx, query, func(r models.Result) error {
+		values = append(values, r.Result)
+		return nil
+	}))
+	assert.Equal(t, []float64{0, 1, 2, 3, 4}, values)
+
+	// Ошибка fn прекращает чтение
+	stop := errors.New("stop")
+	calls := 0
+	err := s.EachResult(ctx, query, func(models.Result) error {
+		calls++
+		return stop
+	})
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, 1, calls)
+}
+
+func TestInsertLog(t *testing.T) {
+	s := openSQLite(t)
+
+	entry := models.LogEntry{Operation: "add", Input: "1 + 2", Result: "3", UserIP: "127.0.0.1", Timestamp: time.Now()}
+	require.NoError(t, s.InsertLog(context.Background(), &entry))
+	assert.False(t, entry.ID.IsZero())
+}
+
+func TestLogs(t *testing.T) {
+	s := openSQLite(t)
+	ctx := context.Background()
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	resultID := primitive.NewObjectID()
+
+	entries := []models.LogEntry{
+		{Operation: "add", Input: "1 + 2", Result: "3", UserIP: "127.0.0.1", Timestamp: base, ResultID: resultID,
+			Status: models.LogStatusSuccess, UserAgent: "curl/8.0", Duration: 1500 * time.Microsecond, NumericClass: "finite"},
+		{Operation: "evaluate", Input: "SQRT(16) + 1", UserIP: "10.0.0.1", Timestamp: base.Add(time.Hour),
+			Status: models.LogStatusValidationError, Error: "Неизвестная функция SQRT"},
+		{Operation: "add", Input: "50% + 1", Result: "2", UserIP: "10.0.0.1", Timestamp: base.Add(2 * time.Hour)},
+	}
+	for i := range entries {
+		require.NoError(t, s.InsertLog(ctx, &entries[i]))
+	}
+
+	entry, err := s.GetLog(ctx, entries[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, entries[0], entry)
+	_, err = s.GetLog(ctx, primitive.NewObjectID())
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+
+	list, err := s.ListLogs(ctx, storage.LogQuery{})
+	require.NoError(t, err)
+	require.Len(t, list, 3)
+	assert.Equal(t, entries[2].ID, list[0].ID)
+
+	tests := map[string]struct {
+		query storage.LogQuery
+		count int64
+	}{
+		"operation": {storage.LogQuery{Operation: "add"}, 2},
+		"ip":        {storage.LogQuery{UserIP: "10.0.0.1"}, 2},
+		"text":      {storage.LogQuery{Text: "sqrt"}, 1},
+		"wildcard":  {storage.LogQuery{Text: "%"}, 1},
+		"range":     {storage.LogQuery{From: base.Add(time.Minute), To: base.Add(time.Hour)}, 1},
+	}
+	for name, tt := range tests {
+		count, err := s.CountLogs(ctx, tt.query)
+		require.NoError(t, err, name)
+		assert.Equal(t, tt.count, count, name)
+	}
+
+	list, err = s.ListLogs(ctx, storage.LogQuery{Offset: 1, Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, entries[1].ID, list[0].ID)
+}
+
+func TestUsers(t *testing.T) {
+	s := openSQLite(t)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	user := models.User{Username: "alice", PasswordHash: "hash", Role: models.RoleUser, CreatedAt: now}
+	require.NoError(t, s.CreateUser(ctx, &user))
+
+	duplicate := models.User{Username: "alice", PasswordHash: "other", CreatedAt: now}
+	assert.ErrorIs(t, s.CreateUser(ctx, &duplicate), storage.ErrDuplicate)
+
+	got, err := s.GetUserByUsername(ctx, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, user, got)
+	_, err = s.GetUser(ctx, primitive.NewObjectID())
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+
+	require.NoError(t, s.SetUserRole(ctx, "alice", models.RoleAdmin))
+	got, err = s.GetUser(ctx, user.ID)
+	require.NoError(t, err)
+	assert.True(t, got.IsAdmin())
+	assert.ErrorIs(t, s.SetUserRole(ctx, "bob", models.RoleAdmin), storage.ErrNotFound)
+
+	require.NoError(t, s.SetUserLocale(ctx, user.ID, "en"))
+	got, err = s.GetUser(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "en", got.Locale)
+	assert.ErrorIs(t, s.SetUserLocale(ctx, primitive.NewObjectID(), "en"), storage.ErrNotFound)
+
+	session := models.Token{UserID: user.ID, Kind: models.TokenSession, Hash: "h1", CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+	api := models.Token{UserID: user.ID, Kind: models.TokenAPI, Name: "ci", Hash: "h2", CreatedAt: now}
+	require.NoError(t, s.CreateToken(ctx, &session))
+	require.NoError(t, s.CreateToken(ctx, &api))
+
+	token, err := s.GetTokenByHash(ctx, "h1")
+	require.NoError(t, err)
+	assert.Equal(t, session, token)
+	token, err = s.GetTokenByHash(ctx, "h2")
+	require.NoError(t, err)
+	assert.True(t, token.ExpiresAt.IsZero())
+
+	require.NoError(t, s.DeleteToken(ctx, "h2"))
+	_, err = s.GetTokenByHash(ctx, "h2")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+}
+
+func TestRebind(t *testing.T) {
+	assert.Equal(t, "a = $1 AND b = $2", dialects[DriverPostgres].rebind("a = ? AND b = ?"))
+	assert.Equal(t, "a = ?", dialects[DriverSQLite].rebind("a = ?"))
+}
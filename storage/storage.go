@@ -0,0 +1,228 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/igor-fedko/go_multiply_app/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrNotFound возвращается, если запись не найдена
+var ErrNotFound = errors.New("запись не найдена")
+
+// ErrDuplicate возвращается при нарушении уникальности, например
+// при регистрации занятого имени пользователя
+var ErrDuplicate = errors.New("запись уже существует")
+
+// PartialInsertError возвращается из InsertResults, если сохранены только
+// первые Inserted результатов среза; остальные не сохранены
+type PartialInsertError struct {
+	Inserted int
+	Err      error
+}
+
+func (e *PartialInsertError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PartialInsertError) Unwrap() error {
+	return e.Err
+}
+
+// InsertedBefore возвращает число первых результатов, сохраненных
+// InsertResults до ошибки err: Inserted для *PartialInsertError, иначе 0
+func InsertedBefore(err error) int {
+	var partial *PartialInsertError
+	if errors.As(err, &partial) {
+		return partial.Inserted
+	}
+	return 0
+}
+
+// ErrNoScope возвращается, если в запросе к результатам не указана
+// область видимости
+var ErrNoScope = errors.New("не указана область видимости результатов")
+
+// Scope - область видимости результатов: результаты одного пользователя
+// или всех пользователей. Нулевое значение не дает доступа ни к каким
+// результатам - хранилище возвращает ErrNoScope, поэтому забытая проверка
+// прав не раскрывает чужую историю.
+type Scope struct {
+	userID primitive.ObjectID
+	all    bool
+}
+
+// UserScope возвращает область результатов пользователя id
+func UserScope(id primitive.ObjectID) Scope {
+	return Scope{userID: id}
+}
+
+// AllUsers возвращает область результатов всех пользователей,
+// включая результаты без владельца
+func AllUsers() Scope {
+	return Scope{all: true}
+}
+
+// Validate возвращает ErrNoScope для пустой области
+func (s Scope) Validate() error {
+	if !s.all && s.userID.IsZero() {
+		return ErrNoScope
+	}
+	return nil
+}
+
+// Owner возвращает пользователя, результатами которого ограничена область;
+// restricted = false означает результаты всех пользователей
+func (s Scope) Owner() (id primitive.ObjectID, restricted bool) {
+	return s.userID, !s.all
+}
+
+// Contains сообщает, входит ли результат владельца owner в область
+func (s Scope) Contains(owner primitive.ObjectID) bool {
+	return s.all || (!s.userID.IsZero() && s.userID == owner)
+}
+
+// Поля, по которым можно сортировать результаты
+const (
+	SortCreatedAt = "created_at"
+	SortNumber1   = "number1"
+	SortNumber2   = "number2"
+	SortResult    = "result"
+	SortOperation = "operation"
+)
+
+// ResultQuery - параметры выборки результатов
+type ResultQuery struct {
+	// Scope - чьи результаты выбираются; обязательна
+	Scope Scope
+	// Operation - фильтр по операции; пустая строка означает все операции
+	Operation string
+	// From и To ограничивают created_at (включительно); нулевое значение - без ограничения
+	From, To time.Time
+	// SortBy - поле сортировки; пустая строка означает created_at
+	SortBy string
+	// SortAsc - сортировка по возрастанию; по умолчанию новые записи первыми
+	SortAsc bool
+	// Offset и Limit задают страницу выборки; Limit = 0 - без ограничения
+	Offset int
+	Limit  int
+}
+
+// ValidSortField сообщает, поддерживается ли сортировка по полю
+func ValidSortField(field string) bool {
+	switch field {
+	case SortCreatedAt, SortNumber1, SortNumber2, SortResult, SortOperation:
+		return true
+	}
+	return false
+}
+
+// ResultStore хранит результаты операций
+type ResultStore interface {
+	// InsertResult сохраняет результат и заполняет его ID
+	InsertResult(ctx context.Context, result *models.Result) error
+	// InsertResults сохраняет результаты одним обращением к хранилищу
+	// и заполняет их ID. Результаты сохраняются по порядку. SQL и память
+	// сохраняют все результаты или ни одного; MongoDB пишет без транзакции
+	// и при ошибке может сохранить начало среза - тогда возвращается
+	// *PartialInsertError с числом сохраненных результатов. Другая ошибка
+	// означает, что не сохранен ни один результат, или, если хранилище
+	// не смогло это проверить (например, при обрыве соединения), что
+	// исход записи неизвестен.
+	InsertResults(ctx context.Context, results []*models.Result) error
+	// GetResult возвращает результат по ID или ErrNotFound,
+	// в том числе если результат не входит в область scope
+	GetResult(ctx context.Context, scope Scope, id primitive.ObjectID) (models.Result, error)
+	// ListResults возвращает страницу результатов, удовлетворяющих запросу
+	ListResults(ctx context.Context, query ResultQuery) ([]models.Result, error)
+	// CountResults возвращает число результатов, удовлетворяющих фильтрам запроса
+	// (Offset и Limit не учитываются)
+	CountResults(ctx context.Context, query ResultQuery) (int64, error)
+	// EachResult передает в fn результаты запроса по одному по мере чтения
+	// из хранилища, не загружая выборку в память целиком. Ошибка fn
+	// прекращает чтение и возвращается из EachResult.
+	EachResult(ctx context.Context, query ResultQuery, fn func(models.Result) error) error
+}
+
+// LogQuery - параметры поиска в журнале операций. Записи возвращаются
+// по убыванию времени, новые первыми.
+type LogQuery struct {
+	// Operation - фильтр по операции; пустая строка означает все операции
+	Operation string
+	// UserIP - фильтр по IP адресу клиента (точное совпадение)
+	UserIP string
+	// From и To ограничивают timestamp (включительно); нулевое значение - без ограничения
+	From, To time.Time
+	// Text - подстрока Input без учета регистра
+	Text string
+	// Offset и Limit задают страницу выборки; Limit = 0 - без ограничения
+	Offset int
+	Limit  int
+}
+
+// LogStore хранит журнал операций
+type LogStore interface {
+	// InsertLog сохраняет запись журнала и заполняет ее ID
+	InsertLog(ctx context.Context, entry *models.LogEntry) error
+	// GetLog возвращает запись журнала по ID или ErrNotFound
+	GetLog(ctx context.Context, id primitive.ObjectID) (models.LogEntry, error)
+	// ListLogs возвращает страницу записей журнала, удовлетворяющих запросу
+	ListLogs(ctx context.Context, query LogQuery) ([]models.LogEntry, error)
+	// CountLogs возвращает число записей журнала, удовлетворяющих фильтрам запроса
+	// (Offset и Limit не учитываются)
+	CountLogs(ctx context.Context, query LogQuery) (int64, error)
+}
+
+// UserStore хранит пользователей и их токены доступа
+type UserStore interface {
+	// CreateUser сохраняет пользователя и заполняет его ID;
+	// если имя занято, возвращает ErrDuplicate
+	CreateUser(ctx context.Context, user *models.User) error
+	// GetUser возвращает пользователя по ID или ErrNotFound
+	GetUser(ctx context.Context, id primitive.ObjectID) (models.User, error)
+	// GetUserByUsername возвращает пользователя по имени или ErrNotFound
+	GetUserByUsername(ctx context.Context, username string) (models.User, error)
+	// CreateToken сохраняет токен и заполняет его ID
+	CreateToken(ctx context.Context, token *models.Token) error
+	// GetTokenByHash возвращает токен по хешу или ErrNotFound
+	GetTokenByHash(ctx context.Context, hash string) (models.Token, error)
+	// DeleteToken удаляет токен по хешу; отсутствие токена не является ошибкой
+	DeleteToken(ctx context.Context, hash string) error
+	// SetUserRole назначает роль пользователю username или возвращает ErrNotFound
+	SetUserRole(ctx context.Context, username, role string) error
+	// SetUserLocale сохраняет локаль пользователя id или возвращает ErrNotFound
+	SetUserLocale(ctx context.Context, id primitive.ObjectID, locale string) error
+}
+
+// Store объединяет хранилища приложения
+type Store interface {
+	ResultStore
+	LogStore
+	UserStore
+	// Ping проверяет доступность хранилища
+	Ping(ctx context.Context) error
+	// Close освобождает ресурсы хранилища
+	Close(ctx context.Context) error
+}
+
+// MigrationStatus - состояние миграции схемы хранилища
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Migrator применяет версионированные миграции схемы.
+// Примененные версии хранятся в самом хранилище, повторный запуск
+// MigrateUp безопасен.
+type Migrator interface {
+	// MigrationStatus возвращает состояние всех известных миграций по возрастанию версии
+	MigrationStatus(ctx context.Context) ([]MigrationStatus, error)
+	// MigrateUp применяет все непримененные миграции по порядку
+	MigrateUp(ctx context.Context) error
+	// MigrateDown откатывает последнюю примененную миграцию
+	MigrateDown(ctx context.Context) error
+}